@@ -0,0 +1,85 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/dto"
+	auditModule "github.com/ericmarcelinotju/gram/module/audit"
+)
+
+// AuditVerifyCommandFactory create and returns a factory to create command line functions for audit chain verification
+func AuditVerifyCommandFactory(auditSvc auditModule.Service) func(context.Context) error {
+	verify := func(ctx context.Context) error {
+		fmt.Println("Verifying audit chain")
+
+		result, err := auditSvc.Verify(ctx)
+		if err != nil {
+			return fmt.Errorf("error when verifying audits %s", err)
+		}
+
+		for _, item := range result.Breaks {
+			fmt.Printf("Broken audit #%d (%s): %s\n", item.Sequence, item.ID, item.Reason)
+		}
+		fmt.Printf("Checked %d audits, %d breaks found\n", result.Checked, len(result.Breaks))
+
+		if !result.Valid {
+			return fmt.Errorf("audit chain is broken")
+		}
+		return nil
+	}
+	return verify
+}
+
+// AuditExportCommandFactory create and returns a factory to create command line functions for audit export
+func AuditExportCommandFactory(auditSvc auditModule.Service) func(ctx context.Context, format, from, to string) error {
+	export := func(ctx context.Context, format, from, to string) error {
+		payload := &dto.ExportAuditDto{Format: format}
+		if format != "csv" && format != "jsonl" {
+			return fmt.Errorf("unsupported export format '%s'", format)
+		}
+
+		if len(from) > 0 {
+			fromDate, err := time.Parse("2006-01-02", from)
+			if err != nil {
+				return fmt.Errorf("invalid from date %s", err)
+			}
+			payload.From = &fromDate
+		}
+		if len(to) > 0 {
+			toDate, err := time.Parse("2006-01-02", to)
+			if err != nil {
+				return fmt.Errorf("invalid to date %s", err)
+			}
+			payload.To = &toDate
+		}
+
+		fmt.Printf("Exporting audits as %s\n", format)
+
+		result, err := auditSvc.Export(ctx, payload)
+		if err != nil {
+			return fmt.Errorf("error when exporting audits %s", err)
+		}
+
+		fmt.Printf("Exported %d audits to '%s'\n", result.Total, result.Filename)
+		return nil
+	}
+	return export
+}
+
+// AuditRestoreCommandFactory create and returns a factory to create command line functions for audit archive restoration
+func AuditRestoreCommandFactory(auditSvc auditModule.Service) func(ctx context.Context, filename string) error {
+	restore := func(ctx context.Context, filename string) error {
+		fmt.Printf("Restoring audits from '%s'\n", filename)
+
+		total, err := auditSvc.Restore(ctx, filename)
+		if err != nil {
+			return fmt.Errorf("error when restoring audits %s", err)
+		}
+
+		fmt.Printf("Restored %d audits\n", total)
+		return nil
+	}
+	return restore
+}
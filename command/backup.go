@@ -0,0 +1,45 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ericmarcelinotju/gram/plugins/database/backup"
+	"github.com/ericmarcelinotju/gram/plugins/storage"
+)
+
+// BackupCommandFactory create and returns a factory to create command line functions for database backup
+func BackupCommandFactory(backup *backup.Backup, store storage.Storage) func(context.Context) error {
+	create := func(ctx context.Context) error {
+		fmt.Println("Backing up database")
+
+		filename, manifest, err := backup.Create(ctx, store)
+		if err != nil {
+			return fmt.Errorf("error when backing up %s", err)
+		}
+		for _, table := range manifest.Tables {
+			fmt.Printf("Dumped %d rows of %s\n", table.Rows, table.Name)
+		}
+		fmt.Printf("Backup of schema version %d written to %s\n", manifest.SchemaVersion, filename)
+		return nil
+	}
+	return create
+}
+
+// RestoreCommandFactory create and returns a factory to create command line functions for database restore
+func RestoreCommandFactory(backup *backup.Backup, store storage.Storage) func(context.Context, string) error {
+	restore := func(ctx context.Context, filename string) error {
+		fmt.Printf("Restoring database from %s\n", filename)
+
+		manifest, err := backup.Restore(ctx, store, filename)
+		if err != nil {
+			return fmt.Errorf("error when restoring %s", err)
+		}
+		for _, table := range manifest.Tables {
+			fmt.Printf("Restored %d rows of %s\n", table.Rows, table.Name)
+		}
+		fmt.Printf("Restored %s backup of %s\n", manifest.Driver, manifest.CreatedAt.Format("2006-01-02 15:04:05"))
+		return nil
+	}
+	return restore
+}
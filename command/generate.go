@@ -0,0 +1,28 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ericmarcelinotju/gram/plugins/database/generator"
+)
+
+// GenerateCommandFactory create and returns a factory to create command line functions for fake data generation
+func GenerateCommandFactory(generator *generator.Generator, environment string) func(context.Context) error {
+	generate := func(ctx context.Context) error {
+		if environment == "prod" || environment == "production" {
+			return fmt.Errorf("refusing to generate data in the %s database", environment)
+		}
+		fmt.Println("Generating data")
+
+		results, err := generator.Generate(ctx)
+		if err != nil {
+			return fmt.Errorf("error when generating %s", err)
+		}
+		for _, table := range []string{"roles", "users", "audits"} {
+			fmt.Printf("Generated %d %s\n", results[table], table)
+		}
+		return nil
+	}
+	return generate
+}
@@ -7,34 +7,85 @@ import (
 	"os"
 	"time"
 
+	"github.com/ericmarcelinotju/gram/model"
+	auditModule "github.com/ericmarcelinotju/gram/module/audit"
 	permissionModule "github.com/ericmarcelinotju/gram/module/permission"
 	roleModule "github.com/ericmarcelinotju/gram/module/role"
+	settingModule "github.com/ericmarcelinotju/gram/module/setting"
 	userModule "github.com/ericmarcelinotju/gram/module/user"
+	"github.com/ericmarcelinotju/gram/plugins/database"
+	"github.com/ericmarcelinotju/gram/plugins/database/backup"
+	"github.com/ericmarcelinotju/gram/plugins/database/generator"
+	"github.com/ericmarcelinotju/gram/plugins/database/scrub"
 	"github.com/ericmarcelinotju/gram/plugins/database/seeder"
+	"github.com/ericmarcelinotju/gram/plugins/storage"
+	"github.com/ericmarcelinotju/gram/utils/crypt"
+	"github.com/ericmarcelinotju/gram/utils/env"
 	"gorm.io/gorm"
 )
 
 type SeederService interface {
-	Seed() error
+	Name() string
+	// Depends names the seeders that seed before this one
+	Depends() []string
+	Seed(fixtures *seeder.Fixtures) ([]seeder.Change, error)
 	Migrate() error
 }
 
-func ProcessCommands(db *gorm.DB) {
+// seederServices lists the seeders, settings are saved through settingStore
+func seederServices(settingStore seeder.SettingStore) func(db *gorm.DB) []SeederService {
+	return func(db *gorm.DB) []SeederService {
+		return []SeederService{
+			seeder.NewAuditSeederService(db),
+			seeder.NewSettingSeederService(db, settingStore),
+			seeder.NewPermissionSeederService(db),
+			seeder.NewRoleSeederService(db),
+			seeder.NewUserSeederService(db),
+		}
+	}
+}
+
+func ProcessCommands(db *gorm.DB, mediaStorage storage.Storage, settingSvc settingModule.Service, settingCodec *settingModule.Codec) {
 	db = db.Session(&gorm.Session{SkipHooks: true})
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
 
 	cmdUser := flag.String("u", "", "Create super user")
-	cmdMigrate := flag.Bool("m", false, "Migrate tables")
+	cmdMigrate := flag.Bool("m", false, "Migrate tables, same as -migrate up")
+	cmdMigration := flag.String("migrate", "", "Run migrations: up, down N, status, redo or create NAME")
+	cmdMigrationDir := flag.String("migrate-dir", "migrations", "Directory of the sql migrations")
 	cmdSeeding := flag.Bool("s", false, "Seeding Init Value")
+	cmdFixtures := flag.String("fixtures", "fixtures", "Directory of the seed fixtures")
+	cmdSeedEnv := flag.String("seed-env", env.Get("ENV"), "Environment of the seed fixtures (dev, test or prod)")
+	cmdDryRun := flag.Bool("dry-run", false, "Print the seeding or settings import changes without applying them")
+	cmdAuditVerify := flag.Bool("audit-verify", false, "Verify audit hash chain")
+	cmdAuditExport := flag.String("audit-export", "", "Export audits as csv or jsonl")
+	cmdAuditFrom := flag.String("audit-from", "", "Export audits from date (YYYY-MM-DD)")
+	cmdAuditTo := flag.String("audit-to", "", "Export audits before date (YYYY-MM-DD)")
+	cmdAuditRestore := flag.String("audit-restore", "", "Restore audits from an archive file")
+	cmdScrub := flag.Bool("scrub", false, "Replace personal data with fake values, refused when ENV=prod")
+	cmdScrubRules := flag.String("scrub-rules", "", "Yaml file of the scrubbed columns per table, users, user imports and audits by default")
+	cmdScrubPassword := flag.String("scrub-password", "password", "Password every scrubbed user gets")
+	cmdGenerate := flag.Bool("generate", false, "Generate fake roles, users and audits, refused when ENV=prod")
+	cmdGenerateSeed := flag.Int64("generate-seed", 1, "Seed of the generated data, the same seed generates the same data")
+	cmdGenerateUsers := flag.Int("generate-users", 1000, "Number of generated users")
+	cmdGenerateRoles := flag.Int("generate-roles", 10, "Number of generated roles")
+	cmdGenerateAudits := flag.Int("generate-audits", 10000, "Number of generated audits")
+	cmdGeneratePassword := flag.String("generate-password", "password", "Password every generated user gets")
+	cmdReencrypt := flag.Bool("reencrypt-settings", false, "Seal plaintext secret settings and the ones sealed with a rotated key with the primary setting key")
+	cmdSettings := flag.String("settings", "", "Manage settings: get NAME, set NAME VALUE, list, import FILE or export [FILE], - is the standard input or output")
+	cmdSettingsFormat := flag.String("settings-format", settingModule.FormatYAML, "Format of exported settings, yaml or json")
+	cmdSettingsSecrets := flag.Bool("settings-secrets", false, "Print and export the values of secret settings")
+	cmdBackup := flag.Bool("backup", false, "Back up every table to the backup storage")
+	cmdRestore := flag.String("restore", "", "Restore a backup file into a freshly migrated database")
 	flag.Parse()
 
 	if cmdUser != nil && len(*cmdUser) > 0 {
-		userRepo := userModule.NewRepository(db, nil, nil)
+		userRepo := userModule.NewRepository(db, nil, nil, nil)
 		roleRepo := roleModule.NewRepository(db)
 		permRepo := permissionModule.NewRepository(db)
 
-		createSuperAdmin := UserCommandFactory(permRepo, roleRepo, userRepo)
+		createSuperAdmin := UserCommandFactory(database.NewUnitOfWork(db), permRepo, roleRepo, userRepo)
 		err := createSuperAdmin(ctx, *cmdUser)
 		if err != nil {
 			cancel()
@@ -44,17 +95,13 @@ func ProcessCommands(db *gorm.DB) {
 		}
 		cancel()
 		os.Exit(0)
-	} else if cmdMigrate != nil && *cmdMigrate {
-		migrate := MigrateCommandFactory(
-			[]SeederService{
-				seeder.NewAuditSeederService(db),
-				seeder.NewSettingSeederService(db),
-				seeder.NewPermissionSeederService(db),
-				seeder.NewRoleSeederService(db),
-				seeder.NewUserSeederService(db),
-			},
-		)
-		err := migrate(ctx)
+	} else if (cmdMigrate != nil && *cmdMigrate) || (cmdMigration != nil && len(*cmdMigration) > 0) {
+		action := "up"
+		if cmdMigration != nil && len(*cmdMigration) > 0 {
+			action = *cmdMigration
+		}
+		migrate := MigrationCommandFactory(db, *cmdMigrationDir, BaselineMigration(seederServices(settingModule.NewSeeder(settingCodec))), SettingHistoryMigration(), AuditSequenceMigration())
+		err := migrate(ctx, action, flag.Args())
 		if err != nil {
 			cancel()
 			fmt.Println(err)
@@ -64,15 +111,166 @@ func ProcessCommands(db *gorm.DB) {
 		cancel()
 		os.Exit(0)
 	} else if cmdSeeding != nil && *cmdSeeding {
-		seeding := SeedingCommandFactory(
-			[]SeederService{
-				seeder.NewSettingSeederService(db),
-				seeder.NewPermissionSeederService(db),
-				seeder.NewRoleSeederService(db),
-				seeder.NewUserSeederService(db),
-			},
-		)
-		err := seeding(ctx)
+		environment := *cmdSeedEnv
+		if environment == "" {
+			environment = "dev"
+		}
+		fixtures, err := seeder.LoadFixtures(*cmdFixtures, environment)
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		seeding := SeedingCommandFactory(db, seederServices(settingModule.NewSeeder(settingCodec)), settingSvc)
+		err = seeding(ctx, fixtures, *cmdDryRun)
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+		cancel()
+		os.Exit(0)
+	} else if cmdAuditVerify != nil && *cmdAuditVerify {
+		auditSvc := auditModule.NewService(auditModule.NewRepository(db, mediaStorage))
+
+		verify := AuditVerifyCommandFactory(auditSvc)
+		err := verify(ctx)
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+		cancel()
+		os.Exit(0)
+	} else if cmdAuditExport != nil && len(*cmdAuditExport) > 0 {
+		auditSvc := auditModule.NewService(auditModule.NewRepository(db, mediaStorage))
+
+		export := AuditExportCommandFactory(auditSvc)
+		err := export(ctx, *cmdAuditExport, *cmdAuditFrom, *cmdAuditTo)
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+		cancel()
+		os.Exit(0)
+	} else if cmdAuditRestore != nil && len(*cmdAuditRestore) > 0 {
+		auditSvc := auditModule.NewService(auditModule.NewRepository(db, mediaStorage))
+
+		restore := AuditRestoreCommandFactory(auditSvc)
+		err := restore(ctx, *cmdAuditRestore)
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+		cancel()
+		os.Exit(0)
+	} else if (cmdBackup != nil && *cmdBackup) || (cmdRestore != nil && len(*cmdRestore) > 0) {
+		// backups may outlast the timeout of the other commands
+		backupCtx := context.Background()
+
+		tables, err := backup.Tables(db, model.Entities...)
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+		store, err := settingSvc.GetBackupStorage(backupCtx, mediaStorage)
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		if *cmdBackup {
+			create := BackupCommandFactory(backup.NewBackup(db, tables), store)
+			err = create(backupCtx)
+		} else {
+			restore := RestoreCommandFactory(backup.NewBackup(db, tables), store)
+			err = restore(backupCtx, *cmdRestore)
+		}
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+		cancel()
+		os.Exit(0)
+	} else if cmdScrub != nil && *cmdScrub {
+		rules := scrub.DefaultRules
+		passwordHash, err := crypt.Hash(*cmdScrubPassword)
+		if err == nil && len(*cmdScrubRules) > 0 {
+			rules, err = scrub.LoadRules(*cmdScrubRules)
+		}
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		run := ScrubCommandFactory(scrub.NewScrubber(db, rules, passwordHash), env.Get("ENV"))
+		// scrubbing rewrites every row and may outlast the timeout of the other commands
+		err = run(context.Background())
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+		cancel()
+		os.Exit(0)
+	} else if cmdGenerate != nil && *cmdGenerate {
+		passwordHash, err := crypt.Hash(*cmdGeneratePassword)
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+
+		run := GenerateCommandFactory(generator.NewGenerator(db, generator.Options{
+			Seed:   *cmdGenerateSeed,
+			Users:  *cmdGenerateUsers,
+			Roles:  *cmdGenerateRoles,
+			Audits: *cmdGenerateAudits,
+			// dates are relative to the day so a seed generates the same data all day long
+			Now: time.Now().UTC().Truncate(24 * time.Hour),
+		}, passwordHash), env.Get("ENV"))
+		// large volumes may outlast the timeout of the other commands
+		err = run(context.Background())
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+		cancel()
+		os.Exit(0)
+	} else if cmdSettings != nil && len(*cmdSettings) > 0 {
+		settings := SettingsCommandFactory(settingSvc, *cmdSettingsFormat, *cmdSettingsSecrets, *cmdDryRun)
+		err := settings(ctx, *cmdSettings, flag.Args())
+		if err != nil {
+			cancel()
+			fmt.Println(err)
+			os.Exit(1)
+			return
+		}
+		cancel()
+		os.Exit(0)
+	} else if cmdReencrypt != nil && *cmdReencrypt {
+		reencrypt := ReencryptSettingsCommandFactory(settingSvc)
+		err := reencrypt(ctx)
 		if err != nil {
 			cancel()
 			fmt.Println(err)
@@ -3,58 +3,144 @@ package command
 import (
 	"context"
 	"fmt"
-	"reflect"
-	"strings"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/model"
+	"github.com/ericmarcelinotju/gram/plugins/database/migration"
+	"gorm.io/gorm"
 )
 
-// MigrateCommandFactory create and returns a factory to create command line functions for migration
-func MigrateCommandFactory(services []SeederService) func(context.Context) error {
-	migrate := func(ctx context.Context) error {
-		var migrateErrors map[string]error = make(map[string]error)
-		var err error
+// BaselineMigration creates the tables of the seeders, the schema later migrations change
+func BaselineMigration(services func(db *gorm.DB) []SeederService) migration.Migration {
+	return migration.Migration{
+		Version: 2,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			for _, service := range services(tx) {
+				if err := service.Migrate(); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
 
-		// TODO :: CREATE EXTENSION "uuid-ossp";
+// SettingHistoryMigration creates the tables recording the changes and snapshots of settings
+func SettingHistoryMigration() migration.Migration {
+	return migration.Migration{
+		Version: 3,
+		Name:    "setting_history",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&model.SettingHistoryEntity{}, &model.SettingSnapshotEntity{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&model.SettingSnapshotEntity{}, &model.SettingHistoryEntity{})
+		},
+	}
+}
 
-		for _, service := range services {
-			var serviceName string
-			if t := reflect.TypeOf(service); t.Kind() == reflect.Ptr {
-				serviceName = strings.Split(t.Elem().String(), ".")[1]
-			} else {
-				serviceName = t.Name()
+// AuditSequenceMigration makes audit sequences unique so concurrent writers cannot fork the chain
+func AuditSequenceMigration() migration.Migration {
+	return migration.Migration{
+		Version: 4,
+		Name:    "audit_sequence_unique",
+		Up: func(tx *gorm.DB) error {
+			// databases migrated before keep a plain index of the same name
+			if tx.Migrator().HasIndex(&model.AuditEntity{}, "idx_audits_sequence") {
+				if err := tx.Migrator().DropIndex(&model.AuditEntity{}, "idx_audits_sequence"); err != nil {
+					return err
+				}
 			}
-
-			fmt.Printf("Migrating %s\n", serviceName)
-			err = service.Migrate()
-			migrateErrors[serviceName] = nil
-			if err != nil {
-				migrateErrors[serviceName] = err
-				fmt.Printf("Error when migrating %ss %s\n", serviceName, err)
+			return tx.Migrator().CreateIndex(&model.AuditEntity{}, "Sequence")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&model.AuditEntity{}, "idx_audits_sequence"); err != nil {
+				return err
 			}
-		}
-
-		var successModules []string
-		var errorModules []string
+			return tx.Exec("CREATE INDEX idx_audits_sequence ON audits (sequence)").Error
+		},
+	}
+}
 
-		for module, err := range migrateErrors {
-			if err == nil {
-				successModules = append(successModules, module)
-			} else {
-				errorModules = append(errorModules, module)
+// MigrationCommandFactory create and returns a factory to create command line functions for migrations
+func MigrationCommandFactory(db *gorm.DB, dir string, migrations ...migration.Migration) func(ctx context.Context, action string, args []string) error {
+	migrate := func(ctx context.Context, action string, args []string) error {
+		if action == "create" {
+			if len(args) == 0 {
+				return fmt.Errorf("migration name is required")
+			}
+			paths, err := migration.Create(dir, args[0], time.Now())
+			for _, path := range paths {
+				fmt.Printf("Created %s\n", path)
 			}
+			return err
 		}
 
-		if len(successModules) <= 0 {
-			fmt.Println("All migrations failed!")
-		} else {
-			fmt.Printf("Successful migration: '%s'\n", strings.Join(successModules, ", "))
+		migrator, err := migration.NewMigrator(db, os.DirFS(dir), migrations...)
+		if err != nil {
+			return fmt.Errorf("error when loading migrations %s", err)
 		}
 
-		if len(errorModules) <= 0 {
-			fmt.Println("all migrations successful!")
-			return nil
-		} else {
-			return fmt.Errorf("failed migration: '%s'\n", strings.Join(errorModules, ", "))
+		switch action {
+		case "up":
+			applied, err := migrator.Up(ctx)
+			for _, item := range applied {
+				fmt.Printf("Applied %d_%s\n", item.Version, item.Name)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%d migrations applied\n", len(applied))
+		case "down":
+			n := 1
+			if len(args) > 0 {
+				if n, err = strconv.Atoi(args[0]); err != nil || n < 1 {
+					return fmt.Errorf("invalid number of migrations '%s'", args[0])
+				}
+			}
+			rolledBack, err := migrator.Down(ctx, n)
+			for _, item := range rolledBack {
+				fmt.Printf("Rolled back %d_%s\n", item.Version, item.Name)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%d migrations rolled back\n", len(rolledBack))
+		case "redo":
+			redone, err := migrator.Redo(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Redone %d_%s\n", redone.Version, redone.Name)
+		case "status":
+			statuses, err := migrator.Status(ctx)
+			if err != nil {
+				return err
+			}
+			writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+			for _, item := range statuses {
+				state, appliedAt := "pending", ""
+				if item.Applied {
+					state, appliedAt = "applied", item.AppliedAt.Format(time.RFC3339)
+				}
+				if item.Changed {
+					state = "changed"
+				}
+				if item.Missing {
+					state = "missing"
+				}
+				fmt.Fprintf(writer, "%d\t%s\t%s\t%s\n", item.Version, item.Name, state, appliedAt)
+			}
+			return writer.Flush()
+		default:
+			return fmt.Errorf("unknown migrate action '%s', use up, down N, status, redo or create NAME", action)
 		}
+		return nil
 	}
 	return migrate
 }
@@ -0,0 +1,51 @@
+package command
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ericmarcelinotju/gram/model"
+	"github.com/ericmarcelinotju/gram/plugins/database"
+	"github.com/ericmarcelinotju/gram/plugins/database/migration"
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestMigrationsSqlite(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migrate.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, database.UseUuids(db, model.Entities...), nil)
+	db = db.Session(&gorm.Session{SkipHooks: true})
+
+	migrator, err := migration.NewMigrator(db, fstest.MapFS{},
+		BaselineMigration(seederServices(nil)), SettingHistoryMigration(), AuditSequenceMigration())
+	assert.Equal(t, err, nil)
+	applied, err := migrator.Up(context.Background())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(applied), 3)
+	for _, entity := range model.Entities {
+		assert.Equal(t, db.Migrator().HasTable(entity), true)
+	}
+
+	// the ids are generated by the application, the database has no default
+	role := model.RoleEntity{Name: "admin"}
+	assert.Equal(t, db.Create(&role).Error, nil)
+	users := []model.UserEntity{
+		{Name: "a", Email: "a@example.com", RoleId: role.Id},
+		{Name: "b", Email: "b@example.com", RoleId: role.Id},
+	}
+	assert.Equal(t, db.Create(&users).Error, nil)
+	assert.NotEqual(t, role.Id, uuid.Nil)
+	assert.NotEqual(t, users[0].Id, uuid.Nil)
+	assert.NotEqual(t, users[0].Id, users[1].Id)
+
+	var stored model.UserEntity
+	assert.Equal(t, db.Preload("Role").First(&stored, "id = ?", users[1].Id).Error, nil)
+	assert.Equal(t, stored.Role.Name, "admin")
+}
@@ -0,0 +1,37 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ericmarcelinotju/gram/plugins/database/scrub"
+)
+
+// ScrubCommandFactory create and returns a factory to create command line functions for personal data scrubbing
+func ScrubCommandFactory(scrubber *scrub.Scrubber, environment string) func(context.Context) error {
+	run := func(ctx context.Context) error {
+		if environment == "prod" || environment == "production" {
+			return fmt.Errorf("refusing to scrub the %s database", environment)
+		}
+		fmt.Println("Scrubbing personal data")
+
+		results, err := scrubber.Scrub(ctx)
+		tables := make([]string, 0, len(results))
+		for table := range results {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+		for _, table := range tables {
+			fmt.Printf("Scrubbed %d rows of %s\n", results[table], table)
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := results["audits"]; ok {
+			fmt.Println("Audit values are blanked, the audit chain no longer verifies")
+		}
+		return nil
+	}
+	return run
+}
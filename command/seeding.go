@@ -2,57 +2,102 @@ package command
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"reflect"
-	"strings"
+
+	settingModule "github.com/ericmarcelinotju/gram/module/setting"
+	"github.com/ericmarcelinotju/gram/plugins/database/seeder"
+	"gorm.io/gorm"
 )
 
-// SeedingCommandFactory create and returns a factory to create command line functions for seeding
-func SeedingCommandFactory(services []SeederService) func(context.Context) error {
-	seed := func(ctx context.Context) error {
-		var seedErrors map[string]error = make(map[string]error)
-		var err error
+// errDryRun rolls back the seeding of a dry run
+var errDryRun = errors.New("dry run")
 
-		for _, service := range services {
-			var serviceName string
-			if t := reflect.TypeOf(service); t.Kind() == reflect.Ptr {
-				serviceName = strings.Split(t.Elem().String(), ".")[1]
-			} else {
-				serviceName = t.Name()
-			}
+// SeedingCommandFactory create and returns a factory to create command line functions for seeding,
+// the seeded settings are refreshed through settingSvc once committed
+func SeedingCommandFactory(db *gorm.DB, services func(db *gorm.DB) []SeederService, settingSvc settingModule.Service) func(ctx context.Context, fixtures *seeder.Fixtures, dryRun bool) error {
+	seed := func(ctx context.Context, fixtures *seeder.Fixtures, dryRun bool) error {
+		var total int
+		var settings []string
 
-			fmt.Printf("Seeding %s\n", serviceName)
-			err = service.Seed()
-			seedErrors[serviceName] = nil
+		// seeders run in one transaction so a dry run sees the changes of the seeders it depends on
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			ordered, err := orderSeeders(services(tx))
 			if err != nil {
-				seedErrors[serviceName] = err
-				fmt.Printf("Error when seeding %ss %s\n", serviceName, err)
+				return err
+			}
+			for _, service := range ordered {
+				fmt.Printf("Seeding %s\n", service.Name())
+				changes, err := service.Seed(fixtures)
+				for _, change := range changes {
+					fmt.Printf("  %s\n", change)
+					if change.Kind == "setting" {
+						settings = append(settings, change.Key)
+					}
+				}
+				total += len(changes)
+				if err != nil {
+					return fmt.Errorf("error when seeding %ss %s", service.Name(), err)
+				}
 			}
+			if dryRun {
+				return errDryRun
+			}
+			return nil
+		})
+		if errors.Is(err, errDryRun) {
+			fmt.Printf("Dry run, %d changes not applied\n", total)
+			return nil
 		}
-
-		var successModules []string
-		var errorModules []string
-
-		for module, err := range seedErrors {
-			if err == nil {
-				successModules = append(successModules, module)
-			} else {
-				errorModules = append(errorModules, module)
+		if err != nil {
+			return err
+		}
+		if len(settings) > 0 {
+			if err := settingSvc.Refresh(ctx, settings); err != nil {
+				return fmt.Errorf("error when refreshing the seeded settings %w", err)
 			}
 		}
+		fmt.Printf("all seeding successful, %d changes applied\n", total)
+		return nil
+	}
+	return seed
+}
 
-		if len(successModules) <= 0 {
-			fmt.Println("All seeding failed!")
-		} else {
-			fmt.Printf("Successful seeding: '%s'\n", strings.Join(successModules, ", "))
-		}
+// orderSeeders sorts the seeders after the seeders they depend on, keeping the given order otherwise
+func orderSeeders(services []SeederService) ([]SeederService, error) {
+	byName := make(map[string]SeederService, len(services))
+	for _, service := range services {
+		byName[service.Name()] = service
+	}
 
-		if len(errorModules) <= 0 {
-			fmt.Println("all seeding successful!")
+	var ordered []SeederService
+	state := make(map[string]int) // 1 visiting, 2 done
+	var visit func(service SeederService) error
+	visit = func(service SeederService) error {
+		switch state[service.Name()] {
+		case 1:
+			return fmt.Errorf("seeder %s depends on itself", service.Name())
+		case 2:
 			return nil
-		} else {
-			return fmt.Errorf("failed seeding: '%s'\n", strings.Join(errorModules, ", "))
 		}
+		state[service.Name()] = 1
+		for _, name := range service.Depends() {
+			dependency, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("seeder %s depends on unknown seeder %s", service.Name(), name)
+			}
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		state[service.Name()] = 2
+		ordered = append(ordered, service)
+		return nil
 	}
-	return seed
+	for _, service := range services {
+		if err := visit(service); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
 }
@@ -0,0 +1,137 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ericmarcelinotju/gram/dto"
+	settingModule "github.com/ericmarcelinotju/gram/module/setting"
+)
+
+// ReencryptSettingsCommandFactory create and returns a factory to create command line functions sealing the secret settings again
+func ReencryptSettingsCommandFactory(settingSvc settingModule.Service) func(context.Context) error {
+	reencrypt := func(ctx context.Context) error {
+		fmt.Println("Sealing secret settings with the primary setting key")
+
+		total, err := settingSvc.Reencrypt(ctx)
+		if err != nil {
+			return fmt.Errorf("error when sealing secret settings %s", err)
+		}
+		fmt.Printf("%d secret settings sealed\n", total)
+		return nil
+	}
+	return reencrypt
+}
+
+// SettingsCommandFactory create and returns a factory to create command line functions managing settings through the
+// setting service, so values are validated, secrets sealed and running instances notified like through the API.
+// Secrets are printed and exported only when secrets is set.
+func SettingsCommandFactory(settingSvc settingModule.Service, format string, secrets, dryRun bool) func(ctx context.Context, action string, args []string) error {
+	settings := func(ctx context.Context, action string, args []string) error {
+		switch action {
+		case "get":
+			if len(args) != 1 {
+				return fmt.Errorf("usage: -settings get NAME")
+			}
+			value, err := settingSvc.ReadByName(ctx, args[0])
+			if err != nil {
+				return err
+			}
+			if settingModule.IsSecret(args[0]) && !secrets && value != "" {
+				value = settingModule.Mask
+			}
+			fmt.Println(value)
+		case "set":
+			if len(args) != 2 {
+				return fmt.Errorf("usage: -settings set NAME VALUE")
+			}
+			if err := settingSvc.Save(ctx, &dto.PostSettingDto{Name: args[0], Value: args[1]}); err != nil {
+				return err
+			}
+			fmt.Printf("Saved %s\n", args[0])
+		case "list":
+			values, err := settingSvc.Export(ctx, secrets)
+			if err != nil {
+				return err
+			}
+			if !secrets {
+				// the masked values tell which secrets are set without opening them
+				stored, err := settingSvc.Read(ctx)
+				if err != nil {
+					return err
+				}
+				for _, setting := range stored {
+					if setting.Secret {
+						values[setting.Name] = setting.Value
+					}
+				}
+			}
+			writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(writer, "NAME\tGROUP\tVALUE")
+			for _, definition := range settingModule.Definitions {
+				fmt.Fprintf(writer, "%s\t%s\t%s\n", definition.Name, definition.Group, values[definition.Name])
+			}
+			return writer.Flush()
+		case "export":
+			values, err := settingSvc.Export(ctx, secrets)
+			if err != nil {
+				return err
+			}
+			content, err := settingModule.MarshalSettings(values, format)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 || args[0] == "-" {
+				_, err = os.Stdout.Write(content)
+				return err
+			}
+			// exports may hold secrets, only the owner reads them
+			if err := os.WriteFile(args[0], content, 0600); err != nil {
+				return err
+			}
+			fmt.Printf("Exported %d settings to %s\n", len(values), args[0])
+		case "import":
+			if len(args) != 1 {
+				return fmt.Errorf("usage: -settings import FILE, - reads the standard input")
+			}
+			var content []byte
+			var err error
+			if args[0] == "-" {
+				content, err = io.ReadAll(os.Stdin)
+			} else {
+				content, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
+			}
+			values, err := settingModule.UnmarshalSettings(content)
+			if err != nil {
+				return fmt.Errorf("invalid settings file %s", err)
+			}
+
+			changes, err := settingSvc.Import(ctx, values, dryRun)
+			if err != nil {
+				return err
+			}
+			for _, change := range changes {
+				if change.OldValue == nil {
+					fmt.Printf("  create %s = %s\n", change.Name, change.NewValue)
+				} else {
+					fmt.Printf("  update %s = %s -> %s\n", change.Name, *change.OldValue, change.NewValue)
+				}
+			}
+			if dryRun {
+				fmt.Printf("Dry run, %d changes not applied\n", len(changes))
+				return nil
+			}
+			fmt.Printf("%d settings imported\n", len(changes))
+		default:
+			return fmt.Errorf("unknown settings action '%s', expected get, set, list, import or export", action)
+		}
+		return nil
+	}
+	return settings
+}
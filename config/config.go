@@ -2,9 +2,11 @@ package config
 
 import (
 	"log"
+	"net"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,17 +17,19 @@ import (
 
 // Config is a struct that contains configuration variables
 type Config struct {
-	Version       string
-	Environment   string
-	Host          url.URL
-	Port          string
-	Net           *Net
-	Database      *Database
-	DatabaseMeter *Database
-	Queue         *Queue
-	Cache         *Cache
-	Secret        string
-	MediaStorage  *Storage
+	Version     string
+	Environment string
+	Host        url.URL
+	Port        string
+	Net         *Net
+	Database    *Database
+	Queue       *Queue
+	Cache       *Cache
+	Secret      string
+	AuditKey    string
+	// SettingKeys seal the secret settings, id:base64 keys with the primary first
+	SettingKeys  string
+	MediaStorage *Storage
 }
 
 // Net is a struct that contains net client's configuration variables
@@ -42,6 +46,27 @@ type Database struct {
 	User     string
 	DB       string
 	Password string
+
+	// Replicas serve the reads, they share the driver, user, password and name of the primary
+	Replicas []*Database
+	// ReplicaPolicy balances the reads between replicas: round-robin, random or least-connections
+	ReplicaPolicy string
+	// ReplicaCheckInterval is how often the replicas are pinged, unhealthy ones are skipped until they answer again
+	ReplicaCheckInterval time.Duration
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// SlowThreshold logs the queries taking longer, zero disables the slow query log
+	SlowThreshold time.Duration
+	// StatsInterval logs the pool statistics periodically, zero disables it
+	StatsInterval time.Duration
+
+	// ConnectRetries is how many times a failed connection is retried, waiting ConnectBackoff doubled on each retry
+	ConnectRetries int
+	ConnectBackoff time.Duration
 }
 
 // Cache is a struct that contains cache's configuration variables
@@ -110,6 +135,19 @@ func HandleLogFile() {
 
 var Instance *Config
 
+// intEnv returns the integer env or fallback when it is empty
+func intEnv(key string, fallback int) int {
+	value := env.Get(key)
+	if value == "" {
+		return fallback
+	}
+	valueInt, err := strconv.Atoi(value)
+	if err != nil {
+		panic("Error when parsing " + key)
+	}
+	return valueInt
+}
+
 // NewConfig creates a new Config struct
 func NewConfig() *Config {
 	env.CheckDotEnv()
@@ -142,6 +180,41 @@ func NewConfig() *Config {
 	}
 	netTimeout := time.Millisecond * time.Duration(netTimeoutInt)
 
+	database := &Database{
+		Driver:        env.MustGet("DB_DRIVER"),
+		Host:          env.MustGet("DB_HOST"),
+		Port:          env.MustGet("DB_PORT"),
+		Instance:      env.Get("DB_INSTANCE"),
+		User:          env.MustGet("DB_USER"),
+		DB:            env.MustGet("DB_NAME"),
+		Password:      env.MustGet("DB_PASSWORD"),
+		ReplicaPolicy: env.Get("DB_REPLICA_POLICY"),
+
+		MaxOpenConns:    intEnv("DB_MAX_OPEN_CONNS", 0),
+		MaxIdleConns:    intEnv("DB_MAX_IDLE_CONNS", 2),
+		ConnMaxLifetime: time.Millisecond * time.Duration(intEnv("DB_CONN_MAX_LIFETIME", 0)),
+		ConnMaxIdleTime: time.Millisecond * time.Duration(intEnv("DB_CONN_MAX_IDLE_TIME", 0)),
+
+		SlowThreshold: time.Millisecond * time.Duration(intEnv("DB_SLOW_THRESHOLD", 200)),
+		StatsInterval: time.Millisecond * time.Duration(intEnv("DB_STATS_INTERVAL", 0)),
+
+		ConnectRetries: intEnv("DB_CONNECT_RETRIES", 5),
+		ConnectBackoff: time.Millisecond * time.Duration(intEnv("DB_CONNECT_BACKOFF", 1000)),
+	}
+	// DB_REPLICAS is a comma separated list of host:port, the port of the primary is used when it is omitted
+	for _, address := range strings.Split(env.Get("DB_REPLICAS"), ",") {
+		if address = strings.TrimSpace(address); address == "" {
+			continue
+		}
+		replica := *database
+		replica.Host = address
+		if host, port, err := net.SplitHostPort(address); err == nil {
+			replica.Host, replica.Port = host, port
+		}
+		database.Replicas = append(database.Replicas, &replica)
+	}
+	database.ReplicaCheckInterval = time.Millisecond * time.Duration(intEnv("DB_REPLICA_CHECK_INTERVAL", 10000))
+
 	config := &Config{
 		Version:     "1.0.0",
 		Environment: environment,
@@ -150,15 +223,7 @@ func NewConfig() *Config {
 		Net: &Net{
 			Timeout: netTimeout,
 		},
-		Database: &Database{
-			Driver:   env.MustGet("DB_DRIVER"),
-			Host:     env.MustGet("DB_HOST"),
-			Port:     env.MustGet("DB_PORT"),
-			Instance: env.Get("DB_INSTANCE"),
-			User:     env.MustGet("DB_USER"),
-			DB:       env.MustGet("DB_NAME"),
-			Password: env.MustGet("DB_PASSWORD"),
-		},
+		Database: database,
 		Cache: &Cache{
 			Driver:        env.MustGet("CACHE_DRIVER"),
 			Password:      env.Get("CACHE_PASSWORD"),
@@ -166,6 +231,17 @@ func NewConfig() *Config {
 			Port:          env.MustGet("CACHE_PORT"),
 			DefaultExpiry: cacheExpiry,
 		},
+		AuditKey:    env.Get("AUDIT_KEY"),
+		SettingKeys: env.Get("SETTING_KEYS"),
+	}
+
+	// keys are better kept out of the environment, in a file mounted from a secret store
+	if keysFile := env.Get("SETTING_KEYS_FILE"); keysFile != "" {
+		keys, err := os.ReadFile(keysFile)
+		if err != nil {
+			panic("Error when reading setting keys file : '" + keysFile + "'")
+		}
+		config.SettingKeys = string(keys)
 	}
 
 	mediaPath := env.Get("MEDIA_PATH")
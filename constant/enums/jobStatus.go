@@ -0,0 +1,33 @@
+package enums
+
+import (
+	"encoding/json"
+)
+
+type JobStatus string
+
+const (
+	DefaultJobStatus JobStatus = ""
+	JobStatusPending JobStatus = "pending"
+	JobStatusRunning JobStatus = "running"
+	JobStatusSuccess JobStatus = "success"
+	JobStatusFailed  JobStatus = "failed"
+)
+
+var jobStatusIds = map[string]JobStatus{
+	"pending": JobStatusPending,
+	"running": JobStatusRunning,
+	"success": JobStatusSuccess,
+	"failed":  JobStatusFailed,
+}
+
+// UnmarshalJSON unmashals a quoted json string to the enum value
+func (s *JobStatus) UnmarshalJSON(b []byte) error {
+	var j string
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	*s = jobStatusIds[j]
+	return nil
+}
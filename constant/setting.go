@@ -11,4 +11,14 @@ const (
 	SFTPUsername      = "sftp_username"
 	SFTPPassword      = "sftp_password"
 	SFTPStorageFolder = "sftp_storage_folder"
+
+	AuditRetention     = "audit_retention"
+	AuditRetentionTime = "audit_retention_time"
+
+	SoftDeleteRetention = "soft_delete_retention"
+	SoftDeletePurgeTime = "soft_delete_purge_time"
+
+	BackupTime      = "backup_time"
+	BackupRetention = "backup_retention"
+	BackupStorage   = "backup_storage"
 )
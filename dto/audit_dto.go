@@ -1,10 +1,15 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/ericmarcelinotju/gram/constant/enums"
+)
 
 // AuditDto struct defines dto for audit entity
 type AuditDto struct {
 	ID            string    `json:"id"`
+	Sequence      int64     `json:"sequence"`
 	Date          time.Time `json:"date"`
 	EntityName    string    `json:"entity_name"`
 	EntityId      string    `json:"entity_id"`
@@ -12,10 +17,77 @@ type AuditDto struct {
 	NewValue      string    `json:"new_value"`
 	OperationType string    `json:"operation_type"`
 	Origin        string    `json:"origin"`
+	RequestId     string    `json:"request_id"`
 
 	UserID string  `json:"user_id"`
 	User   UserDto `json:"user"`
 
 	PermissionID string        `json:"permission_id"`
 	Permission   PermissionDto `json:"permission"`
+
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+type GetAuditDto struct {
+	EntityName *string `json:"entity_name" form:"entity_name"`
+	EntityId   *string `json:"entity_id" form:"entity_id"`
+	UserId     *string `json:"user_id" form:"user_id" binding:"omitempty,uuid"`
+
+	FilterDto
+	FieldsDto
+	*PaginationDto
+	*SortDto
+}
+
+type ExportAuditDto struct {
+	EntityName *string    `json:"entity_name" form:"entity_name"`
+	From       *time.Time `json:"from" form:"from" time_format:"2006-01-02"`
+	To         *time.Time `json:"to" form:"to" time_format:"2006-01-02"`
+	Format     string     `json:"format" form:"format" binding:"required,oneof=csv jsonl"`
+}
+
+type AuditExportDto struct {
+	Filename string `json:"filename"`
+	Total    int64  `json:"total"`
+}
+
+// AuditChainBreakDto describes an audit entry that does not match the hash chain
+type AuditChainBreakDto struct {
+	ID       string `json:"id"`
+	Sequence int64  `json:"sequence"`
+	Reason   string `json:"reason"`
+}
+
+type AuditVerificationDto struct {
+	Checked int64                `json:"checked"`
+	Valid   bool                 `json:"valid"`
+	Breaks  []AuditChainBreakDto `json:"breaks"`
+}
+
+// AuditArchiveDto struct defines dto for an audit retention run
+type AuditArchiveDto struct {
+	Id         string          `json:"id"`
+	EntityName string          `json:"entity_name"`
+	Cutoff     time.Time       `json:"cutoff"`
+	Filename   string          `json:"filename"`
+	Total      int64           `json:"total"`
+	Status     enums.JobStatus `json:"status"`
+	Error      string          `json:"error"`
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+type ArchiveAuditDto struct {
+	EntityName    string `json:"entity_name" binding:"required"`
+	RetentionDays int    `json:"retention_days" binding:"required,min=1"`
+}
+
+type GetAuditArchiveDto struct {
+	EntityName *string `json:"entity_name" form:"entity_name"`
+
+	*PaginationDto
+	*SortDto
 }
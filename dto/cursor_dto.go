@@ -0,0 +1,212 @@
+package dto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const defaultCursorLimit = 20
+
+// cursorDto is the position encoded in an opaque cursor
+type cursorDto struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+	Prev   bool              `json:"p,omitempty"`
+}
+
+// cursorSeek keeps the state of a cursor query to build the cursors of its result
+type cursorSeek struct {
+	spec     string
+	fields   []*schema.Field
+	limit    int
+	prev     bool
+	position bool
+}
+
+func encodeCursor(cursor cursorDto) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*cursorDto, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var cursor cursorDto
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// Paginate orders the query by the sort spec then applies the page, or seeks past the cursor position
+func (p *PaginationDto) Paginate(db *gorm.DB, sort *SortDto, config SortConfig) (*gorm.DB, error) {
+	if !p.IsCursor() {
+		db, err := sort.Apply(db, config)
+		if err != nil || p == nil {
+			return db, err
+		}
+		return p.Apply(db), nil
+	}
+
+	var position *cursorDto
+	if *p.Cursor != "" {
+		var err error
+		if position, err = decodeCursor(*p.Cursor); err != nil {
+			return db, err
+		}
+		// the cursor keeps the order it was created with
+		sort = &SortDto{Sort: &position.Sort}
+	}
+	keys, err := sort.Keys(config)
+	if err != nil {
+		return db, err
+	}
+	if position != nil && len(position.Values) != len(keys) {
+		return db, errors.New("invalid cursor")
+	}
+
+	if err := db.Statement.Parse(db.Statement.Model); err != nil {
+		return db, err
+	}
+	seek := &cursorSeek{
+		fields:   make([]*schema.Field, len(keys)),
+		limit:    defaultCursorLimit,
+		position: position != nil,
+		prev:     position != nil && position.Prev,
+	}
+	if p.Limit != nil {
+		seek.limit = *p.Limit
+	}
+
+	columns := make([]clause.Column, len(keys))
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		column, ok := config.Fields[key.Field]
+		if !ok {
+			column = key.Field
+		}
+		field := db.Statement.Schema.LookUpField(column)
+		if field == nil {
+			return db, fmt.Errorf("unknown sort column '%s'", column)
+		}
+		if field.FieldType.Kind() == reflect.Ptr {
+			return db, fmt.Errorf("cursor pagination cannot sort on nullable '%s'", key.Field)
+		}
+		seek.fields[i] = field
+		columns[i] = clause.Column{Table: clause.CurrentTable, Name: column}
+
+		if position != nil {
+			value := reflect.New(field.FieldType)
+			if err := json.Unmarshal(position.Values[i], value.Interface()); err != nil {
+				return db, errors.New("invalid cursor")
+			}
+			values[i] = value.Elem().Interface()
+		}
+
+		if i > 0 {
+			seek.spec += ","
+		}
+		seek.spec += key.Field
+		if key.Desc {
+			seek.spec += ":desc"
+		}
+	}
+
+	if position != nil {
+		// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... with the comparison following each key direction
+		conditions := make([]clause.Expression, len(keys))
+		for i, key := range keys {
+			expressions := make([]clause.Expression, 0, i+1)
+			for j := 0; j < i; j++ {
+				expressions = append(expressions, clause.Eq{Column: columns[j], Value: values[j]})
+			}
+			if key.Desc == seek.prev {
+				expressions = append(expressions, clause.Gt{Column: columns[i], Value: values[i]})
+			} else {
+				expressions = append(expressions, clause.Lt{Column: columns[i], Value: values[i]})
+			}
+			conditions[i] = clause.And(expressions...)
+		}
+		db = db.Where(clause.Or(conditions...))
+	}
+
+	// a sparse fieldset still needs the sort columns to build the cursors
+	if len(db.Statement.Selects) > 0 {
+		selects := append([]string{}, db.Statement.Selects...)
+		for _, column := range columns {
+			selects = append(selects, column.Name)
+		}
+		db = db.Select(uniqueStrings(selects))
+	}
+
+	// a previous page is read backward and reversed once found
+	for i, key := range keys {
+		db = db.Order(clause.OrderByColumn{Column: columns[i], Desc: key.Desc != seek.prev})
+	}
+	p.seek = seek
+	return db.Limit(seek.limit + 1), nil
+}
+
+func (s *cursorSeek) cursor(db *gorm.DB, row reflect.Value, prev bool) (*string, error) {
+	cursor := cursorDto{
+		Sort:   s.spec,
+		Values: make([]json.RawMessage, len(s.fields)),
+		Prev:   prev,
+	}
+	for i, field := range s.fields {
+		value, _ := field.ValueOf(db.Statement.Context, row)
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		cursor.Values[i] = data
+	}
+	result := encodeCursor(cursor)
+	return &result, nil
+}
+
+// SetCursors trims the look ahead row of a cursor query and sets the next and previous cursors
+func (p *PaginationDto) SetCursors(db *gorm.DB, entities interface{}) error {
+	if p == nil || p.seek == nil {
+		return nil
+	}
+	seek := p.seek
+
+	rows := reflect.ValueOf(entities).Elem()
+	hasMore := rows.Len() > seek.limit
+	if hasMore {
+		rows.Set(rows.Slice(0, seek.limit))
+	}
+	if seek.prev {
+		for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+			first, last := rows.Index(i).Interface(), rows.Index(j).Interface()
+			rows.Index(i).Set(reflect.ValueOf(last))
+			rows.Index(j).Set(reflect.ValueOf(first))
+		}
+	}
+	if rows.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if hasMore && !seek.prev || seek.prev {
+		if p.NextCursor, err = seek.cursor(db, rows.Index(rows.Len()-1), false); err != nil {
+			return err
+		}
+	}
+	if hasMore && seek.prev || !seek.prev && seek.position {
+		if p.PrevCursor, err = seek.cursor(db, rows.Index(0), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
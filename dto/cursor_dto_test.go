@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+var testCursorConfig = SortConfig{
+	Fields:  map[string]string{"id": "id", "name": "name", "level": "level"},
+	Default: "level:desc",
+}
+
+func cursorPage(t *testing.T, db *gorm.DB, cursor, sort string) ([]string, *PaginationDto) {
+	limit := 3
+	pagination := &PaginationDto{Limit: &limit, Cursor: &cursor}
+	var entities []filterUser
+
+	query, err := pagination.Paginate(db.Model(&filterUser{}), &SortDto{Sort: &sort}, testCursorConfig)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, query.Find(&entities).Error, nil)
+	assert.Equal(t, pagination.SetCursors(query, &entities), nil)
+
+	ids := make([]string, len(entities))
+	for i, entity := range entities {
+		ids[i] = entity.Id
+	}
+	return ids, pagination
+}
+
+func TestCursorPagination(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "cursor.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, db.AutoMigrate(&filterUser{}), nil)
+	for i := 1; i <= 7; i++ {
+		user := filterUser{Id: fmt.Sprintf("u%d", i), Name: fmt.Sprintf("n%d", i%3), Level: i % 2}
+		assert.Equal(t, db.Create(&user).Error, nil)
+	}
+
+	// name asc with ties broken by id
+	ids, page := cursorPage(t, db, "", "name:asc")
+	assert.Equal(t, ids, []string{"u3", "u6", "u1"})
+	assert.Equal(t, page.PrevCursor, (*string)(nil))
+
+	ids, page = cursorPage(t, db, *page.NextCursor, "")
+	assert.Equal(t, ids, []string{"u4", "u7", "u2"})
+
+	ids, last := cursorPage(t, db, *page.NextCursor, "")
+	assert.Equal(t, ids, []string{"u5"})
+	assert.Equal(t, last.NextCursor, (*string)(nil))
+
+	ids, page = cursorPage(t, db, *last.PrevCursor, "")
+	assert.Equal(t, ids, []string{"u4", "u7", "u2"})
+	ids, page = cursorPage(t, db, *page.PrevCursor, "")
+	assert.Equal(t, ids, []string{"u3", "u6", "u1"})
+	assert.Equal(t, page.PrevCursor, (*string)(nil))
+
+	invalid := "not-a-cursor"
+	_, err = (&PaginationDto{Cursor: &invalid}).Paginate(db.Model(&filterUser{}), nil, testCursorConfig)
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,195 @@
+package dto
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// IncludeConfig maps an include to the relation it preloads and the foreign key columns the preload needs
+type IncludeConfig struct {
+	Preload string
+	Columns []string
+}
+
+// FieldsConfig whitelists the selectable fields mapped to their columns and the relations a list may include
+type FieldsConfig struct {
+	Fields   map[string]string
+	Includes map[string]IncludeConfig
+}
+
+// FieldsDto holds a comma separated sparse fieldset and a comma separated list of relations to include
+type FieldsDto struct {
+	Fields  *string `json:"fields" form:"fields" uri:"fields"`
+	Include *string `json:"include" form:"include" uri:"include"`
+}
+
+func splitList(value *string) []string {
+	if value == nil {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(*value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// FieldNames returns the requested fields validated against the config, empty when every field is wanted
+func (f *FieldsDto) FieldNames(config FieldsConfig) ([]string, error) {
+	if f == nil {
+		return nil, nil
+	}
+	fields := splitList(f.Fields)
+	for _, field := range fields {
+		if _, ok := config.Fields[field]; !ok {
+			return nil, fmt.Errorf("field '%s' is not allowed", field)
+		}
+	}
+	return fields, nil
+}
+
+// Includes returns the requested relations validated against the config, a nil dto includes every relation
+func (f *FieldsDto) Includes(config FieldsConfig) (map[string]bool, error) {
+	includes := make(map[string]bool, len(config.Includes))
+	if f == nil {
+		for name := range config.Includes {
+			includes[name] = true
+		}
+		return includes, nil
+	}
+	for _, name := range splitList(f.Include) {
+		if _, ok := config.Includes[name]; !ok {
+			return nil, fmt.Errorf("including '%s' is not allowed", name)
+		}
+		// role.permissions implies role
+		for parts := strings.Split(name, "."); len(parts) > 0; parts = parts[:len(parts)-1] {
+			includes[strings.Join(parts, ".")] = true
+		}
+	}
+	return includes, nil
+}
+
+// Apply selects the requested columns and preloads the requested relations
+func (f *FieldsDto) Apply(db *gorm.DB, config FieldsConfig) (*gorm.DB, error) {
+	fields, err := f.FieldNames(config)
+	if err != nil {
+		return db, err
+	}
+	includes, err := f.Includes(config)
+	if err != nil {
+		return db, err
+	}
+
+	// preloads run in a sorted order so parents load before their nested relations
+	names := make([]string, 0, len(includes))
+	for name := range includes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var columns []string
+	if len(fields) > 0 {
+		columns = append(columns, "id")
+		for _, field := range fields {
+			if column := config.Fields[field]; column != "" {
+				columns = append(columns, column)
+			}
+		}
+	}
+	for _, name := range names {
+		include := config.Includes[name]
+		db = db.Preload(include.Preload)
+		columns = append(columns, include.Columns...)
+	}
+
+	if len(fields) > 0 {
+		db = db.Select(uniqueStrings(columns))
+	}
+	return db, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	results := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			results = append(results, value)
+		}
+	}
+	return results
+}
+
+// Project serializes the items keeping only the requested fields and included relations
+func Project[T any](items []T, f *FieldsDto, config FieldsConfig) ([]map[string]interface{}, error) {
+	fields, err := f.FieldNames(config)
+	if err != nil {
+		return nil, err
+	}
+	includes, err := f.Includes(config)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		var result map[string]interface{}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, err
+		}
+
+		if len(fields) > 0 {
+			keep := make(map[string]bool, len(fields)+len(includes))
+			keep["id"] = true
+			for _, field := range fields {
+				keep[field] = true
+			}
+			for name := range includes {
+				keep[strings.Split(name, ".")[0]] = true
+			}
+			for key := range result {
+				if !keep[key] {
+					delete(result, key)
+				}
+			}
+		}
+		pruneRelations(result, "", config, includes)
+		results[i] = result
+	}
+	return results, nil
+}
+
+// pruneRelations drops the relations under prefix that were not included
+func pruneRelations(value interface{}, prefix string, config FieldsConfig, includes map[string]bool) {
+	switch value := value.(type) {
+	case []interface{}:
+		for _, item := range value {
+			pruneRelations(item, prefix, config, includes)
+		}
+	case map[string]interface{}:
+		for name := range config.Includes {
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			key := strings.TrimPrefix(name, prefix)
+			if strings.Contains(key, ".") {
+				continue
+			}
+			if !includes[name] {
+				delete(value, key)
+				continue
+			}
+			pruneRelations(value[key], name+".", config, includes)
+		}
+	}
+}
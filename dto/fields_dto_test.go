@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+var testFieldsConfig = FieldsConfig{
+	Fields: map[string]string{"id": "id", "name": "name", "email": "email", "role_id": "role_id"},
+	Includes: map[string]IncludeConfig{
+		"role":             {Preload: "Role", Columns: []string{"role_id"}},
+		"role.permissions": {Preload: "Role.Permissions", Columns: []string{"role_id"}},
+	},
+}
+
+func TestFieldsApply(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "fields.db")), &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := "name,email"
+	tx, err := (&FieldsDto{Fields: &fields}).Apply(db.Model(&filterUser{}), testFieldsConfig)
+	assert.Equal(t, err, nil)
+	statement := tx.Find(&[]filterUser{}).Statement
+	assert.Equal(t, strings.HasPrefix(statement.SQL.String(), "SELECT `id`,`name`,`email` FROM"), true)
+
+	fields = "password"
+	_, err = (&FieldsDto{Fields: &fields}).Apply(db.Model(&filterUser{}), testFieldsConfig)
+	assert.NotEqual(t, err, nil)
+
+	include := "roles"
+	_, err = (&FieldsDto{Include: &include}).Apply(db.Model(&filterUser{}), testFieldsConfig)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestFieldsProject(t *testing.T) {
+	type role struct {
+		Id          string   `json:"id"`
+		Permissions []string `json:"permissions"`
+	}
+	type user struct {
+		Id    string `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+		Role  role   `json:"role"`
+	}
+	users := []user{{Id: "1", Name: "jo", Email: "jo@mail.com", Role: role{Id: "2"}}}
+
+	results, err := Project(users, &FieldsDto{}, testFieldsConfig)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(results[0]), 3)
+	_, ok := results[0]["role"]
+	assert.Equal(t, ok, false)
+
+	fields, include := "name", "role"
+	results, err = Project(users, &FieldsDto{Fields: &fields, Include: &include}, testFieldsConfig)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, results[0], map[string]interface{}{
+		"id":   "1",
+		"name": "jo",
+		"role": map[string]interface{}{"id": "2"},
+	})
+
+	include = "role.permissions"
+	results, err = Project(users, &FieldsDto{Include: &include}, testFieldsConfig)
+	assert.Equal(t, err, nil)
+	_, ok = results[0]["role"].(map[string]interface{})["permissions"]
+	assert.Equal(t, ok, true)
+}
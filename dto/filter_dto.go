@@ -0,0 +1,232 @@
+package dto
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FilterType int
+
+const (
+	FilterString FilterType = iota
+	FilterNumber
+	FilterTime
+	FilterUuid
+	FilterBool
+)
+
+const (
+	FilterEq      = "eq"
+	FilterNe      = "ne"
+	FilterLike    = "like"
+	FilterIlike   = "ilike"
+	FilterIn      = "in"
+	FilterGt      = "gt"
+	FilterGte     = "gte"
+	FilterLt      = "lt"
+	FilterLte     = "lte"
+	FilterBetween = "between"
+)
+
+// filterOperators lists the operators allowed for each field type
+var filterOperators = map[FilterType][]string{
+	FilterString: {FilterEq, FilterNe, FilterLike, FilterIlike, FilterIn},
+	FilterNumber: {FilterEq, FilterNe, FilterIn, FilterGt, FilterGte, FilterLt, FilterLte, FilterBetween},
+	FilterTime:   {FilterEq, FilterGt, FilterGte, FilterLt, FilterLte, FilterBetween},
+	FilterUuid:   {FilterEq, FilterNe, FilterIn},
+	FilterBool:   {FilterEq, FilterNe},
+}
+
+// filterKey matches query keys like name[like] or role.name[eq]
+var filterKey = regexp.MustCompile(`^([a-z_]+(?:\.[a-z_]+)?)\[([a-z]+)\]$`)
+
+// FilterRelation filters through the foreign key of a related model
+type FilterRelation struct {
+	Model      interface{}
+	ForeignKey string
+}
+
+// FilterField whitelists a filterable field and maps it to a column
+type FilterField struct {
+	Column   string
+	Type     FilterType
+	Relation *FilterRelation
+}
+
+// FilterConfig defines the filterable fields and free text search columns of a module
+type FilterConfig struct {
+	Fields map[string]FilterField
+	Search []string
+}
+
+type FilterCondition struct {
+	Field    string
+	Operator string
+	Value    string
+}
+
+// FilterDto holds conditions written as field[operator]=value and the q free text search
+type FilterDto struct {
+	Q          *string           `json:"q" form:"q"`
+	Conditions []FilterCondition `json:"-" form:"-"`
+}
+
+// BindQuery collects the filter conditions of the query string
+func (f *FilterDto) BindQuery(query url.Values) error {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f.Conditions = nil
+	for _, key := range keys {
+		match := filterKey.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		for _, value := range query[key] {
+			f.Conditions = append(f.Conditions, FilterCondition{
+				Field:    match[1],
+				Operator: match[2],
+				Value:    value,
+			})
+		}
+	}
+	return nil
+}
+
+func parseFilterValue(fieldType FilterType, value string) (interface{}, error) {
+	switch fieldType {
+	case FilterNumber:
+		return strconv.ParseFloat(value, 64)
+	case FilterTime:
+		if date, err := time.Parse("2006-01-02", value); err == nil {
+			return date, nil
+		}
+		return time.Parse(time.RFC3339, value)
+	case FilterUuid:
+		return uuid.Parse(value)
+	case FilterBool:
+		return strconv.ParseBool(value)
+	}
+	return value, nil
+}
+
+func (c FilterCondition) expression(field FilterField) (clause.Expression, error) {
+	allowed := false
+	for _, operator := range filterOperators[field.Type] {
+		allowed = allowed || operator == c.Operator
+	}
+	if !allowed {
+		return nil, fmt.Errorf("operator '%s' is not allowed on '%s'", c.Operator, c.Field)
+	}
+
+	table := clause.CurrentTable
+	if field.Relation != nil {
+		table = ""
+	}
+	column := clause.Column{Table: table, Name: field.Column}
+
+	rawValues := []string{c.Value}
+	if c.Operator == FilterIn || c.Operator == FilterBetween {
+		rawValues = strings.Split(c.Value, ",")
+	}
+	if c.Operator == FilterBetween && len(rawValues) != 2 {
+		return nil, fmt.Errorf("'%s' between needs two values", c.Field)
+	}
+	values := make([]interface{}, len(rawValues))
+	for i, rawValue := range rawValues {
+		value, err := parseFilterValue(field.Type, strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value of '%s': %w", c.Field, err)
+		}
+		values[i] = value
+	}
+
+	switch c.Operator {
+	case FilterEq:
+		return clause.Eq{Column: column, Value: values[0]}, nil
+	case FilterNe:
+		return clause.Neq{Column: column, Value: values[0]}, nil
+	case FilterLike:
+		return clause.Expr{SQL: "? LIKE ? ESCAPE '" + likeEscape + "'", Vars: []interface{}{column, containing(c.Value)}}, nil
+	case FilterIlike:
+		return clause.Expr{SQL: "LOWER(?) LIKE ? ESCAPE '" + likeEscape + "'", Vars: []interface{}{column, containing(strings.ToLower(c.Value))}}, nil
+	case FilterIn:
+		return clause.IN{Column: column, Values: values}, nil
+	case FilterGt:
+		return clause.Gt{Column: column, Value: values[0]}, nil
+	case FilterGte:
+		return clause.Gte{Column: column, Value: values[0]}, nil
+	case FilterLt:
+		return clause.Lt{Column: column, Value: values[0]}, nil
+	case FilterLte:
+		return clause.Lte{Column: column, Value: values[0]}, nil
+	case FilterBetween:
+		return clause.And(
+			clause.Gte{Column: column, Value: values[0]},
+			clause.Lte{Column: column, Value: values[1]},
+		), nil
+	}
+	return nil, fmt.Errorf("unknown operator '%s'", c.Operator)
+}
+
+// likeEscape escapes the wildcards of LIKE patterns, backslash is avoided as mysql string literals treat it specially
+const likeEscape = "!"
+
+var likeEscaper = strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_", "[", likeEscape+"[")
+
+// containing returns the LIKE pattern matching values containing value, its wildcards match themselves
+func containing(value string) string {
+	return "%" + likeEscaper.Replace(value) + "%"
+}
+
+// Apply translates the conditions allowed by the config into where clauses
+func (f FilterDto) Apply(db *gorm.DB, config FilterConfig) (*gorm.DB, error) {
+	for _, condition := range f.Conditions {
+		field, ok := config.Fields[condition.Field]
+		if !ok {
+			return db, fmt.Errorf("filtering on '%s' is not allowed", condition.Field)
+		}
+		expression, err := condition.expression(field)
+		if err != nil {
+			return db, err
+		}
+
+		if field.Relation != nil {
+			subQuery := db.Session(&gorm.Session{NewDB: true}).
+				Model(field.Relation.Model).
+				Select("id").
+				Where(expression)
+			db = db.Where(clause.Expr{
+				SQL:  "? IN (?)",
+				Vars: []interface{}{clause.Column{Table: clause.CurrentTable, Name: field.Relation.ForeignKey}, subQuery},
+			})
+			continue
+		}
+		db = db.Where(expression)
+	}
+
+	if f.Q != nil && *f.Q != "" && len(config.Search) > 0 {
+		search := containing(strings.ToLower(*f.Q))
+		expressions := make([]clause.Expression, len(config.Search))
+		for i, column := range config.Search {
+			expressions[i] = clause.Expr{
+				SQL:  "LOWER(?) LIKE ? ESCAPE '" + likeEscape + "'",
+				Vars: []interface{}{clause.Column{Table: clause.CurrentTable, Name: column}, search},
+			}
+		}
+		db = db.Where(clause.Or(expressions...))
+	}
+	return db, nil
+}
@@ -0,0 +1,120 @@
+package dto
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+type filterRole struct {
+	Id   string
+	Name string
+}
+
+type filterUser struct {
+	Id     string
+	Name   string
+	Email  string
+	Level  int
+	RoleId string
+}
+
+var testFilterConfig = FilterConfig{
+	Fields: map[string]FilterField{
+		"name":  {Column: "name", Type: FilterString},
+		"level": {Column: "level", Type: FilterNumber},
+		"role.name": {
+			Column:   "name",
+			Type:     FilterString,
+			Relation: &FilterRelation{Model: &filterRole{}, ForeignKey: "role_id"},
+		},
+	},
+	Search: []string{"name", "email"},
+}
+
+func filterSQL(t *testing.T, query string) (string, error) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "filter.db")), &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var filter FilterDto
+	if err := filter.BindQuery(values); err != nil {
+		return "", err
+	}
+	if q := values.Get("q"); q != "" {
+		filter.Q = &q
+	}
+
+	tx, err := filter.Apply(db.Model(&filterUser{}), testFilterConfig)
+	if err != nil {
+		return "", err
+	}
+	statement := tx.Find(&[]filterUser{}).Statement
+	return db.Dialector.Explain(statement.SQL.String(), statement.Vars...), nil
+}
+
+func TestFilterApply(t *testing.T) {
+	sql, err := filterSQL(t, "name[ilike]=Jo&level[between]=1,3&limit=10")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(sql, "LOWER(`filter_users`.`name`) LIKE \"%jo%\" ESCAPE '!'"), true)
+	assert.Equal(t, strings.Contains(sql, "`filter_users`.`level` >= 1 AND `filter_users`.`level` <= 3"), true)
+
+	sql, err = filterSQL(t, "role.name[in]=admin,super&q=ann")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, strings.Contains(sql, "`filter_users`.`role_id` IN (SELECT `id` FROM `filter_roles` WHERE `name` IN (\"admin\",\"super\"))"), true)
+	assert.Equal(t, strings.Contains(sql, "(LOWER(`filter_users`.`name`) LIKE \"%ann%\" ESCAPE '!' OR LOWER(`filter_users`.`email`) LIKE \"%ann%\" ESCAPE '!')"), true)
+}
+
+func TestFilterRejects(t *testing.T) {
+	_, err := filterSQL(t, "password[eq]=secret")
+	assert.NotEqual(t, err, nil)
+
+	_, err = filterSQL(t, "name[gt]=a")
+	assert.NotEqual(t, err, nil)
+
+	_, err = filterSQL(t, "level[eq]=abc")
+	assert.NotEqual(t, err, nil)
+}
+
+func TestFilterLikeEscapes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "like.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, db.Exec("CREATE TABLE filter_users (id text PRIMARY KEY, name text, email text, level integer, role_id text)").Error, nil)
+	for _, name := range []string{"a_b", "axb", "100%", "1000", "wow!"} {
+		assert.Equal(t, db.Create(&filterUser{Id: name, Name: name}).Error, nil)
+	}
+
+	// wildcards in values match themselves only
+	for query, expected := range map[string][]string{
+		"name[like]=_":    {"a_b"},
+		"name[ilike]=%25": {"100%"},
+		"name[like]=!":    {"wow!"},
+		"q=A_":            {"a_b"},
+	} {
+		values, err := url.ParseQuery(query)
+		assert.Equal(t, err, nil)
+		var filter FilterDto
+		assert.Equal(t, filter.BindQuery(values), nil)
+		if q := values.Get("q"); q != "" {
+			filter.Q = &q
+		}
+		tx, err := filter.Apply(db.Model(&filterUser{}), testFilterConfig)
+		assert.Equal(t, err, nil)
+
+		var names []string
+		assert.Equal(t, tx.Order("name").Pluck("name", &names).Error, nil)
+		assert.Equal(t, names, expected)
+	}
+}
@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"context"
 	"encoding/json"
 	"time"
 )
@@ -9,13 +10,16 @@ type JobDto struct {
 	Value     interface{}
 	CreatedAt time.Time
 	Retry     int
+	RequestId string
 }
 
-func CreateJob(value interface{}) JobDto {
+func CreateJob(ctx context.Context, value interface{}) JobDto {
+	requestId, _ := ctx.Value("request-id").(string)
 	job := JobDto{
 		Value:     value,
 		CreatedAt: time.Now(),
 		Retry:     0,
+		RequestId: requestId,
 	}
 	return job
 }
@@ -13,6 +13,7 @@ type PermissionDto struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DeletedAt   *time.Time `json:"deleted_at"`
+	Version     int64      `json:"version"`
 }
 
 type PostPermissionDto struct {
@@ -26,12 +27,16 @@ type PutPermissionDto struct {
 	Method      string `json:"method"`
 	Module      string `json:"module"`
 	Description string `json:"description"`
+	Version     int64  `json:"-"`
 }
 
 type GetPermissionDto struct {
 	Method *string `json:"method" form:"method"`
 	Module *string `json:"module" form:"module"`
 
+	FilterDto
+	FieldsDto
 	*PaginationDto
 	*SortDto
+	*DeletedDto
 }
@@ -7,15 +7,40 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type IdDto struct {
 	Id string `json:"id" form:"id" uri:"id" binding:"required,uuid"`
 }
 
+// PaginationDto pages by limit & page, or by cursor when cursor is given (empty for the first page)
 type PaginationDto struct {
-	Limit *int `json:"limit" form:"limit" uri:"limit" binding:"omitempty,min=1"`
-	Page  *int `json:"page" form:"page" uri:"page" binding:"omitempty,min=1"`
+	Limit  *int    `json:"limit" form:"limit" uri:"limit" binding:"omitempty,min=1"`
+	Page   *int    `json:"page" form:"page" uri:"page" binding:"omitempty,min=1"`
+	Cursor *string `json:"cursor" form:"cursor" uri:"cursor"`
+	Count  *bool   `json:"count" form:"count" uri:"count"`
+
+	NextCursor *string `json:"-" form:"-"`
+	PrevCursor *string `json:"-" form:"-"`
+
+	seek *cursorSeek
+}
+
+// IsCursor reports whether the cursor pagination mode is requested
+func (p *PaginationDto) IsCursor() bool {
+	return p != nil && p.Cursor != nil
+}
+
+// Counted reports whether the total should be counted, by default only in page mode
+func (p *PaginationDto) Counted() bool {
+	if p == nil {
+		return true
+	}
+	if p.Count != nil {
+		return *p.Count
+	}
+	return !p.IsCursor()
 }
 
 func (p PaginationDto) Apply(db *gorm.DB) *gorm.DB {
@@ -31,22 +56,103 @@ func (p PaginationDto) Apply(db *gorm.DB) *gorm.DB {
 	return db
 }
 
+type DeletedDto struct {
+	IncludeDeleted *bool `json:"include_deleted" form:"include_deleted" uri:"include_deleted"`
+}
+
+func (d DeletedDto) Apply(db *gorm.DB) *gorm.DB {
+	if d.IncludeDeleted != nil && *d.IncludeDeleted {
+		return db.Unscoped()
+	}
+	return db
+}
+
+// SortDto holds a sort spec of comma separated field:direction keys, e.g. name:asc,created_at:desc
 type SortDto struct {
 	Sort *string `json:"sort" form:"sort" uri:"sort"`
 }
 
-func (s SortDto) Apply(db *gorm.DB) *gorm.DB {
-	sorts := strings.Split(*s.Sort, ":")
-	if len(sorts) == 2 {
-		column := sorts[0]
-		order := sorts[1]
+// SortConfig whitelists the sortable fields mapped to their columns, Default is the spec used when none is given
+type SortConfig struct {
+	Fields  map[string]string
+	Default string
+}
+
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort parses a sort spec into its keys
+func ParseSort(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		field, direction, _ := strings.Cut(part, ":")
+		key := SortKey{Field: strings.TrimSpace(field)}
+		switch strings.ToLower(strings.TrimSpace(direction)) {
+		case "", "asc":
+		case "desc":
+			key.Desc = true
+		default:
+			return nil, fmt.Errorf("invalid sort direction '%s'", direction)
+		}
+		if seen[key.Field] {
+			return nil, fmt.Errorf("field '%s' is sorted more than once", key.Field)
+		}
+		seen[key.Field] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Keys returns the whitelisted sort keys, ending with id so the order is deterministic
+func (s *SortDto) Keys(config SortConfig) ([]SortKey, error) {
+	spec := config.Default
+	if s != nil && s.Sort != nil && strings.TrimSpace(*s.Sort) != "" {
+		spec = *s.Sort
+	}
+	keys, err := ParseSort(spec)
+	if err != nil {
+		return nil, err
+	}
 
-		if len(column) > 0 && len(order) > 0 {
-			order := fmt.Sprintf("%s %s", column, order)
-			return db.Order(order)
+	hasId := false
+	for _, key := range keys {
+		column, ok := config.Fields[key.Field]
+		if !ok && key.Field != "id" {
+			return nil, fmt.Errorf("sorting on '%s' is not allowed", key.Field)
+		}
+		hasId = hasId || column == "id" || key.Field == "id"
+	}
+	if !hasId {
+		keys = append(keys, SortKey{Field: "id"})
+	}
+	return keys, nil
+}
+
+// Apply orders the query by the whitelisted columns of the sort spec
+func (s *SortDto) Apply(db *gorm.DB, config SortConfig) (*gorm.DB, error) {
+	keys, err := s.Keys(config)
+	if err != nil {
+		return db, err
+	}
+	for _, key := range keys {
+		column, ok := config.Fields[key.Field]
+		if !ok {
+			column = key.Field
 		}
+		db = db.Order(clause.OrderByColumn{
+			Column: clause.Column{Table: clause.CurrentTable, Name: column},
+			Desc:   key.Desc,
+		})
 	}
-	return db.Order("created_at DESC")
+	return db, nil
 }
 
 func BindFile(c *gin.Context) (*multipart.File, error) {
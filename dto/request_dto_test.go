@@ -0,0 +1,50 @@
+package dto
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+var testSortConfig = SortConfig{
+	Fields: map[string]string{
+		"name":       "name",
+		"first_name": "firstname",
+		"created_at": "created_at",
+	},
+	Default: "created_at:desc",
+}
+
+func sortSQL(t *testing.T, sort *SortDto) (string, error) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "sort.db")), &gorm.Config{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := sort.Apply(db.Model(&filterUser{}), testSortConfig)
+	if err != nil {
+		return "", err
+	}
+	return tx.Find(&[]filterUser{}).Statement.SQL.String(), nil
+}
+
+func TestSortApply(t *testing.T) {
+	spec := "first_name:desc, name"
+	sql, err := sortSQL(t, &SortDto{Sort: &spec})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, sql, "SELECT * FROM `filter_users` ORDER BY `filter_users`.`firstname` DESC,`filter_users`.`name`,`filter_users`.`id`")
+
+	// a nil sort falls back to the default
+	sql, err = sortSQL(t, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, sql, "SELECT * FROM `filter_users` ORDER BY `filter_users`.`created_at` DESC,`filter_users`.`id`")
+}
+
+func TestSortRejects(t *testing.T) {
+	for _, spec := range []string{"password:asc", "name:sideways", "name;DROP TABLE users", "name,name:desc"} {
+		_, err := sortSQL(t, &SortDto{Sort: &spec})
+		assert.NotEqual(t, err, nil)
+	}
+}
@@ -2,6 +2,66 @@ package dto
 
 // ListDto struct defines http response of users
 type ListDto[T any] struct {
-	Data  []T   `json:"data"`
-	Total int64 `json:"totalItem"`
+	Data       []T       `json:"data"`
+	Total      *int64    `json:"totalItem,omitempty"`
+	Page       *int      `json:"page,omitempty"`
+	Limit      *int      `json:"limit,omitempty"`
+	TotalPages *int64    `json:"total_pages,omitempty"`
+	HasNext    bool      `json:"has_next"`
+	NextCursor *string   `json:"next_cursor,omitempty"`
+	PrevCursor *string   `json:"prev_cursor,omitempty"`
+	Links      *LinksDto `json:"links,omitempty"`
+}
+
+// LinksDto struct defines the navigation links of a list response
+type LinksDto struct {
+	Self  string `json:"self"`
+	First string `json:"first,omitempty"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// NewListDto creates the list response with its page metadata, the total is left out when it was not counted
+func NewListDto[T any](data []T, total int64, pagination *PaginationDto) ListDto[T] {
+	result := ListDto[T]{Data: data}
+	counted := pagination.Counted()
+	if counted {
+		result.Total = &total
+	}
+
+	if pagination.IsCursor() {
+		limit := defaultCursorLimit
+		if pagination.Limit != nil {
+			limit = *pagination.Limit
+		}
+		result.Limit = &limit
+		result.HasNext = pagination.NextCursor != nil
+		result.NextCursor = pagination.NextCursor
+		result.PrevCursor = pagination.PrevCursor
+		return result
+	}
+
+	page := 1
+	if pagination != nil && pagination.Page != nil {
+		page = *pagination.Page
+	}
+	result.Page = &page
+	if pagination == nil || pagination.Limit == nil {
+		// unpaged lists hold every row in a single page
+		totalPages := int64(1)
+		result.TotalPages = &totalPages
+		return result
+	}
+
+	limit := *pagination.Limit
+	result.Limit = &limit
+	if counted {
+		totalPages := (total + int64(limit) - 1) / int64(limit)
+		result.TotalPages = &totalPages
+		result.HasNext = int64(page) < totalPages
+	} else {
+		result.HasNext = len(data) == limit
+	}
+	return result
 }
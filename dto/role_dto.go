@@ -12,6 +12,7 @@ type RoleDto struct {
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at"`
 	DeletedAt   *time.Time      `json:"deleted_at"`
+	Version     int64           `json:"version"`
 }
 
 type PostRoleDto struct {
@@ -27,11 +28,15 @@ type PutRoleDto struct {
 	Description string  `json:"description"`
 	Level       int     `json:"level"`
 	Permissions []IdDto `json:"permissions"`
+	Version     int64   `json:"-"`
 }
 
 type GetRoleDto struct {
 	Name *string `json:"name" form:"name"`
 
+	FilterDto
+	FieldsDto
 	*PaginationDto
 	*SortDto
+	*DeletedDto
 }
@@ -1,12 +1,70 @@
 package dto
 
+import (
+	"mime/multipart"
+	"time"
+)
+
 // SettingDto struct defines dto of setting entity
 type SettingDto struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
+	// Secret settings are write only, their value is masked and Set tells whether they have one
+	Secret bool `json:"secret"`
+	Set    bool `json:"set"`
 }
 
 type PostSettingDto struct {
 	Name  string `json:"name" binding:"required"`
 	Value string `json:"value" binding:"required"`
 }
+
+// SettingHistoryDto struct defines dto of a change of a setting
+type SettingHistoryDto struct {
+	Name      string    `json:"name"`
+	Version   int64     `json:"version"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	Origin    string    `json:"origin"`
+	ActorId   string    `json:"actor_id"`
+	ActorName string    `json:"actor_name"`
+	RequestId string    `json:"request_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RollbackSettingDto struct {
+	Name    string `uri:"name" binding:"required"`
+	Version int64  `uri:"version" binding:"required,min=1"`
+}
+
+// SettingSnapshotDto struct defines dto of the values of every setting at a point in time
+type SettingSnapshotDto struct {
+	Id        string            `json:"id"`
+	Name      string            `json:"name"`
+	Values    map[string]string `json:"values"`
+	ActorId   string            `json:"actor_id"`
+	ActorName string            `json:"actor_name"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type PostSettingSnapshotDto struct {
+	Name string `json:"name"`
+}
+
+// SettingChangeDto struct defines dto of a change an import makes to a setting
+type SettingChangeDto struct {
+	Name     string  `json:"name"`
+	Action   string  `json:"action"`
+	OldValue *string `json:"old_value"`
+	NewValue string  `json:"new_value"`
+}
+
+type ExportSettingDto struct {
+	Format string `form:"format" binding:"omitempty,oneof=json yaml"`
+}
+
+// ImportSettingDto is the form of a settings import, File is a yaml or json object of names to values
+type ImportSettingDto struct {
+	File   *multipart.FileHeader `form:"file" binding:"required" swaggerignore:"true"`
+	DryRun bool                  `json:"dry_run" form:"dry_run"`
+}
@@ -21,8 +21,10 @@ type UserDto struct {
 
 	LastLogin *time.Time `json:"last_login"`
 
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	Version   int64      `json:"version"`
 }
 
 type PostUserDto struct {
@@ -46,6 +48,7 @@ type PutUserDto struct {
 	Email     string                `json:"email" form:"email" binding:"email"`
 	Avatar    *multipart.FileHeader `form:"avatar" swaggerignore:"true"`
 	RoleId    string                `json:"role_id" form:"role_id" binding:"required,uuid"`
+	Version   int64                 `json:"-" form:"-"`
 }
 
 type GetUserDto struct {
@@ -53,8 +56,11 @@ type GetUserDto struct {
 	Email  *string `json:"email" form:"email" uri:"email"`
 	RoleId *string `json:"role_id" form:"role_id" uri:"role_id" binding:"omitempty,uuid"`
 
+	FilterDto
+	FieldsDto
 	*PaginationDto
 	*SortDto
+	*DeletedDto
 }
 
 type UserChannelDto struct {
@@ -0,0 +1,59 @@
+package dto
+
+import (
+	"mime/multipart"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/constant/enums"
+)
+
+// ImportUserDto is the form of a bulk user import. Mapping is a JSON object of user field to column header,
+// fields are name, email, password, first_name, last_name, title, role_id and role
+type ImportUserDto struct {
+	File    *multipart.FileHeader `form:"file" binding:"required" swaggerignore:"true"`
+	Mapping string                `json:"mapping" form:"mapping"`
+	DryRun  bool                  `json:"dry_run" form:"dry_run"`
+}
+
+// UserImportRowDto reports the validation of a row of an import file
+type UserImportRowDto struct {
+	Row    int      `json:"row"`
+	Name   string   `json:"name"`
+	Email  string   `json:"email"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// UserImportJobRowDto carries a valid row to the import job, the password of User is hashed
+type UserImportJobRowDto struct {
+	Row  int
+	User UserDto
+}
+
+// UserImportJobDto is the payload of a queued import commit
+type UserImportJobDto struct {
+	ImportId string
+	Rows     []UserImportJobRowDto
+}
+
+// UserImportDto reports the validation or the progress of a bulk user import
+type UserImportDto struct {
+	Id         string             `json:"id,omitempty"`
+	Filename   string             `json:"filename"`
+	DryRun     bool               `json:"dry_run"`
+	Status     enums.JobStatus    `json:"status,omitempty"`
+	Total      int                `json:"total"`
+	Valid      int                `json:"valid"`
+	Invalid    int                `json:"invalid"`
+	Processed  int                `json:"processed"`
+	Failed     int                `json:"failed"`
+	Rows       []UserImportRowDto `json:"rows,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// ExportUserDto filters users to export the same way GetUserDto does
+type ExportUserDto struct {
+	GetUserDto
+	Format string `json:"format" form:"format" binding:"omitempty,oneof=csv xlsx"`
+}
@@ -54,6 +54,10 @@ const (
 	DismissedError        = "Dismissed"
 	dismissedErrorMessage = "Operation dismissed"
 
+	// PreconditionFailedError indicates a write against an outdated version of a record
+	PreconditionFailedError        = "PreconditionFailed"
+	preconditionFailedErrorMessage = "Record has been modified"
+
 	// StorageError indicates an error in storage operation
 	StorageError        = "StorageError"
 	storageErrorMessage = "Storage error"
@@ -88,6 +92,8 @@ var (
 	ErrUnsupported = errors.New(UnsupportedError)
 	// ErrDismissed indicates an error because the app have dismissed the request
 	ErrDismissed = errors.New(DismissedError)
+	// ErrPreconditionFailed indicates an error because the record was modified by another request
+	ErrPreconditionFailed = errors.New(PreconditionFailedError)
 	// ErrStorage indicates an error when doing storage operation
 	ErrStorage = errors.New(StorageError)
 	// ErrUnknownError indicates an error that the app cannot find the cause for
@@ -135,6 +141,8 @@ func NewAppErrorWithType(errType string) *AppError {
 		err = ErrUnsupported
 	case DismissedError:
 		err = ErrDismissed
+	case PreconditionFailedError:
+		err = ErrPreconditionFailed
 	case StorageError:
 		err = ErrStorage
 	default:
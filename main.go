@@ -12,10 +12,12 @@ import (
 
 	"github.com/ericmarcelinotju/gram/plugins/cache"
 	"github.com/ericmarcelinotju/gram/plugins/database"
+	"github.com/ericmarcelinotju/gram/plugins/database/backup"
 	"github.com/ericmarcelinotju/gram/plugins/job"
 	"github.com/ericmarcelinotju/gram/plugins/notifier"
 	"github.com/ericmarcelinotju/gram/plugins/storage"
 
+	auditModule "github.com/ericmarcelinotju/gram/module/audit"
 	authModule "github.com/ericmarcelinotju/gram/module/auth"
 	permissionModule "github.com/ericmarcelinotju/gram/module/permission"
 	roleModule "github.com/ericmarcelinotju/gram/module/role"
@@ -23,9 +25,16 @@ import (
 	userModule "github.com/ericmarcelinotju/gram/module/user"
 	websocketStore "github.com/ericmarcelinotju/gram/plugins/websocket"
 
+	auditScheduler "github.com/ericmarcelinotju/gram/scheduler/audit"
+	backupScheduler "github.com/ericmarcelinotju/gram/scheduler/backup"
 	exampleScheduler "github.com/ericmarcelinotju/gram/scheduler/example"
+	purgeScheduler "github.com/ericmarcelinotju/gram/scheduler/purge"
+	userScheduler "github.com/ericmarcelinotju/gram/scheduler/user"
 
+	"github.com/ericmarcelinotju/gram/model"
 	router "github.com/ericmarcelinotju/gram/router"
+	"github.com/ericmarcelinotju/gram/utils/crypt"
+	"github.com/ericmarcelinotju/gram/utils/logger"
 )
 
 // @securityDefinitions.apikey Auth
@@ -35,6 +44,24 @@ func main() {
 	// get configuration stucts via .env file
 	configuration := config.NewConfig()
 
+	// seal audit hash chain with server key, without it anyone writing the database could recompute the chain
+	if configuration.AuditKey == "" {
+		log.Fatalln("[AUDIT] : AUDIT_KEY is required to seal the audit chain")
+	}
+	model.SetAuditKey(configuration.AuditKey)
+
+	// seal secret settings with the setting keys
+	var settingKeyring *crypt.Keyring
+	if configuration.SettingKeys == "" {
+		log.Println("[SETTING] : SETTING_KEYS is empty, secret settings cannot be saved")
+	} else {
+		keyring, err := crypt.ParseKeyring(configuration.SettingKeys)
+		if err != nil {
+			log.Fatalln("[SETTING] : ", err)
+		}
+		settingKeyring = keyring
+	}
+
 	// establish DB connection
 	db, err := database.Connect(configuration.Database)
 	if err != nil {
@@ -62,6 +89,21 @@ func main() {
 		log.Fatalln("[BACKUP QUEUE] : ", err)
 	}
 
+	// establish user import queue using redis
+	userImportQueue, err := job.ConnectQueue(
+		&config.Queue{
+			Name:            "user-import",
+			Number:          1,
+			PrefetchLimit:   1,
+			PollDuration:    time.Second * 3,
+			ReportBatchSize: 1,
+		},
+		redisCache.Client(),
+	)
+	if err != nil {
+		log.Fatalln("[USER IMPORT QUEUE] : ", err)
+	}
+
 	// initialize websocket dispatcher
 	dispatcher, err := websocketStore.NewDispatcher()
 	if err != nil {
@@ -77,43 +119,49 @@ func main() {
 		}
 	}
 
-	// initialize scheduler for backup worker
-	var scheduler *job.Scheduler
-
-	var forgotEmail *notifier.EmailNotifier
+	// the forgot password email is rebuilt when the smtp settings change
+	forgotEmail := notifier.NewReloadable(nil)
 
 	settingRepo := settingModule.NewRepository(db, redisCache)
+	settingCodec := settingModule.NewCodec(settingKeyring)
+	// setting changes reach the other instances through redis
+	settingEvents := settingModule.NewEvents(redisCache.Client())
 
 	authRepo := authModule.NewRepository(db, redisCache, forgotEmail)
 
-	userRepo := userModule.NewRepository(db, mediaStorage, dispatcher)
+	userRepo := userModule.NewRepository(db, mediaStorage, dispatcher, userImportQueue)
 	roleRepo := roleModule.NewRepository(db)
 	permissionRepo := permissionModule.NewRepository(db)
+	auditRepo := auditModule.NewRepository(db, mediaStorage)
 
 	authSvc := authModule.NewService(authRepo, userRepo)
 
-	userSvc := userModule.NewService(userRepo)
+	userSvc := userModule.NewService(userRepo, database.NewUnitOfWork(db))
 	roleSvc := roleModule.NewService(roleRepo)
 	permissionSvc := permissionModule.NewService(permissionRepo)
+	auditSvc := auditModule.NewService(auditRepo)
 
-	settingSvc := settingModule.NewService(settingRepo, scheduler, forgotEmail)
+	settingSvc := settingModule.NewService(settingRepo, settingCodec, settingEvents)
 
 	// Setup smtp from setting
-	smtpConf, err := settingSvc.GetSMTPConfig(context.Background())
-	if err != nil {
-		log.Println("[NOREPLY EMAIL] : ", err)
-	}
-	if smtpConf != nil {
-		forgotEmail, err = notifier.NewEmailNotifier(
-			smtpConf,
-			template.Must(template.ParseFiles("./email/template/forgot.html")),
-		)
+	forgotTemplate := template.Must(template.ParseFiles("./email/template/forgot.html"))
+	reloadForgotEmail := func(ctx context.Context, _ settingModule.Event) {
+		smtpConf, err := settingSvc.GetSMTPConfig(ctx)
+		if err != nil {
+			logger.Printf(ctx, "[NOREPLY EMAIL] : %s", err)
+			return
+		}
+		emailNotifier, err := notifier.NewEmailNotifier(smtpConf, forgotTemplate)
 		if err != nil {
-			log.Println("[FORGOT EMAIL] : ", err)
+			logger.Printf(ctx, "[FORGOT EMAIL] : %s", err)
+			return
 		}
+		forgotEmail.Set(emailNotifier)
 	}
+	reloadForgotEmail(context.Background(), settingModule.Event{})
+	settingSvc.Subscribe(settingModule.Subscription{Groups: []string{"smtp"}}, reloadForgotEmail)
 
-	command.ProcessCommands(db)
+	command.ProcessCommands(db, mediaStorage, settingSvc, settingCodec)
 
 	exampleScheduler, err := exampleScheduler.NewScheduler(jobQueue)
 	if err != nil {
@@ -121,6 +169,39 @@ func main() {
 	}
 	exampleScheduler.Start()
 
+	userImportWorker, err := userScheduler.NewWorker(userImportQueue, userSvc)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err = userImportWorker.Start(); err != nil {
+		log.Println("[USER IMPORT] : ", err)
+	}
+
+	retentionScheduler, err := auditScheduler.NewScheduler(auditSvc, settingSvc)
+	if err != nil {
+		log.Println("[AUDIT RETENTION] : ", err)
+	} else if err = retentionScheduler.Start(); err != nil {
+		log.Println("[AUDIT RETENTION] : ", err)
+	}
+
+	softDeleteScheduler, err := purgeScheduler.NewScheduler(userSvc, roleSvc, permissionSvc, settingSvc)
+	if err != nil {
+		log.Println("[PURGE] : ", err)
+	} else if err = softDeleteScheduler.Start(); err != nil {
+		log.Println("[PURGE] : ", err)
+	}
+
+	backupTables, err := backup.Tables(db, model.Entities...)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	databaseBackupScheduler, err := backupScheduler.NewScheduler(backup.NewBackup(db, backupTables), settingSvc, mediaStorage)
+	if err != nil {
+		log.Println("[BACKUP] : ", err)
+	} else if err = databaseBackupScheduler.Start(); err != nil {
+		log.Println("[BACKUP] : ", err)
+	}
+
 	router := router.NewHTTPHandler(
 		authSvc,
 
@@ -130,6 +211,8 @@ func main() {
 
 		settingSvc,
 
+		auditSvc,
+
 		// TODO :: fix this shit
 		jobQueue.Connection,
 		jobQueue,
@@ -0,0 +1 @@
+-- the extension is kept, tables of other schemas may use it
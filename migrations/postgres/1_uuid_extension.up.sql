@@ -0,0 +1,2 @@
+-- the uuid_generate_v4() default of every model
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
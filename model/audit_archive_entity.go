@@ -0,0 +1,73 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ericmarcelinotju/gram/constant/enums"
+	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/google/uuid"
+)
+
+// AuditArchiveEntity struct defines the database model for an audit retention run.
+type AuditArchiveEntity struct {
+	Model
+	EntityName string
+	Cutoff     time.Time
+	Filename   string
+	Total      int64
+	Status     enums.JobStatus
+	Error      string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+}
+
+func (AuditArchiveEntity) TableName() string {
+	return "audit_archives"
+}
+
+// AuditTombstoneEntity keeps the hash of an archived audit so the chain stays verifiable.
+type AuditTombstoneEntity struct {
+	Sequence  int64 `gorm:"primaryKey;autoIncrement:false"`
+	Hash      string
+	ArchiveId uuid.UUID
+}
+
+func (AuditTombstoneEntity) TableName() string {
+	return "audit_tombstones"
+}
+
+func NewAuditArchiveEntity(entity *dto.AuditArchiveDto) *AuditArchiveEntity {
+	id, _ := uuid.Parse(entity.Id)
+
+	return &AuditArchiveEntity{
+		Model: Model{
+			Id:        id,
+			CreatedAt: entity.CreatedAt,
+			UpdatedAt: entity.UpdatedAt,
+		},
+		EntityName: entity.EntityName,
+		Cutoff:     entity.Cutoff,
+		Filename:   entity.Filename,
+		Total:      entity.Total,
+		Status:     entity.Status,
+		Error:      entity.Error,
+		StartedAt:  entity.StartedAt,
+		FinishedAt: entity.FinishedAt,
+	}
+}
+
+func (entity *AuditArchiveEntity) ToDto() *dto.AuditArchiveDto {
+	return &dto.AuditArchiveDto{
+		Id:         entity.Id.String(),
+		EntityName: entity.EntityName,
+		Cutoff:     entity.Cutoff,
+		Filename:   entity.Filename,
+		Total:      entity.Total,
+		Status:     entity.Status,
+		Error:      entity.Error,
+		StartedAt:  entity.StartedAt,
+		FinishedAt: entity.FinishedAt,
+		CreatedAt:  entity.CreatedAt,
+		UpdatedAt:  entity.UpdatedAt,
+	}
+}
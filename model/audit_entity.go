@@ -1,15 +1,33 @@
 package model
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ericmarcelinotju/gram/dto"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+var (
+	auditKey   []byte
+	auditMutex sync.Mutex
+)
+
+// SetAuditKey sets the server key used to seal the audit hash chain
+func SetAuditKey(key string) {
+	auditKey = []byte(key)
+}
+
 // AuditEntity struct defines the database model for a audit.
 type AuditEntity struct {
 	Id            uuid.UUID `gorm:"type:string"`
+	Sequence      int64     `gorm:"uniqueIndex"`
 	Date          time.Time
 	EntityName    string
 	EntityId      string
@@ -17,16 +35,128 @@ type AuditEntity struct {
 	NewValue      string
 	OperationType string
 	Origin        string
+	RequestId     string
 	UserId        uuid.UUID
 	User          UserEntity `gorm:"foreignKey:UserId"`
 	PermissionId  uuid.UUID
 	Permission    PermissionEntity `gorm:"foreignKey:PermissionId"`
+	PrevHash      string
+	Hash          string
 }
 
 func (AuditEntity) TableName() string {
 	return "audits"
 }
 
+// ComputeHash returns the HMAC of the entry content chained with the previous entry hash
+func (entity *AuditEntity) ComputeHash() string {
+	fields := []string{
+		strconv.FormatInt(entity.Sequence, 10),
+		entity.Id.String(),
+		strconv.FormatInt(entity.Date.UnixMilli(), 10),
+		entity.EntityName,
+		entity.EntityId,
+		entity.OldValue,
+		entity.NewValue,
+		entity.OperationType,
+		entity.Origin,
+		entity.UserId.String(),
+		entity.PermissionId.String(),
+		entity.PrevHash,
+	}
+	// entries sealed before request IDs were recorded keep their original hash
+	if entity.RequestId != "" {
+		fields = append(fields, entity.RequestId)
+	}
+
+	mac := hmac.New(sha256.New, auditKey)
+	mac.Write([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// chainRetries bounds how many times an entry is linked again after another writer took its sequence
+const chainRetries = 5
+
+// CreateAudit links the entry to the latest audit row and inserts it.
+// Sequences are unique so concurrent writers, on this instance or another, cannot fork the chain:
+// the writer losing the race links its entry again to the new tail.
+func CreateAudit(tx *gorm.DB, entity *AuditEntity) error {
+	return createChained(tx, []*AuditEntity{entity}, 1)
+}
+
+// CreateAudits links the entries one after the other to the latest audit row and inserts them in batches
+func CreateAudits(tx *gorm.DB, entities []*AuditEntity, batchSize int) error {
+	return createChained(tx, entities, batchSize)
+}
+
+func createChained(tx *gorm.DB, entities []*AuditEntity, batchSize int) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	// writers of this instance wait for each other instead of retrying
+	auditMutex.Lock()
+	defer auditMutex.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		// a savepoint when tx is a transaction, so a conflict leaves the enclosing transaction usable
+		err := tx.Transaction(func(tx *gorm.DB) error {
+			last, err := lastAudit(tx)
+			if err != nil {
+				return err
+			}
+			for _, entity := range entities {
+				entity.link(last)
+				last = entity
+			}
+			return tx.Model(&AuditEntity{}).CreateInBatches(entities, batchSize).Error
+		})
+		if err == nil || attempt == chainRetries {
+			return err
+		}
+
+		var taken int64
+		if countErr := tx.Model(&AuditEntity{}).
+			Where("sequence BETWEEN ? AND ?", entities[0].Sequence, entities[len(entities)-1].Sequence).
+			Count(&taken).Error; countErr != nil || taken == 0 {
+			return err
+		}
+	}
+}
+
+// lastAudit returns the sequence and hash ending the chain
+func lastAudit(tx *gorm.DB) (*AuditEntity, error) {
+	var last AuditEntity
+	if err := tx.Model(&AuditEntity{}).
+		Select("sequence", "hash").
+		Order("sequence DESC").
+		Limit(1).
+		Find(&last).Error; err != nil {
+		return nil, err
+	}
+
+	// the tail of the chain may have been archived
+	var lastTombstone AuditTombstoneEntity
+	if err := tx.Model(&AuditTombstoneEntity{}).
+		Order("sequence DESC").
+		Limit(1).
+		Find(&lastTombstone).Error; err != nil {
+		return nil, err
+	}
+	if lastTombstone.Sequence > last.Sequence {
+		last.Sequence = lastTombstone.Sequence
+		last.Hash = lastTombstone.Hash
+	}
+	return &last, nil
+}
+
+// link places the entry after last in the chain and seals it
+func (entity *AuditEntity) link(last *AuditEntity) {
+	entity.Sequence = last.Sequence + 1
+	entity.PrevHash = last.Hash
+	entity.Date = entity.Date.Truncate(time.Millisecond)
+	entity.Hash = entity.ComputeHash()
+}
+
 func NewAuditEntity(dto *dto.AuditDto) *AuditEntity {
 	id, _ := uuid.Parse(dto.ID)
 	userId, _ := uuid.Parse(dto.UserID)
@@ -34,6 +164,7 @@ func NewAuditEntity(dto *dto.AuditDto) *AuditEntity {
 
 	return &AuditEntity{
 		Id:            id,
+		Sequence:      dto.Sequence,
 		Date:          dto.Date,
 		EntityName:    dto.EntityName,
 		EntityId:      dto.EntityId,
@@ -41,8 +172,11 @@ func NewAuditEntity(dto *dto.AuditDto) *AuditEntity {
 		NewValue:      dto.NewValue,
 		OperationType: dto.OperationType,
 		Origin:        dto.Origin,
+		RequestId:     dto.RequestId,
 		UserId:        userId,
 		PermissionId:  permissionID,
+		PrevHash:      dto.PrevHash,
+		Hash:          dto.Hash,
 	}
 }
 
@@ -50,6 +184,7 @@ func (entity *AuditEntity) ToDto() *dto.AuditDto {
 
 	return &dto.AuditDto{
 		ID:            entity.Id.String(),
+		Sequence:      entity.Sequence,
 		Date:          entity.Date,
 		EntityName:    entity.EntityName,
 		EntityId:      entity.EntityId,
@@ -57,9 +192,12 @@ func (entity *AuditEntity) ToDto() *dto.AuditDto {
 		NewValue:      entity.NewValue,
 		OperationType: entity.OperationType,
 		Origin:        entity.Origin,
+		RequestId:     entity.RequestId,
 		UserID:        entity.UserId.String(),
 		User:          *entity.User.ToDto(),
 		PermissionID:  entity.PermissionId.String(),
 		Permission:    *entity.Permission.ToDto(),
+		PrevHash:      entity.PrevHash,
+		Hash:          entity.Hash,
 	}
 }
@@ -1,7 +1,9 @@
 package model
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,9 +11,60 @@ import (
 )
 
 type Model struct {
-	Id        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4()"`
+	Id        uuid.UUID `gorm:"type:uuid"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	Version   int64 `gorm:"not null;default:1"`
+}
+
+func (m *Model) GetId() uuid.UUID {
+	return m.Id
+}
+
+func (m *Model) GetVersion() int64 {
+	return m.Version
+}
+
+// Entities lists every persisted entity, referenced entities before the ones referencing them
+var Entities = []interface{}{
+	&SettingEntity{},
+	&SettingHistoryEntity{},
+	&SettingSnapshotEntity{},
+	&PermissionEntity{},
+	&RoleEntity{},
+	&UserEntity{},
+	&UserImportEntity{},
+	&AuditEntity{},
+	&AuditArchiveEntity{},
+	&AuditTombstoneEntity{},
+}
+
+// ErrStaleVersion reports a write made against an outdated version of a record
+var ErrStaleVersion = errors.New("record has been modified since it was read")
+
+// Versioned scopes an update of value to the expected version and moves the model to the next version,
+// the stored version is expected when none is given
+func (m *Model) Versioned(tx *gorm.DB, value interface{}, expected int64) (*gorm.DB, error) {
+	if expected == 0 {
+		var versions []int64
+		if err := tx.Session(&gorm.Session{NewDB: true}).
+			Model(value).
+			Where("id = ?", m.Id).
+			Pluck("version", &versions).Error; err != nil {
+			return tx, err
+		}
+		if len(versions) == 0 {
+			return tx, gorm.ErrRecordNotFound
+		}
+		expected = versions[0]
+	}
+	m.Version = expected + 1
+	return tx.Model(value).Where("version = ?", expected), nil
+}
+
+func requestId(ctx context.Context) string {
+	requestId, _ := ctx.Value("request-id").(string)
+	return requestId
 }
 
 func (m *Model) BeforeCreate(tx *gorm.DB) error {
@@ -36,19 +89,18 @@ func (m *Model) BeforeCreate(tx *gorm.DB) error {
 
 	newValue, _ := json.Marshal(tx.Statement.Dest)
 
-	tx.Model(&AuditEntity{}).Create(&AuditEntity{
+	return CreateAudit(tx, &AuditEntity{
 		Id:            uuid.New(),
 		OperationType: "insert",
 		EntityName:    tx.Statement.Table,
 		EntityId:      m.Id.String(),
 		NewValue:      string(newValue),
 		Origin:        "",
+		RequestId:     requestId(ctx),
 		UserId:        userId,
 		PermissionId:  permissionId,
 		Date:          time.Now(),
 	})
-
-	return nil
 }
 
 func (m *Model) BeforeUpdate(tx *gorm.DB) error {
@@ -77,7 +129,7 @@ func (m *Model) BeforeUpdate(tx *gorm.DB) error {
 	oldValue, _ := json.Marshal(result)
 	newValue, _ := json.Marshal(tx.Statement.Dest)
 
-	tx.Model(&AuditEntity{}).Create(&AuditEntity{
+	return CreateAudit(tx, &AuditEntity{
 		Id:            uuid.New(),
 		OperationType: "update",
 		EntityName:    tx.Statement.Table,
@@ -85,12 +137,11 @@ func (m *Model) BeforeUpdate(tx *gorm.DB) error {
 		OldValue:      string(oldValue),
 		NewValue:      string(newValue),
 		Origin:        "",
+		RequestId:     requestId(ctx),
 		UserId:        userId,
 		PermissionId:  permissionId,
 		Date:          time.Now(),
 	})
-
-	return nil
 }
 
 func (m *Model) BeforeDelete(tx *gorm.DB) error {
@@ -110,16 +161,15 @@ func (m *Model) BeforeDelete(tx *gorm.DB) error {
 		return nil
 	}
 
-	tx.Model(&AuditEntity{}).Create(&AuditEntity{
+	return CreateAudit(tx, &AuditEntity{
 		Id:            uuid.New(),
 		OperationType: "delete",
 		EntityName:    tx.Statement.Table,
 		EntityId:      m.Id.String(),
 		Origin:        "",
+		RequestId:     requestId(ctx),
 		UserId:        user.Model.Id,
 		PermissionId:  permission.Model.Id,
 		Date:          time.Now(),
 	})
-
-	return nil
 }
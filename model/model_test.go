@@ -0,0 +1,86 @@
+package model
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type versionedEntity struct {
+	Model
+	Name string
+}
+
+func TestVersioned(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "model.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db = db.WithContext(context.Background())
+	assert.Equal(t, db.Exec(`CREATE TABLE versioned_entities (
+		id TEXT PRIMARY KEY, name TEXT, created_at DATETIME, updated_at DATETIME, version INTEGER NOT NULL DEFAULT 1
+	)`).Error, nil)
+
+	id := uuid.New()
+	assert.Equal(t, db.Create(&versionedEntity{Model: Model{Id: id}, Name: "a"}).Error, nil)
+
+	// a stale version updates nothing
+	entity := &versionedEntity{Model: Model{Id: id}, Name: "b"}
+	query, err := entity.Versioned(db, entity, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, query.Updates(entity).RowsAffected, int64(0))
+
+	// the stored version is expected when none is given
+	entity = &versionedEntity{Model: Model{Id: id}, Name: "c"}
+	query, err = entity.Versioned(db, entity, 0)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, query.Updates(entity).RowsAffected, int64(1))
+
+	var stored versionedEntity
+	assert.Equal(t, db.First(&stored, "id = ?", id).Error, nil)
+	assert.Equal(t, stored.Version, int64(2))
+	assert.Equal(t, stored.Name, "c")
+
+	_, err = (&versionedEntity{Model: Model{Id: uuid.New()}}).Versioned(db, &versionedEntity{}, 0)
+	assert.Equal(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestCreateAuditConflict(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.db")+"?_pragma=journal_mode(WAL)"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range []string{
+		`CREATE TABLE audits (
+			id text PRIMARY KEY, sequence integer UNIQUE, date datetime, entity_name text, entity_id text,
+			old_value text, new_value text, operation_type text, origin text, request_id text,
+			user_id text, permission_id text, prev_hash text, hash text
+		)`,
+		`CREATE TABLE audit_tombstones (sequence integer PRIMARY KEY, hash text, archive_id text)`,
+	} {
+		assert.Equal(t, db.Exec(statement).Error, nil)
+	}
+	SetAuditKey("test-key")
+
+	// another writer takes the sequence after the entry was linked, before it is inserted
+	forked := false
+	err = db.Callback().Create().Before("gorm:create").Register("test:fork", func(tx *gorm.DB) {
+		if forked {
+			return
+		}
+		forked = true
+		other := db.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+		assert.Equal(t, other.Exec("INSERT INTO audits (id, sequence, hash) VALUES (?, 1, 'other')", uuid.NewString()).Error, nil)
+	})
+	assert.Equal(t, err, nil)
+
+	entity := &AuditEntity{Id: uuid.New(), EntityName: "users", OperationType: "insert"}
+	assert.Equal(t, CreateAudit(db, entity), nil)
+	assert.Equal(t, entity.Sequence, int64(2))
+	assert.Equal(t, entity.PrevHash, "other")
+}
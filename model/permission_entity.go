@@ -3,6 +3,7 @@ package model
 import (
 	"github.com/ericmarcelinotju/gram/dto"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // PermissionEntity struct defines the database model for a permission.
@@ -11,6 +12,7 @@ type PermissionEntity struct {
 	Method      string
 	Module      string
 	Description string
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
 }
 
 func (PermissionEntity) PermissionEntity() string {
@@ -25,6 +27,7 @@ func NewPermissionEntity(dto *dto.PermissionDto) *PermissionEntity {
 			Id:        id,
 			CreatedAt: dto.CreatedAt,
 			UpdatedAt: dto.UpdatedAt,
+			Version:   dto.Version,
 		},
 		Method:      dto.Method,
 		Module:      dto.Module,
@@ -33,12 +36,17 @@ func NewPermissionEntity(dto *dto.PermissionDto) *PermissionEntity {
 }
 
 func (entity *PermissionEntity) ToDto() *dto.PermissionDto {
-	return &dto.PermissionDto{
+	permission := &dto.PermissionDto{
 		Id:          entity.Id.String(),
 		Method:      entity.Method,
 		Module:      entity.Module,
 		Description: entity.Description,
 		CreatedAt:   entity.CreatedAt,
 		UpdatedAt:   entity.UpdatedAt,
+		Version:     entity.Version,
 	}
+	if entity.DeletedAt.Valid {
+		permission.DeletedAt = &entity.DeletedAt.Time
+	}
+	return permission
 }
@@ -15,6 +15,7 @@ type RoleEntity struct {
 	Description string
 	Level       int
 	Permissions []PermissionEntity `gorm:"many2many:role_permissions;"`
+	DeletedAt   gorm.DeletedAt     `gorm:"index"`
 }
 
 func (RoleEntity) TableName() string {
@@ -49,6 +50,7 @@ func NewRoleEntity(entity *dto.RoleDto) *RoleEntity {
 			Id:        id,
 			CreatedAt: entity.CreatedAt,
 			UpdatedAt: entity.UpdatedAt,
+			Version:   entity.Version,
 		},
 		Name:        entity.Name,
 		Description: entity.Description,
@@ -63,7 +65,7 @@ func (entity *RoleEntity) ToDto() *dto.RoleDto {
 		permissions[i] = *permission.ToDto()
 	}
 
-	return &dto.RoleDto{
+	role := &dto.RoleDto{
 		Id:          entity.Id.String(),
 		Name:        entity.Name,
 		Description: entity.Description,
@@ -71,5 +73,10 @@ func (entity *RoleEntity) ToDto() *dto.RoleDto {
 		Permissions: permissions,
 		CreatedAt:   entity.CreatedAt,
 		UpdatedAt:   entity.UpdatedAt,
+		Version:     entity.Version,
 	}
+	if entity.DeletedAt.Valid {
+		role.DeletedAt = &entity.DeletedAt.Time
+	}
+	return role
 }
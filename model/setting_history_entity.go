@@ -0,0 +1,69 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/google/uuid"
+)
+
+// SettingHistoryEntity records a change of a setting, values are kept as they are stored so secrets stay sealed
+type SettingHistoryEntity struct {
+	Id        uuid.UUID `gorm:"type:uuid"`
+	Name      string    `gorm:"uniqueIndex:idx_setting_histories_version"`
+	Version   int64     `gorm:"uniqueIndex:idx_setting_histories_version"`
+	OldValue  *string
+	NewValue  string
+	Origin    string
+	ActorId   string
+	ActorName string
+	RequestId string
+	CreatedAt time.Time
+}
+
+func (SettingHistoryEntity) TableName() string {
+	return "setting_histories"
+}
+
+func (entity *SettingHistoryEntity) ToDto() *dto.SettingHistoryDto {
+	return &dto.SettingHistoryDto{
+		Name:      entity.Name,
+		Version:   entity.Version,
+		OldValue:  entity.OldValue,
+		NewValue:  entity.NewValue,
+		Origin:    entity.Origin,
+		ActorId:   entity.ActorId,
+		ActorName: entity.ActorName,
+		RequestId: entity.RequestId,
+		CreatedAt: entity.CreatedAt,
+	}
+}
+
+// SettingSnapshotEntity keeps the stored values of every setting at a point in time
+type SettingSnapshotEntity struct {
+	Id        uuid.UUID `gorm:"type:uuid"`
+	Name      string
+	Values    string
+	ActorId   string
+	ActorName string
+	CreatedAt time.Time
+}
+
+func (SettingSnapshotEntity) TableName() string {
+	return "setting_snapshots"
+}
+
+func (entity *SettingSnapshotEntity) ToDto() *dto.SettingSnapshotDto {
+	var values map[string]string
+	_ = json.Unmarshal([]byte(entity.Values), &values)
+
+	return &dto.SettingSnapshotDto{
+		Id:        entity.Id.String(),
+		Name:      entity.Name,
+		Values:    values,
+		ActorId:   entity.ActorId,
+		ActorName: entity.ActorName,
+		CreatedAt: entity.CreatedAt,
+	}
+}
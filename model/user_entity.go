@@ -5,6 +5,7 @@ import (
 
 	"github.com/ericmarcelinotju/gram/dto"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // UserEntity struct defines the database model for an user.
@@ -23,6 +24,8 @@ type UserEntity struct {
 	Role      RoleEntity `gorm:"foreignKey:RoleId"`
 
 	ForgotPasswordToken *string
+
+	DeletedAt gorm.DeletedAt `gorm:"index"`
 }
 
 func (UserEntity) TableName() string {
@@ -38,6 +41,7 @@ func NewUserEntity(entity *dto.UserDto) *UserEntity {
 			Id:        id,
 			CreatedAt: entity.CreatedAt,
 			UpdatedAt: entity.UpdatedAt,
+			Version:   entity.Version,
 		},
 		Name:      entity.Name,
 		Email:     entity.Email,
@@ -70,6 +74,10 @@ func (entity *UserEntity) ToDto() *dto.UserDto {
 
 		CreatedAt: entity.CreatedAt,
 		UpdatedAt: entity.UpdatedAt,
+		Version:   entity.Version,
+	}
+	if entity.DeletedAt.Valid {
+		user.DeletedAt = &entity.DeletedAt.Time
 	}
 
 	return user
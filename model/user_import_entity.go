@@ -0,0 +1,70 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/constant/enums"
+	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/google/uuid"
+)
+
+// UserImportEntity struct defines the database model for a bulk user import run.
+type UserImportEntity struct {
+	Model
+	Filename   string
+	Status     enums.JobStatus
+	Total      int
+	Valid      int
+	Invalid    int
+	Processed  int
+	Failed     int
+	Rows       string
+	FinishedAt *time.Time
+}
+
+func (UserImportEntity) TableName() string {
+	return "user_imports"
+}
+
+func NewUserImportEntity(entity *dto.UserImportDto) *UserImportEntity {
+	id, _ := uuid.Parse(entity.Id)
+	rows, _ := json.Marshal(entity.Rows)
+
+	return &UserImportEntity{
+		Model: Model{
+			Id:        id,
+			CreatedAt: entity.CreatedAt,
+			UpdatedAt: entity.UpdatedAt,
+		},
+		Filename:   entity.Filename,
+		Status:     entity.Status,
+		Total:      entity.Total,
+		Valid:      entity.Valid,
+		Invalid:    entity.Invalid,
+		Processed:  entity.Processed,
+		Failed:     entity.Failed,
+		Rows:       string(rows),
+		FinishedAt: entity.FinishedAt,
+	}
+}
+
+func (entity *UserImportEntity) ToDto() *dto.UserImportDto {
+	var rows []dto.UserImportRowDto
+	_ = json.Unmarshal([]byte(entity.Rows), &rows)
+
+	return &dto.UserImportDto{
+		Id:         entity.Id.String(),
+		Filename:   entity.Filename,
+		Status:     entity.Status,
+		Total:      entity.Total,
+		Valid:      entity.Valid,
+		Invalid:    entity.Invalid,
+		Processed:  entity.Processed,
+		Failed:     entity.Failed,
+		Rows:       rows,
+		FinishedAt: entity.FinishedAt,
+		CreatedAt:  entity.CreatedAt,
+		UpdatedAt:  entity.UpdatedAt,
+	}
+}
@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/ericmarcelinotju/gram/utils/request"
+	"github.com/ericmarcelinotju/gram/utils/response"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAudit godoc
+// @Summary     Get list of audits
+// @Description Get list of audits, filter with field[operator]=value e.g. entity_name[eq]=users, select fields=id,date and include=user,permission
+// @Tags        Audit
+// @Accept      json
+// @Produce     json
+// @Param       item   query      dto.GetAuditDto   true   "Paging, Search & Filter"
+// @Success     200    {object}   response.SetResponse{data=dto.ListAuditDto}
+// @Router      /audit  [get]
+// @Security    Auth
+func Get(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		payload, err := request.Bind[dto.GetAuditDto](c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		audits, total, err := service.Read(c, payload)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		results, err := dto.Project(audits, &payload.FieldsDto, fieldsConfig)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		response.ResponseList(c, results, total, payload.PaginationDto)
+	}
+}
+
+// VerifyAudit godoc
+// @Summary     Verify audit chain
+// @Description Walk the audit hash chain and report broken entries
+// @Tags        Audit
+// @Accept      json
+// @Produce     json
+// @Success     200    {object}   response.SetResponse{data=dto.AuditVerificationDto}
+// @Router      /audit/verify  [get]
+// @Security    Auth
+func Verify(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		res, err := service.Verify(c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, res)
+	}
+}
+
+// ExportAudit godoc
+// @Summary     Export audits
+// @Description Export audits of a date range to the storage as CSV or JSON Lines
+// @Tags        Audit
+// @Accept      json
+// @Produce     json
+// @Param       export   body       dto.ExportAuditDto   true   "Export Range & Format"
+// @Success     200      {object}   response.SetResponse{data=dto.AuditExportDto}
+// @Router      /audit/export  [post]
+// @Security    Auth
+func Export(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		payload, err := request.Bind[dto.ExportAuditDto](c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		res, err := service.Export(c, payload)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, res)
+	}
+}
+
+// GetAuditArchive godoc
+// @Summary     Get list of audit archive runs
+// @Description Get list of audit retention runs with their status
+// @Tags        Audit
+// @Accept      json
+// @Produce     json
+// @Param       item   query      dto.GetAuditArchiveDto   true   "Paging, Search & Filter"
+// @Success     200    {object}   response.SetResponse{data=dto.ListAuditArchiveDto}
+// @Router      /audit/archive  [get]
+// @Security    Auth
+func GetArchive(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		payload, err := request.Bind[dto.GetAuditArchiveDto](c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		archives, total, err := service.ReadArchives(c, payload)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseList(c, archives, total, payload.PaginationDto)
+	}
+}
+
+// PostAuditArchive godoc
+// @Summary     Archive audits
+// @Description Archive and delete audits of an entity older than the retention
+// @Tags        Audit
+// @Accept      json
+// @Produce     json
+// @Param       archive   body       dto.ArchiveAuditDto   true   "Entity & Retention"
+// @Success     200       {object}   response.SetResponse{data=dto.AuditArchiveDto}
+// @Router      /audit/archive  [post]
+// @Security    Auth
+func PostArchive(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		payload, err := request.Bind[dto.ArchiveAuditDto](c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		res, err := service.Archive(c, payload)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, res)
+	}
+}
@@ -0,0 +1,483 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	pkgErr "github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/ericmarcelinotju/gram/dto"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
+	"github.com/ericmarcelinotju/gram/model"
+	"github.com/ericmarcelinotju/gram/plugins/database"
+	"github.com/ericmarcelinotju/gram/plugins/storage"
+)
+
+const (
+	selectError  = "Error in selecting audits in the database"
+	verifyError  = "Error in verifying audit chain"
+	exportError  = "Error in exporting audits"
+	archiveError = "Error in archiving audits"
+	restoreError = "Error in restoring audits"
+
+	deleteBatchSize = 500
+)
+
+// filterConfig whitelists the fields audits can be filtered on
+var filterConfig = dto.FilterConfig{
+	Fields: map[string]dto.FilterField{
+		"sequence":       {Column: "sequence", Type: dto.FilterNumber},
+		"date":           {Column: "date", Type: dto.FilterTime},
+		"entity_name":    {Column: "entity_name", Type: dto.FilterString},
+		"entity_id":      {Column: "entity_id", Type: dto.FilterString},
+		"operation_type": {Column: "operation_type", Type: dto.FilterString},
+		"origin":         {Column: "origin", Type: dto.FilterString},
+		"request_id":     {Column: "request_id", Type: dto.FilterString},
+		"user_id":        {Column: "user_id", Type: dto.FilterUuid},
+		"permission_id":  {Column: "permission_id", Type: dto.FilterUuid},
+		"user.name": {
+			Column:   "name",
+			Type:     dto.FilterString,
+			Relation: &dto.FilterRelation{Model: &model.UserEntity{}, ForeignKey: "user_id"},
+		},
+	},
+	Search: []string{"entity_name", "entity_id", "request_id"},
+}
+
+// sortConfig whitelists the fields audits can be sorted on
+var sortConfig = dto.SortConfig{
+	Fields: map[string]string{
+		"id":             "id",
+		"sequence":       "sequence",
+		"date":           "date",
+		"entity_name":    "entity_name",
+		"operation_type": "operation_type",
+	},
+	Default: "sequence:desc",
+}
+
+// archiveSortConfig whitelists the fields audit archive runs can be sorted on
+var archiveSortConfig = dto.SortConfig{
+	Fields: map[string]string{
+		"id":          "id",
+		"entity_name": "entity_name",
+		"status":      "status",
+		"total":       "total",
+		"started_at":  "started_at",
+		"finished_at": "finished_at",
+	},
+	Default: "started_at:desc",
+}
+
+// fieldsConfig whitelists the fields and relations audit lists can select
+var fieldsConfig = dto.FieldsConfig{
+	Fields: map[string]string{
+		"id":             "id",
+		"sequence":       "sequence",
+		"date":           "date",
+		"entity_name":    "entity_name",
+		"entity_id":      "entity_id",
+		"old_value":      "old_value",
+		"new_value":      "new_value",
+		"operation_type": "operation_type",
+		"origin":         "origin",
+		"request_id":     "request_id",
+		"user_id":        "user_id",
+		"permission_id":  "permission_id",
+		"prev_hash":      "prev_hash",
+		"hash":           "hash",
+	},
+	Includes: map[string]dto.IncludeConfig{
+		"user":       {Preload: "User", Columns: []string{"user_id"}},
+		"permission": {Preload: "Permission", Columns: []string{"permission_id"}},
+	},
+}
+
+// Repository provides an abstraction on top of the audit data source
+type Repository interface {
+	Select(context.Context, *dto.AuditDto, *dto.FilterDto, *dto.FieldsDto, *dto.PaginationDto, *dto.SortDto) ([]dto.AuditDto, int64, error)
+	SelectRange(ctx context.Context, payload *dto.ExportAuditDto, fn func(*dto.AuditDto) error) (int64, error)
+	Verify(context.Context) (*dto.AuditVerificationDto, error)
+
+	CountRange(context.Context, *dto.ExportAuditDto) (int64, error)
+	DeleteRange(ctx context.Context, payload *dto.ExportAuditDto, archiveId string) (int64, error)
+	InsertRaw(context.Context, []dto.AuditDto) (int64, error)
+
+	InsertArchive(context.Context, *dto.AuditArchiveDto) error
+	UpdateArchive(context.Context, *dto.AuditArchiveDto) error
+	SelectArchives(context.Context, *dto.AuditArchiveDto, *dto.PaginationDto, *dto.SortDto) ([]dto.AuditArchiveDto, int64, error)
+
+	SaveExport(reader io.Reader, filename string) error
+	LoadExport(filename string) ([]byte, error)
+}
+
+type repository struct {
+	db      *gorm.DB
+	storage storage.Storage
+}
+
+// NewRepository creates a new repository struct
+func NewRepository(db *gorm.DB, storage storage.Storage) *repository {
+	return &repository{db: db, storage: storage}
+}
+
+func (s *repository) Select(
+	ctx context.Context,
+	filter *dto.AuditDto,
+	conditions *dto.FilterDto,
+	fields *dto.FieldsDto,
+	pagination *dto.PaginationDto,
+	sort *dto.SortDto,
+) ([]dto.AuditDto, int64, error) {
+	var total int64
+	var entities []model.AuditEntity
+
+	query := database.WithContext(ctx, s.db).
+		Model(&model.AuditEntity{})
+
+	if filter != nil {
+		query.Where(model.NewAuditEntity(filter))
+	}
+	var err error
+	if conditions != nil {
+		if query, err = conditions.Apply(query, filterConfig); err != nil {
+			return nil, 0, customErrors.NewAppError(err, customErrors.ValidationError)
+		}
+	}
+	if pagination.Counted() {
+		query.Count(&total)
+	}
+	if query, err = fields.Apply(query, fieldsConfig); err != nil {
+		return nil, 0, customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+	if query, err = pagination.Paginate(query, sort, sortConfig); err != nil {
+		return nil, 0, customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+	query.Find(&entities)
+
+	if err := query.Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, total, appErr
+	}
+
+	if err := pagination.SetCursors(query, &entities); err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, total, appErr
+	}
+
+	var results = make([]dto.AuditDto, len(entities))
+	for i, element := range entities {
+		results[i] = *element.ToDto()
+	}
+
+	return results, total, nil
+}
+
+func rangeScope(payload *dto.ExportAuditDto) func(*gorm.DB) *gorm.DB {
+	return func(query *gorm.DB) *gorm.DB {
+		if payload.EntityName != nil {
+			query = query.Where("entity_name = ?", *payload.EntityName)
+		}
+		if payload.From != nil {
+			query = query.Where("date >= ?", *payload.From)
+		}
+		if payload.To != nil {
+			query = query.Where("date < ?", *payload.To)
+		}
+		return query
+	}
+}
+
+func (s *repository) SelectRange(ctx context.Context, payload *dto.ExportAuditDto, fn func(*dto.AuditDto) error) (int64, error) {
+	var total int64
+
+	rows, err := database.WithContext(ctx, s.db).
+		Model(&model.AuditEntity{}).
+		Scopes(rangeScope(payload)).
+		Order("sequence ASC").
+		Rows()
+	if err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return total, appErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entity model.AuditEntity
+		if err := s.db.ScanRows(rows, &entity); err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+			return total, appErr
+		}
+		if err := fn(entity.ToDto()); err != nil {
+			return total, err
+		}
+		total++
+	}
+
+	if err := rows.Err(); err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return total, appErr
+	}
+	return total, nil
+}
+
+func (s *repository) Verify(ctx context.Context) (*dto.AuditVerificationDto, error) {
+	result := &dto.AuditVerificationDto{Breaks: []dto.AuditChainBreakDto{}}
+
+	rows, err := database.WithContext(ctx, s.db).
+		Model(&model.AuditEntity{}).
+		Order("sequence ASC").
+		Order("date ASC").
+		Rows()
+	if err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, verifyError), customErrors.DatabaseError)
+		return nil, appErr
+	}
+	defer rows.Close()
+
+	var expectedSequence int64 = 1
+	var prevHash string
+
+	for rows.Next() {
+		var entity model.AuditEntity
+		if err := s.db.ScanRows(rows, &entity); err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, verifyError), customErrors.DatabaseError)
+			return nil, appErr
+		}
+		result.Checked++
+
+		brokenAt := func(reason string) {
+			result.Breaks = append(result.Breaks, dto.AuditChainBreakDto{
+				ID:       entity.Id.String(),
+				Sequence: entity.Sequence,
+				Reason:   reason,
+			})
+		}
+
+		if entity.Hash == "" {
+			brokenAt("entry is not sealed")
+			continue
+		}
+		if entity.Sequence > expectedSequence {
+			// a gap is only valid when every missing entry was archived
+			var tombstones []model.AuditTombstoneEntity
+			if err := database.WithContext(ctx, s.db).
+				Model(&model.AuditTombstoneEntity{}).
+				Where("sequence >= ? AND sequence < ?", expectedSequence, entity.Sequence).
+				Order("sequence ASC").
+				Find(&tombstones).Error; err != nil {
+				appErr := customErrors.NewAppError(pkgErr.Wrap(err, verifyError), customErrors.DatabaseError)
+				return nil, appErr
+			}
+			if int64(len(tombstones)) == entity.Sequence-expectedSequence {
+				prevHash = tombstones[len(tombstones)-1].Hash
+				expectedSequence = entity.Sequence
+			}
+		}
+		if entity.Sequence != expectedSequence {
+			brokenAt(fmt.Sprintf("expected sequence %d", expectedSequence))
+		}
+		if entity.PrevHash != prevHash {
+			brokenAt("previous hash does not match")
+		}
+		if entity.ComputeHash() != entity.Hash {
+			brokenAt("entry content does not match its hash")
+		}
+
+		expectedSequence = entity.Sequence + 1
+		prevHash = entity.Hash
+	}
+
+	if err := rows.Err(); err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, verifyError), customErrors.DatabaseError)
+		return nil, appErr
+	}
+
+	result.Valid = len(result.Breaks) == 0
+	return result, nil
+}
+
+func (s *repository) CountRange(ctx context.Context, payload *dto.ExportAuditDto) (int64, error) {
+	var total int64
+	if err := database.WithContext(ctx, s.db).
+		Model(&model.AuditEntity{}).
+		Scopes(rangeScope(payload)).
+		Count(&total).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return 0, appErr
+	}
+	return total, nil
+}
+
+// DeleteRange removes the audits in batches, leaving a tombstone of each entry hash behind
+func (s *repository) DeleteRange(ctx context.Context, payload *dto.ExportAuditDto, archiveId string) (int64, error) {
+	var total int64
+	archiveUUID, _ := uuid.Parse(archiveId)
+
+	for {
+		var entities []model.AuditEntity
+		if err := database.WithContext(ctx, s.db).
+			Model(&model.AuditEntity{}).
+			Select("id", "sequence", "hash").
+			Scopes(rangeScope(payload)).
+			Order("sequence ASC").
+			Limit(deleteBatchSize).
+			Find(&entities).Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, archiveError), customErrors.DatabaseError)
+			return total, appErr
+		}
+		if len(entities) == 0 {
+			return total, nil
+		}
+
+		ids := make([]uuid.UUID, len(entities))
+		var tombstones []model.AuditTombstoneEntity
+		for i, entity := range entities {
+			ids[i] = entity.Id
+			if entity.Hash != "" {
+				tombstones = append(tombstones, model.AuditTombstoneEntity{
+					Sequence:  entity.Sequence,
+					Hash:      entity.Hash,
+					ArchiveId: archiveUUID,
+				})
+			}
+		}
+
+		err := database.WithContext(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+			if len(tombstones) > 0 {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tombstones).Error; err != nil {
+					return err
+				}
+			}
+			return tx.Where("id IN ?", ids).Delete(&model.AuditEntity{}).Error
+		})
+		if err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, archiveError), customErrors.DatabaseError)
+			return total, appErr
+		}
+		total += int64(len(entities))
+	}
+}
+
+// InsertRaw stores audits as they are without chaining them, existing entries are skipped
+func (s *repository) InsertRaw(ctx context.Context, payload []dto.AuditDto) (int64, error) {
+	if len(payload) == 0 {
+		return 0, nil
+	}
+
+	entities := make([]model.AuditEntity, len(payload))
+	for i, item := range payload {
+		entities[i] = *model.NewAuditEntity(&item)
+	}
+
+	query := database.WithContext(ctx, s.db).
+		Omit("User", "Permission").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entities)
+	if err := query.Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, restoreError), customErrors.DatabaseError)
+		return 0, appErr
+	}
+	return query.RowsAffected, nil
+}
+
+func (s *repository) InsertArchive(ctx context.Context, payload *dto.AuditArchiveDto) error {
+	entity := model.NewAuditArchiveEntity(payload)
+	entity.Id = uuid.New()
+
+	if err := database.WithContext(ctx, s.db).Create(entity).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, archiveError), customErrors.DatabaseError)
+		return appErr
+	}
+	payload.Id = entity.Id.String()
+	return nil
+}
+
+func (s *repository) UpdateArchive(ctx context.Context, payload *dto.AuditArchiveDto) error {
+	entity := model.NewAuditArchiveEntity(payload)
+
+	// a map writes zero values too, like an empty error or no archived audit
+	query := database.WithContext(ctx, s.db).Model(entity).Updates(map[string]interface{}{
+		"filename":    entity.Filename,
+		"total":       entity.Total,
+		"status":      entity.Status,
+		"error":       entity.Error,
+		"started_at":  entity.StartedAt,
+		"finished_at": entity.FinishedAt,
+	})
+	if err := query.Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, archiveError), customErrors.DatabaseError)
+		return appErr
+	}
+	return nil
+}
+
+func (s *repository) SelectArchives(
+	ctx context.Context,
+	filter *dto.AuditArchiveDto,
+	pagination *dto.PaginationDto,
+	sort *dto.SortDto,
+) ([]dto.AuditArchiveDto, int64, error) {
+	var total int64
+	var entities []model.AuditArchiveEntity
+
+	query := database.WithContext(ctx, s.db).Model(&model.AuditArchiveEntity{})
+
+	if filter != nil {
+		query.Where(model.NewAuditArchiveEntity(filter))
+	}
+	if pagination.Counted() {
+		query.Count(&total)
+	}
+	query, err := pagination.Paginate(query, sort, archiveSortConfig)
+	if err != nil {
+		return nil, 0, customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+	query.Find(&entities)
+
+	if err := query.Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, total, appErr
+	}
+
+	if err := pagination.SetCursors(query, &entities); err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, total, appErr
+	}
+
+	var results = make([]dto.AuditArchiveDto, len(entities))
+	for i, element := range entities {
+		results[i] = *element.ToDto()
+	}
+
+	return results, total, nil
+}
+
+func (s *repository) SaveExport(reader io.Reader, filename string) error {
+	if s.storage == nil {
+		appErr := customErrors.NewAppError(pkgErr.New("storage not configured"), customErrors.StorageError)
+		return appErr
+	}
+	if err := s.storage.UploadStream(reader, filename); err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, exportError), customErrors.StorageError)
+		return appErr
+	}
+	return nil
+}
+
+func (s *repository) LoadExport(filename string) ([]byte, error) {
+	if s.storage == nil {
+		appErr := customErrors.NewAppError(pkgErr.New("storage not configured"), customErrors.StorageError)
+		return nil, appErr
+	}
+	data, err := s.storage.Download(filename)
+	if err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, restoreError), customErrors.StorageError)
+		return nil, appErr
+	}
+	return data, nil
+}
@@ -0,0 +1,18 @@
+package audit
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// NewRoutesFactory create and returns a factory to create routes for the audit
+func NewRoutesFactory(router *gin.RouterGroup) func(service Service) {
+	group := router.Group("/api/audit")
+	auditRoutesFactory := func(service Service) {
+		group.GET("", Get(service))
+		group.GET("/verify", Verify(service))
+		group.POST("/export", Export(service))
+		group.GET("/archive", GetArchive(service))
+		group.POST("/archive", PostArchive(service))
+	}
+	return auditRoutesFactory
+}
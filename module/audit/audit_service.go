@@ -0,0 +1,317 @@
+package audit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/constant/enums"
+	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/ericmarcelinotju/gram/utils/logger"
+)
+
+const restoreBatchSize = 500
+
+var csvHeader = []string{
+	"id",
+	"sequence",
+	"date",
+	"entity_name",
+	"entity_id",
+	"old_value",
+	"new_value",
+	"operation_type",
+	"origin",
+	"request_id",
+	"user_id",
+	"permission_id",
+	"prev_hash",
+	"hash",
+}
+
+// auditRecord is the flat representation of an audit written to exports
+type auditRecord struct {
+	ID            string    `json:"id"`
+	Sequence      int64     `json:"sequence"`
+	Date          time.Time `json:"date"`
+	EntityName    string    `json:"entity_name"`
+	EntityId      string    `json:"entity_id"`
+	OldValue      string    `json:"old_value"`
+	NewValue      string    `json:"new_value"`
+	OperationType string    `json:"operation_type"`
+	Origin        string    `json:"origin"`
+	RequestId     string    `json:"request_id"`
+	UserID        string    `json:"user_id"`
+	PermissionID  string    `json:"permission_id"`
+	PrevHash      string    `json:"prev_hash"`
+	Hash          string    `json:"hash"`
+}
+
+// Service defines audit service behavior.
+type Service interface {
+	Read(context.Context, *dto.GetAuditDto) ([]dto.AuditDto, int64, error)
+	Verify(context.Context) (*dto.AuditVerificationDto, error)
+	Export(context.Context, *dto.ExportAuditDto) (*dto.AuditExportDto, error)
+
+	Archive(context.Context, *dto.ArchiveAuditDto) (*dto.AuditArchiveDto, error)
+	ReadArchives(context.Context, *dto.GetAuditArchiveDto) ([]dto.AuditArchiveDto, int64, error)
+	Restore(ctx context.Context, filename string) (int64, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService creates a new service struct
+func NewService(repo Repository) *service {
+	return &service{repo: repo}
+}
+
+func (svc *service) Read(ctx context.Context, payload *dto.GetAuditDto) ([]dto.AuditDto, int64, error) {
+	filter := &dto.AuditDto{}
+
+	if payload.EntityName != nil {
+		filter.EntityName = *payload.EntityName
+	}
+	if payload.EntityId != nil {
+		filter.EntityId = *payload.EntityId
+	}
+	if payload.UserId != nil {
+		filter.UserID = *payload.UserId
+	}
+
+	return svc.repo.Select(
+		ctx,
+		filter,
+		&payload.FilterDto,
+		&payload.FieldsDto,
+		payload.PaginationDto,
+		payload.SortDto,
+	)
+}
+
+func (svc *service) Verify(ctx context.Context) (*dto.AuditVerificationDto, error) {
+	return svc.repo.Verify(ctx)
+}
+
+// Export streams the audits of the requested range to the storage as CSV or JSON Lines
+func (svc *service) Export(ctx context.Context, payload *dto.ExportAuditDto) (*dto.AuditExportDto, error) {
+	filename := fmt.Sprintf("audit/audit-%d.%s", time.Now().Unix(), payload.Format)
+
+	reader, writer := io.Pipe()
+	totalChan := make(chan int64, 1)
+	go func() {
+		total, err := svc.writeExport(ctx, payload, writer)
+		totalChan <- total
+		writer.CloseWithError(err)
+	}()
+
+	if err := svc.repo.SaveExport(reader, filename); err != nil {
+		reader.CloseWithError(err)
+		return nil, err
+	}
+
+	return &dto.AuditExportDto{
+		Filename: filename,
+		Total:    <-totalChan,
+	}, nil
+}
+
+func (svc *service) writeExport(ctx context.Context, payload *dto.ExportAuditDto, w io.Writer) (int64, error) {
+	if payload.Format == "csv" {
+		return svc.writeCsv(ctx, payload, w)
+	}
+	return svc.writeJsonLines(ctx, payload, w)
+}
+
+func (svc *service) writeCsv(ctx context.Context, payload *dto.ExportAuditDto, w io.Writer) (int64, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return 0, err
+	}
+	total, err := svc.repo.SelectRange(ctx, payload, func(audit *dto.AuditDto) error {
+		return writer.Write([]string{
+			audit.ID,
+			strconv.FormatInt(audit.Sequence, 10),
+			audit.Date.UTC().Format(time.RFC3339Nano),
+			audit.EntityName,
+			audit.EntityId,
+			audit.OldValue,
+			audit.NewValue,
+			audit.OperationType,
+			audit.Origin,
+			audit.RequestId,
+			audit.UserID,
+			audit.PermissionID,
+			audit.PrevHash,
+			audit.Hash,
+		})
+	})
+	if err != nil {
+		return total, err
+	}
+	writer.Flush()
+	return total, writer.Error()
+}
+
+func (svc *service) writeJsonLines(ctx context.Context, payload *dto.ExportAuditDto, w io.Writer) (int64, error) {
+	encoder := json.NewEncoder(w)
+	return svc.repo.SelectRange(ctx, payload, func(audit *dto.AuditDto) error {
+		return encoder.Encode(auditRecord{
+			ID:            audit.ID,
+			Sequence:      audit.Sequence,
+			Date:          audit.Date.UTC(),
+			EntityName:    audit.EntityName,
+			EntityId:      audit.EntityId,
+			OldValue:      audit.OldValue,
+			NewValue:      audit.NewValue,
+			OperationType: audit.OperationType,
+			Origin:        audit.Origin,
+			RequestId:     audit.RequestId,
+			UserID:        audit.UserID,
+			PermissionID:  audit.PermissionID,
+			PrevHash:      audit.PrevHash,
+			Hash:          audit.Hash,
+		})
+	})
+}
+
+// Archive moves the audits of an entity older than the retention into a compressed JSON Lines file
+func (svc *service) Archive(ctx context.Context, payload *dto.ArchiveAuditDto) (*dto.AuditArchiveDto, error) {
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -payload.RetentionDays)
+	filter := &dto.ExportAuditDto{
+		EntityName: &payload.EntityName,
+		To:         &cutoff,
+	}
+
+	archive := &dto.AuditArchiveDto{
+		EntityName: payload.EntityName,
+		Cutoff:     cutoff,
+		Status:     enums.JobStatusRunning,
+		StartedAt:  now,
+	}
+	if err := svc.repo.InsertArchive(ctx, archive); err != nil {
+		return nil, err
+	}
+	logger.Printf(ctx, "[AUDIT ARCHIVE] %s : archiving '%s' audits before %s", archive.Id, payload.EntityName, cutoff.Format(time.RFC3339))
+
+	total, err := svc.repo.CountRange(ctx, filter)
+	if err != nil {
+		return svc.finishArchive(ctx, archive, err)
+	}
+	if total == 0 {
+		return svc.finishArchive(ctx, archive, nil)
+	}
+
+	archive.Filename = fmt.Sprintf("audit/archive/%s-%d.jsonl.gz", payload.EntityName, now.Unix())
+
+	reader, writer := io.Pipe()
+	go func() {
+		gzipWriter := gzip.NewWriter(writer)
+		_, err := svc.writeJsonLines(ctx, filter, gzipWriter)
+		if err == nil {
+			err = gzipWriter.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+	if err := svc.repo.SaveExport(reader, archive.Filename); err != nil {
+		reader.CloseWithError(err)
+		return svc.finishArchive(ctx, archive, err)
+	}
+
+	archive.Total, err = svc.repo.DeleteRange(ctx, filter, archive.Id)
+	return svc.finishArchive(ctx, archive, err)
+}
+
+func (svc *service) finishArchive(ctx context.Context, archive *dto.AuditArchiveDto, err error) (*dto.AuditArchiveDto, error) {
+	finishedAt := time.Now()
+	archive.FinishedAt = &finishedAt
+	archive.Status = enums.JobStatusSuccess
+	if err != nil {
+		archive.Status = enums.JobStatusFailed
+		archive.Error = err.Error()
+		logger.Printf(ctx, "[AUDIT ARCHIVE] %s : failed after %d audits : %s", archive.Id, archive.Total, err)
+	} else {
+		logger.Printf(ctx, "[AUDIT ARCHIVE] %s : archived %d audits to '%s'", archive.Id, archive.Total, archive.Filename)
+	}
+
+	if updateErr := svc.repo.UpdateArchive(ctx, archive); updateErr != nil {
+		return archive, updateErr
+	}
+	return archive, err
+}
+
+func (svc *service) ReadArchives(ctx context.Context, payload *dto.GetAuditArchiveDto) ([]dto.AuditArchiveDto, int64, error) {
+	filter := &dto.AuditArchiveDto{}
+
+	if payload.EntityName != nil {
+		filter.EntityName = *payload.EntityName
+	}
+
+	return svc.repo.SelectArchives(
+		ctx,
+		filter,
+		payload.PaginationDto,
+		payload.SortDto,
+	)
+}
+
+// Restore inserts back the audits of an archive file, entries already present are skipped
+func (svc *service) Restore(ctx context.Context, filename string) (int64, error) {
+	data, err := svc.repo.LoadExport(filename)
+	if err != nil {
+		return 0, err
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	defer gzipReader.Close()
+
+	var total int64
+	var batch []dto.AuditDto
+	flush := func() error {
+		inserted, err := svc.repo.InsertRaw(ctx, batch)
+		total += inserted
+		batch = batch[:0]
+		return err
+	}
+
+	decoder := json.NewDecoder(gzipReader)
+	for decoder.More() {
+		var record auditRecord
+		if err := decoder.Decode(&record); err != nil {
+			return total, err
+		}
+		batch = append(batch, dto.AuditDto{
+			ID:            record.ID,
+			Sequence:      record.Sequence,
+			Date:          record.Date,
+			EntityName:    record.EntityName,
+			EntityId:      record.EntityId,
+			OldValue:      record.OldValue,
+			NewValue:      record.NewValue,
+			OperationType: record.OperationType,
+			Origin:        record.Origin,
+			RequestId:     record.RequestId,
+			UserID:        record.UserID,
+			PermissionID:  record.PermissionID,
+			PrevHash:      record.PrevHash,
+			Hash:          record.Hash,
+		})
+		if len(batch) >= restoreBatchSize {
+			if err := flush(); err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, flush()
+}
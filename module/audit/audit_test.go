@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/constant/enums"
+	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/ericmarcelinotju/gram/model"
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type memoryStorage map[string][]byte
+
+func (m memoryStorage) Upload(file multipart.File, name string) error {
+	return m.UploadStream(file, name)
+}
+func (m memoryStorage) UploadStream(reader io.Reader, name string) error {
+	data, err := io.ReadAll(reader)
+	m[name] = data
+	return err
+}
+func (m memoryStorage) Download(name string) ([]byte, error) { return m[name], nil }
+func (m memoryStorage) DownloadStream(name string) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(m[name])), nil
+}
+func (m memoryStorage) Remove(name string) error           { delete(m, name); return nil }
+func (m memoryStorage) List(string) ([]fs.FileInfo, error) { return nil, nil }
+func (m memoryStorage) Path() string                       { return "" }
+
+func setupService(t *testing.T) (context.Context, *gorm.DB, Service) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// audit tables only, related tables use postgres specific defaults
+	for _, statement := range []string{
+		`CREATE TABLE audits (
+			id text PRIMARY KEY, sequence integer UNIQUE, date datetime, entity_name text, entity_id text,
+			old_value text, new_value text, operation_type text, origin text, request_id text,
+			user_id text, permission_id text, prev_hash text, hash text
+		)`,
+		`CREATE TABLE audit_archives (
+			id text PRIMARY KEY, created_at datetime, updated_at datetime, entity_name text,
+			cutoff datetime, filename text, total integer, status text, error text,
+			started_at datetime, finished_at datetime, version integer NOT NULL DEFAULT 1
+		)`,
+		`CREATE TABLE audit_tombstones (sequence integer PRIMARY KEY, hash text, archive_id text)`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	model.SetAuditKey("test-key")
+
+	for i, entityName := range []string{"users", "roles", "users"} {
+		err := model.CreateAudit(db, &model.AuditEntity{
+			Id:            uuid.New(),
+			OperationType: "insert",
+			EntityName:    entityName,
+			EntityId:      uuid.NewString(),
+			NewValue:      `{"name":"test"}`,
+			RequestId:     uuid.NewString(),
+			Date:          time.Now().AddDate(0, 0, i-10),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return context.Background(), db, NewService(NewRepository(db, memoryStorage{}))
+}
+
+func TestVerifyAuditHandler(t *testing.T) {
+	ctx, _, svc := setupService(t)
+
+	res, err := svc.Verify(ctx)
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Checked, int64(3))
+	assert.Equal(t, res.Valid, true)
+}
+
+func TestVerifyTamperedAuditHandler(t *testing.T) {
+	ctx, db, svc := setupService(t)
+
+	db.Model(&model.AuditEntity{}).Where("sequence = ?", 2).Update("new_value", `{"name":"edited"}`)
+
+	res, err := svc.Verify(ctx)
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Valid, false)
+	assert.Equal(t, len(res.Breaks), 1)
+	assert.Equal(t, res.Breaks[0].Sequence, int64(2))
+}
+
+func TestVerifyDeletedAuditHandler(t *testing.T) {
+	ctx, db, svc := setupService(t)
+
+	db.Where("sequence = ?", 2).Delete(&model.AuditEntity{})
+
+	res, err := svc.Verify(ctx)
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, res.Valid, false)
+	assert.Equal(t, res.Breaks[0].Sequence, int64(3))
+}
+
+func TestArchiveAuditHandler(t *testing.T) {
+	ctx, db, svc := setupService(t)
+
+	archive, err := svc.Archive(ctx, &dto.ArchiveAuditDto{EntityName: "users", RetentionDays: 5})
+
+	assert.Equal(t, err, nil)
+	assert.Equal(t, archive.Total, int64(2))
+
+	var remaining int64
+	db.Model(&model.AuditEntity{}).Count(&remaining)
+	assert.Equal(t, remaining, int64(1))
+
+	verification, err := svc.Verify(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, verification.Valid, true)
+
+	restored, err := svc.Restore(ctx, archive.Filename)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, restored, int64(2))
+
+	verification, err = svc.Verify(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, verification.Checked, int64(3))
+	assert.Equal(t, verification.Valid, true)
+}
+
+func TestUpdateArchive(t *testing.T) {
+	ctx, db, _ := setupService(t)
+	repo := NewRepository(db, memoryStorage{})
+
+	archive := &dto.AuditArchiveDto{EntityName: "users", Total: 5, Status: enums.JobStatusFailed, Error: "storage is down"}
+	assert.Equal(t, repo.InsertArchive(ctx, archive), nil)
+
+	// zero values are written as well
+	archive.Total = 0
+	archive.Error = ""
+	archive.Status = enums.JobStatusSuccess
+	assert.Equal(t, repo.UpdateArchive(ctx, archive), nil)
+
+	var stored model.AuditArchiveEntity
+	assert.Equal(t, db.First(&stored, "id = ?", archive.Id).Error, nil)
+	assert.Equal(t, stored.Total, int64(0))
+	assert.Equal(t, stored.Error, "")
+	assert.Equal(t, stored.Status, enums.JobStatusSuccess)
+}
@@ -17,6 +17,7 @@ import (
 	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/ericmarcelinotju/gram/model"
 	"github.com/ericmarcelinotju/gram/plugins/cache"
+	"github.com/ericmarcelinotju/gram/plugins/database"
 	"github.com/ericmarcelinotju/gram/plugins/notifier"
 	"github.com/ericmarcelinotju/gram/utils/crypt"
 )
@@ -57,7 +58,7 @@ func (s *repository) Login(ctx context.Context, username string, password string
 	var err error
 	var result model.UserEntity
 
-	query := s.db.
+	query := database.WithContext(ctx, s.db).
 		Preload("Role").
 		Preload("Role.Permissions").
 		First(&result, "name = ?", username)
@@ -82,7 +83,7 @@ func (s *repository) Login(ctx context.Context, username string, password string
 
 	now := time.Now()
 	result.LastLogin = &now
-	if err = s.db.WithContext(ctx).Model(&result).Updates(result).Error; err != nil {
+	if err = database.WithContext(ctx, s.db).Model(&result).Updates(result).Error; err != nil {
 		err = customErrors.NewAppError(pkgErr.Wrap(err, loginError), customErrors.DatabaseError)
 		return nil, "", err
 	}
@@ -36,7 +36,7 @@ func setupService() (context.Context, Service) {
 	}
 
 	repo := NewRepository(db, cache, emailer)
-	userRepo := user.NewRepository(db, fileStorage, nil)
+	userRepo := user.NewRepository(db, fileStorage, nil, nil)
 	return context.Background(), NewService(repo, userRepo)
 }
 
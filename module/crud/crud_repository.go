@@ -0,0 +1,286 @@
+package crud
+
+import (
+	"context"
+	"errors"
+
+	pkgErr "github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	"github.com/ericmarcelinotju/gram/dto"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
+	"github.com/ericmarcelinotju/gram/model"
+	"github.com/ericmarcelinotju/gram/plugins/database"
+	"github.com/google/uuid"
+)
+
+// Entity is a pointer to a database model embedding model.Model that maps to the dto D
+type Entity[D any] interface {
+	ToDto() *D
+	GetId() uuid.UUID
+	GetVersion() int64
+	Versioned(tx *gorm.DB, value interface{}, expected int64) (*gorm.DB, error)
+}
+
+// Hook runs inside the transaction of a write with the entity being written
+type Hook[E any] func(tx *gorm.DB, entity E) error
+
+// Errors holds the messages wrapping the errors of each operation
+type Errors struct {
+	Insert  string
+	Update  string
+	Delete  string
+	Restore string
+	Select  string
+}
+
+// Config parameterises a Repository with the mappers, query whitelists and hooks of a module
+type Config[E Entity[D], D any] struct {
+	NewEntity func(*D) E
+	Errors    Errors
+
+	Filter dto.FilterConfig
+	Sort   dto.SortConfig
+	Fields dto.FieldsConfig
+
+	// Preloads are loaded by SelectById, lists preload the relations they include
+	Preloads []string
+	// Omit skips associations on insert and update, they are saved by the hooks
+	Omit []string
+	// Scope narrows every select
+	Scope func(*gorm.DB) *gorm.DB
+
+	BeforeInsert Hook[E]
+	AfterInsert  Hook[E]
+	BeforeUpdate Hook[E]
+	AfterUpdate  Hook[E]
+	BeforeDelete Hook[E]
+	AfterDelete  Hook[E]
+}
+
+// Repository implements the insert, update, select, delete and restore of an entity E mapped to the dto D
+type Repository[E Entity[D], D any] struct {
+	db     *gorm.DB
+	config Config[E, D]
+}
+
+// NewRepository creates a new generic repository
+func NewRepository[E Entity[D], D any](db *gorm.DB, config Config[E, D]) *Repository[E, D] {
+	return &Repository[E, D]{db: db, config: config}
+}
+
+func runHook[E any](hook Hook[E], tx *gorm.DB, entity E) error {
+	if hook == nil {
+		return nil
+	}
+	return hook(tx, entity)
+}
+
+func (r *Repository[E, D]) query(ctx context.Context) *gorm.DB {
+	query := database.WithContext(ctx, r.db)
+	if r.config.Scope != nil {
+		query = r.config.Scope(query)
+	}
+	return query
+}
+
+// Insert creates the entity of payload and maps the created entity back to payload
+func (r *Repository[E, D]) Insert(ctx context.Context, payload *D) error {
+	entity := r.config.NewEntity(payload)
+
+	return database.WithContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := runHook(r.config.BeforeInsert, tx, entity); err != nil {
+			return err
+		}
+		if err := tx.Omit(r.config.Omit...).Create(entity).Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Insert), customErrors.DatabaseError)
+			return appErr
+		}
+		if err := runHook(r.config.AfterInsert, tx, entity); err != nil {
+			return err
+		}
+		*payload = *entity.ToDto()
+		return nil
+	})
+}
+
+// Update writes the entity when its version is still the payload version, a zero version skips the check
+func (r *Repository[E, D]) Update(ctx context.Context, payload *D) error {
+	entity := r.config.NewEntity(payload)
+
+	return database.WithContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := runHook(r.config.BeforeUpdate, tx, entity); err != nil {
+			return err
+		}
+		query, err := entity.Versioned(tx, entity, entity.GetVersion())
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Update), customErrors.NotFoundError)
+			return appErr
+		}
+		if err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Update), customErrors.DatabaseError)
+			return appErr
+		}
+		query = query.Omit(r.config.Omit...).Updates(entity)
+		if err := query.Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Update), customErrors.DatabaseError)
+			return appErr
+		}
+		if query.RowsAffected == 0 {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(model.ErrStaleVersion, r.config.Errors.Update), customErrors.PreconditionFailedError)
+			return appErr
+		}
+		if err := runHook(r.config.AfterUpdate, tx, entity); err != nil {
+			return err
+		}
+		*payload = *entity.ToDto()
+		return nil
+	})
+}
+
+// Select lists the entities matching the filter, conditions and fields, paged and sorted
+func (r *Repository[E, D]) Select(
+	ctx context.Context,
+	filter *D,
+	conditions *dto.FilterDto,
+	fields *dto.FieldsDto,
+	pagination *dto.PaginationDto,
+	sort *dto.SortDto,
+	deleted *dto.DeletedDto,
+) ([]D, int64, error) {
+	var total int64
+	var entities []E
+
+	query := r.query(ctx).Model(r.config.NewEntity(new(D)))
+
+	if deleted != nil {
+		query = deleted.Apply(query)
+	}
+	if filter != nil {
+		query = query.Where(r.config.NewEntity(filter))
+	}
+	var err error
+	if conditions != nil {
+		if query, err = conditions.Apply(query, r.config.Filter); err != nil {
+			return nil, 0, customErrors.NewAppError(err, customErrors.ValidationError)
+		}
+	}
+	if pagination.Counted() {
+		query.Count(&total)
+	}
+	if query, err = fields.Apply(query, r.config.Fields); err != nil {
+		return nil, 0, customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+	if query, err = pagination.Paginate(query, sort, r.config.Sort); err != nil {
+		return nil, 0, customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+	query.Find(&entities)
+
+	if err := query.Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Select), customErrors.DatabaseError)
+		return nil, total, appErr
+	}
+
+	if err := pagination.SetCursors(query, &entities); err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Select), customErrors.DatabaseError)
+		return nil, total, appErr
+	}
+
+	var results = make([]D, len(entities))
+	for i, element := range entities {
+		results[i] = *element.ToDto()
+	}
+
+	return results, total, nil
+}
+
+// SelectById reads an entity with its preloads
+func (r *Repository[E, D]) SelectById(ctx context.Context, id string) (*D, error) {
+	entity := r.config.NewEntity(new(D))
+
+	query := r.query(ctx)
+	for _, preload := range r.config.Preloads {
+		query = query.Preload(preload)
+	}
+	query = query.First(entity, "id = ?", id)
+
+	if errors.Is(query.Error, gorm.ErrRecordNotFound) {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, r.config.Errors.Select), customErrors.NotFoundError)
+		return nil, appErr
+	}
+	if err := query.Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Select), customErrors.DatabaseError)
+		return nil, appErr
+	}
+	return entity.ToDto(), nil
+}
+
+// Delete soft deletes the entity of payload, a non zero payload version must match
+func (r *Repository[E, D]) Delete(ctx context.Context, payload *D) error {
+	entity := r.config.NewEntity(payload)
+	return r.DeleteById(ctx, entity.GetId().String(), entity.GetVersion())
+}
+
+// DeleteById soft deletes an entity, a non zero version must match
+func (r *Repository[E, D]) DeleteById(ctx context.Context, id string, version int64) error {
+	entity := r.config.NewEntity(new(D))
+
+	return database.WithContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		query := tx.First(entity, "id = ?", id)
+		if errors.Is(query.Error, gorm.ErrRecordNotFound) {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, r.config.Errors.Delete), customErrors.NotFoundError)
+			return appErr
+		}
+		if err := query.Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Delete), customErrors.DatabaseError)
+			return appErr
+		}
+		if err := runHook(r.config.BeforeDelete, tx, entity); err != nil {
+			return err
+		}
+
+		// the version moves on so a tag read before the delete no longer matches the restored entity
+		query = tx.Model(entity)
+		if version > 0 {
+			query = query.Where("version = ?", version)
+		}
+		query = query.UpdateColumn("version", gorm.Expr("version + 1"))
+		if err := query.Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Delete), customErrors.DatabaseError)
+			return appErr
+		}
+		if query.RowsAffected == 0 && version > 0 {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(model.ErrStaleVersion, r.config.Errors.Delete), customErrors.PreconditionFailedError)
+			return appErr
+		}
+		if err := tx.Delete(entity).Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Delete), customErrors.DatabaseError)
+			return appErr
+		}
+		return runHook(r.config.AfterDelete, tx, entity)
+	})
+}
+
+// Restore undeletes a soft deleted entity
+func (r *Repository[E, D]) Restore(ctx context.Context, id string) error {
+	entity := r.config.NewEntity(new(D))
+
+	return database.WithContext(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		query := tx.Unscoped().First(entity, "id = ? AND deleted_at IS NOT NULL", id)
+		if errors.Is(query.Error, gorm.ErrRecordNotFound) {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, r.config.Errors.Restore), customErrors.NotFoundError)
+			return appErr
+		}
+		if err := query.Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Restore), customErrors.DatabaseError)
+			return appErr
+		}
+
+		updates := map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}
+		if err := tx.Unscoped().Model(entity).Updates(updates).Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, r.config.Errors.Restore), customErrors.DatabaseError)
+			return appErr
+		}
+		return nil
+	})
+}
@@ -0,0 +1,35 @@
+package crud
+
+import (
+	"context"
+)
+
+// Store is the part of a repository the generic service reads and deletes through
+type Store[D any] interface {
+	SelectById(context.Context, string) (*D, error)
+	DeleteById(context.Context, string, int64) error
+	Restore(context.Context, string) error
+}
+
+// Service implements the read, delete and restore use cases shared by the modules
+type Service[D any] struct {
+	store Store[D]
+}
+
+// NewService creates a new generic service
+func NewService[D any](store Store[D]) *Service[D] {
+	return &Service[D]{store: store}
+}
+
+func (svc *Service[D]) ReadById(ctx context.Context, id string) (*D, error) {
+	return svc.store.SelectById(ctx, id)
+}
+
+// DeleteById soft deletes the record, a non zero version must be the stored one
+func (svc *Service[D]) DeleteById(ctx context.Context, id string, version int64) error {
+	return svc.store.DeleteById(ctx, id, version)
+}
+
+func (svc *Service[D]) RestoreById(ctx context.Context, id string) error {
+	return svc.store.Restore(ctx, id)
+}
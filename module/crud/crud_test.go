@@ -0,0 +1,103 @@
+package crud
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ericmarcelinotju/gram/dto"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
+	"github.com/ericmarcelinotju/gram/model"
+)
+
+type itemDto struct {
+	Id      string
+	Name    string
+	Version int64
+}
+
+type itemEntity struct {
+	model.Model
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+func newItemEntity(item *itemDto) *itemEntity {
+	id, _ := uuid.Parse(item.Id)
+	return &itemEntity{Model: model.Model{Id: id, Version: item.Version}, Name: item.Name}
+}
+
+func (entity *itemEntity) ToDto() *itemDto {
+	return &itemDto{Id: entity.Id.String(), Name: entity.Name, Version: entity.Version}
+}
+
+func setupRepository(t *testing.T) *Repository[*itemEntity, itemDto] {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "crud.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, db.Exec(`CREATE TABLE item_entities (
+		id text PRIMARY KEY, name text, created_at datetime, updated_at datetime,
+		version integer NOT NULL DEFAULT 1, deleted_at datetime
+	)`).Error, nil)
+
+	return NewRepository(db, Config[*itemEntity, itemDto]{
+		NewEntity: newItemEntity,
+		Sort:      dto.SortConfig{Fields: map[string]string{"name": "name"}, Default: "name"},
+		BeforeInsert: func(tx *gorm.DB, entity *itemEntity) error {
+			entity.Id = uuid.New()
+			return nil
+		},
+		BeforeDelete: func(tx *gorm.DB, entity *itemEntity) error {
+			if entity.Name == "locked" {
+				return errors.New("locked")
+			}
+			return nil
+		},
+	})
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	repo := setupRepository(t)
+
+	item := itemDto{Name: "a"}
+	assert.Equal(t, repo.Insert(ctx, &item), nil)
+	assert.NotEqual(t, item.Id, "")
+	assert.Equal(t, item.Version, int64(1))
+
+	stale := itemDto{Id: item.Id, Name: "b", Version: 2}
+	assert.Equal(t, errors.Is(repo.Update(ctx, &stale), customErrors.ErrPreconditionFailed), true)
+
+	update := itemDto{Id: item.Id, Name: "b", Version: 1}
+	assert.Equal(t, repo.Update(ctx, &update), nil)
+	assert.Equal(t, update.Version, int64(2))
+
+	locked := itemDto{Name: "locked"}
+	assert.Equal(t, repo.Insert(ctx, &locked), nil)
+	assert.NotEqual(t, repo.DeleteById(ctx, locked.Id, 0), nil)
+
+	items, total, err := repo.Select(ctx, nil, nil, nil, nil, nil, nil)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, total, int64(2))
+	assert.Equal(t, items[0].Name, "b")
+
+	assert.Equal(t, errors.Is(repo.DeleteById(ctx, item.Id, 1), customErrors.ErrPreconditionFailed), true)
+	assert.Equal(t, repo.DeleteById(ctx, item.Id, 2), nil)
+	_, err = repo.SelectById(ctx, item.Id)
+	assert.Equal(t, errors.Is(err, customErrors.ErrNotFound), true)
+
+	assert.Equal(t, repo.Restore(ctx, item.Id), nil)
+	restored, err := repo.SelectById(ctx, item.Id)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, restored.Name, "b")
+	// the delete and the restore each moved the version on
+	assert.Equal(t, restored.Version, int64(4))
+	assert.Equal(t, errors.Is(repo.Restore(ctx, item.Id), customErrors.ErrNotFound), true)
+}
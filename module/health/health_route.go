@@ -1,8 +1,12 @@
 package health
 
 import (
+	"errors"
+	"net/http"
 	"time"
 
+	"github.com/ericmarcelinotju/gram/plugins/database"
+	"github.com/ericmarcelinotju/gram/utils/logger"
 	"github.com/ericmarcelinotju/gram/utils/response"
 	"github.com/gin-gonic/gin"
 )
@@ -17,7 +21,29 @@ func NewRoutesFactory(group *gin.RouterGroup) func() {
 				"zone":  zone,
 			})
 		})
+
+		// database answers 503 when the primary does not answer, it is public so the cause and the pools stay hidden
+		group.GET("/database", func(c *gin.Context) {
+			if err := database.Ping(c); err != nil {
+				logger.Printf(c, "[HEALTH] : database ping failed : %s\n", err)
+				response.ResponseError(c, errors.New("database is unavailable"), http.StatusServiceUnavailable)
+				return
+			}
+			response.ResponseSuccess(c, gin.H{"status": "ok"})
+		})
 	}
 
 	return healthRoutesFactory
 }
+
+// NewStatisticRoutesFactory create and returns a factory to create the authenticated routes reading the pool statistics
+func NewStatisticRoutesFactory(router *gin.RouterGroup) func() {
+	group := router.Group("/api/statistic")
+	statisticRoutesFactory := func() {
+		group.GET("/database", func(c *gin.Context) {
+			response.ResponseSuccess(c, database.Stats())
+		})
+	}
+
+	return statisticRoutesFactory
+}
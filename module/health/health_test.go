@@ -0,0 +1,26 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ericmarcelinotju/gram/utils/response"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+)
+
+func TestDatabaseHealthHidesCause(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	NewRoutesFactory(router.Group("/health"))()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/health/database", nil))
+
+	var body response.SetResponse
+	assert.Equal(t, json.Unmarshal(recorder.Body.Bytes(), &body), nil)
+	assert.Equal(t, recorder.Code, http.StatusServiceUnavailable)
+	assert.Equal(t, body.Data, "database is unavailable")
+}
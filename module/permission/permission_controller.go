@@ -1,9 +1,11 @@
 package permission
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/ericmarcelinotju/gram/dto"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/ericmarcelinotju/gram/utils/request"
 	"github.com/ericmarcelinotju/gram/utils/response"
 	"github.com/gin-gonic/gin"
@@ -11,7 +13,7 @@ import (
 
 // GetPermission godoc
 // @Summary     Get list of permissions
-// @Description Get list of permissions
+// @Description Get list of permissions, filter with field[operator]=value e.g. module[ilike]=user, select fields=id,module
 // @Tags        Permission
 // @Accept      json
 // @Produce     json
@@ -32,12 +34,13 @@ func Get(service Service) func(c *gin.Context) {
 			return
 		}
 
-		result := dto.ListDto[dto.PermissionDto]{
-			Data:  permissions,
-			Total: total,
+		results, err := dto.Project(permissions, &payload.FieldsDto, fieldsConfig)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
 		}
 
-		response.ResponseSuccess(c, result)
+		response.ResponseList(c, results, total, payload.PaginationDto)
 	}
 }
 
@@ -48,6 +51,7 @@ func Get(service Service) func(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id    path       string   true   "Permission ID"
+// @Param       If-None-Match   header   string   false   "Entity tag of a cached version"
 // @Success     200   {object}   response.SetResponse{data=dto.PermissionDto}
 // @Router      /permission/{id}  [get]
 // @Security    Auth
@@ -64,7 +68,7 @@ func GetDetail(service Service) func(c *gin.Context) {
 			return
 		}
 
-		response.ResponseSuccess(c, res)
+		response.ResponseVersioned(c, res, res.Version)
 	}
 }
 
@@ -102,8 +106,11 @@ func Post(service Service) func(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id           path       string           			 true   "Permission ID"
+// @Param       If-Match   header   string   false   "Entity tag of the version being updated"
 // @Param       permission   body       dto.PutPermissionDto   true   "Permission Data"
 // @Success     200          {object}   response.SetResponse{data=dto.PermissionDto}
+// @Failure     400   {object}   response.SetResponse
+// @Failure     412   {object}   response.SetResponse
 // @Router      /permission/{id} [put]
 // @Security    Auth
 func Put(service Service) func(c *gin.Context) {
@@ -119,14 +126,27 @@ func Put(service Service) func(c *gin.Context) {
 			return
 		}
 		payload.Id = id
+		payload.Version, err = request.IfMatch(c)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusBadRequest)
+			return
+		}
 
 		res, err := service.Update(c, payload)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
 		if err != nil {
 			response.ResponseError(c, err, http.StatusInternalServerError)
 			return
 		}
 
-		response.ResponseSuccess(c, res)
+		response.ResponseVersioned(c, res, res.Version)
 	}
 }
 
@@ -137,7 +157,11 @@ func Put(service Service) func(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id    path       string   true   "Permission ID"
+// @Param       If-Match   header   string   false   "Entity tag of the version being deleted"
 // @Success     200   {object}   response.SetResponse
+// @Failure     400   {object}   response.SetResponse
+// @Failure     404   {object}   response.SetResponse
+// @Failure     412   {object}   response.SetResponse
 // @Router      /permission/{id} [delete]
 // @Security    Auth
 func Delete(service Service) func(c *gin.Context) {
@@ -148,7 +172,53 @@ func Delete(service Service) func(c *gin.Context) {
 			return
 		}
 
-		err = service.DeleteById(c, id)
+		version, err := request.IfMatch(c)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusBadRequest)
+			return
+		}
+
+		err = service.DeleteById(c, id, version)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if errors.Is(err, customErrors.ErrNotFound) {
+			response.ResponseError(c, err, http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, nil)
+	}
+}
+
+// RestorePermission godoc
+// @Summary     Restore permission by id
+// @Description Restore a deleted permission by id
+// @Tags        Permission
+// @Accept      json
+// @Produce     json
+// @Param       id    path       string   true   "Permission ID"
+// @Success     200   {object}   response.SetResponse
+// @Router      /permission/{id}/restore [post]
+// @Security    Auth
+func Restore(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		id, err := request.BindId(c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		err = service.RestoreById(c, id)
 		if err != nil {
 			response.ResponseError(c, err, http.StatusInternalServerError)
 			return
@@ -2,128 +2,128 @@ package permission
 
 import (
 	"context"
-	"errors"
+	"time"
 
 	pkgErr "github.com/pkg/errors"
 
 	"github.com/ericmarcelinotju/gram/dto"
 	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/ericmarcelinotju/gram/model"
+	"github.com/ericmarcelinotju/gram/module/crud"
+	"github.com/ericmarcelinotju/gram/plugins/database"
+	"github.com/google/uuid"
 
 	"gorm.io/gorm"
 )
 
 const (
-	insertError = "Error in inserting new permission"
-	updateError = "Error in updating permission"
-	deleteError = "Error in deleting permission"
-	selectError = "Error in selecting permissions in the database"
+	insertError  = "Error in inserting new permission"
+	updateError  = "Error in updating permission"
+	deleteError  = "Error in deleting permission"
+	restoreError = "Error in restoring permission"
+	purgeError   = "Error in purging deleted permissions"
+	selectError  = "Error in selecting permissions in the database"
 )
 
+// filterConfig whitelists the fields permissions can be filtered on
+var filterConfig = dto.FilterConfig{
+	Fields: map[string]dto.FilterField{
+		"method":      {Column: "method", Type: dto.FilterString},
+		"module":      {Column: "module", Type: dto.FilterString},
+		"description": {Column: "description", Type: dto.FilterString},
+		"created_at":  {Column: "created_at", Type: dto.FilterTime},
+		"updated_at":  {Column: "updated_at", Type: dto.FilterTime},
+	},
+	Search: []string{"method", "module", "description"},
+}
+
+// sortConfig whitelists the fields permissions can be sorted on
+var sortConfig = dto.SortConfig{
+	Fields: map[string]string{
+		"id":         "id",
+		"method":     "method",
+		"module":     "module",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Default: "created_at:desc",
+}
+
+// fieldsConfig whitelists the fields permission lists can select
+var fieldsConfig = dto.FieldsConfig{
+	Fields: map[string]string{
+		"id":          "id",
+		"method":      "method",
+		"module":      "module",
+		"description": "description",
+		"created_at":  "created_at",
+		"updated_at":  "updated_at",
+		"deleted_at":  "deleted_at",
+	},
+}
+
 // Repository provides an abstraction on top of the permission data source
 type Repository interface {
 	Insert(context.Context, *dto.PermissionDto) error
 	Update(context.Context, *dto.PermissionDto) error
-	Select(context.Context, *dto.PermissionDto, *dto.PaginationDto, *dto.SortDto) ([]dto.PermissionDto, int64, error)
+	Select(context.Context, *dto.PermissionDto, *dto.FilterDto, *dto.FieldsDto, *dto.PaginationDto, *dto.SortDto, *dto.DeletedDto) ([]dto.PermissionDto, int64, error)
 	SelectById(context.Context, string) (*dto.PermissionDto, error)
 	Delete(context.Context, *dto.PermissionDto) error
+	DeleteById(context.Context, string, int64) error
+	Restore(context.Context, string) error
+	Purge(context.Context, time.Time) (int64, error)
 }
 
 type repository struct {
+	*crud.Repository[*model.PermissionEntity, dto.PermissionDto]
 	db *gorm.DB
 }
 
 // New creates a new Store struct
 func NewRepository(db *gorm.DB) *repository {
-	return &repository{db: db}
-}
-
-func (s *repository) Insert(ctx context.Context, payload *dto.PermissionDto) error {
-	entity := model.NewPermissionEntity(payload)
-
-	if err := s.db.WithContext(ctx).Create(entity).Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.DatabaseError)
-		return appErr
+	return &repository{
+		Repository: crud.NewRepository(db, crud.Config[*model.PermissionEntity, dto.PermissionDto]{
+			NewEntity: model.NewPermissionEntity,
+			Errors: crud.Errors{
+				Insert:  insertError,
+				Update:  updateError,
+				Delete:  deleteError,
+				Restore: restoreError,
+				Select:  selectError,
+			},
+			Filter: filterConfig,
+			Sort:   sortConfig,
+			Fields: fieldsConfig,
+		}),
+		db: db,
 	}
-
-	payload.Id = entity.Id.String()
-
-	return nil
 }
 
-func (s *repository) Update(ctx context.Context, payload *dto.PermissionDto) error {
-	entity := model.NewPermissionEntity(payload)
-
-	if err := s.db.WithContext(ctx).Model(entity).Updates(entity).Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, updateError), customErrors.DatabaseError)
-		return appErr
-	}
-
-	return nil
-}
-
-func (s *repository) Select(
-	ctx context.Context,
-	filter *dto.PermissionDto,
-	pagination *dto.PaginationDto,
-	sort *dto.SortDto,
-) ([]dto.PermissionDto, int64, error) {
-	var total int64
-	var entities []model.PermissionEntity
-
-	query := s.db.WithContext(ctx).
-		Model(&model.PermissionEntity{})
-
-	if filter != nil {
-		query.Where(model.NewPermissionEntity(filter))
-	}
-	query.Count(&total)
-	if pagination != nil {
-		query = pagination.Apply(query)
-	}
-	if sort != nil {
-		sort.Apply(query)
-	}
-	query.Find(&entities)
-
-	if errors.Is(query.Error, gorm.ErrRecordNotFound) {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, selectError), customErrors.NotFoundError)
-		return nil, total, appErr
-	}
-
+// Purge permanently deletes permissions deleted before the time and removes them from roles
+func (s *repository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var ids []uuid.UUID
+	query := database.WithContext(ctx, s.db).
+		Unscoped().
+		Model(&model.PermissionEntity{}).
+		Where("deleted_at < ?", before).
+		Pluck("id", &ids)
 	if err := query.Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
-		return nil, total, appErr
-	}
-
-	var results = make([]dto.PermissionDto, len(entities))
-	for i, element := range entities {
-		results[i] = *element.ToDto()
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, purgeError), customErrors.DatabaseError)
+		return 0, appErr
 	}
-
-	return results, total, nil
-}
-
-func (s *repository) SelectById(ctx context.Context, id string) (*dto.PermissionDto, error) {
-	var permission model.PermissionEntity
-
-	if err := s.db.WithContext(ctx).
-		Model(&model.PermissionEntity{}).
-		First(&permission, "id = ?", id).Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
-		return nil, appErr
+	if len(ids) == 0 {
+		return 0, nil
 	}
 
-	return permission.ToDto(), nil
-}
-
-func (s *repository) Delete(ctx context.Context, payload *dto.PermissionDto) error {
-	entity := model.NewPermissionEntity(payload)
-
-	if err := s.db.WithContext(ctx).Delete(entity).Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, deleteError), customErrors.DatabaseError)
-		return appErr
+	err := database.WithContext(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM role_permissions WHERE permission_entity_id IN ?", ids).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&model.PermissionEntity{}).Error
+	})
+	if err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, purgeError), customErrors.DatabaseError)
+		return 0, appErr
 	}
-
-	return nil
+	return int64(len(ids)), nil
 }
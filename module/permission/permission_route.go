@@ -13,6 +13,7 @@ func NewRoutesFactory(router *gin.RouterGroup) func(service Service) {
 		group.POST("", Post(service))
 		group.PUT("/:id", Put(service))
 		group.DELETE("/:id", Delete(service))
+		group.POST("/:id/restore", Restore(service))
 	}
 	return permissionRoutesFactory
 }
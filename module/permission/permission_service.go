@@ -2,8 +2,10 @@ package permission
 
 import (
 	"context"
+	"time"
 
 	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/ericmarcelinotju/gram/module/crud"
 )
 
 // Service defines permission service behavior.
@@ -12,16 +14,19 @@ type Service interface {
 	Read(ctx context.Context, filter *dto.GetPermissionDto) ([]dto.PermissionDto, int64, error)
 	ReadById(context.Context, string) (*dto.PermissionDto, error)
 	Update(context.Context, *dto.PutPermissionDto) (*dto.PermissionDto, error)
-	DeleteById(context.Context, string) error
+	DeleteById(context.Context, string, int64) error
+	RestoreById(context.Context, string) error
+	Purge(context.Context, time.Time) (int64, error)
 }
 
 type service struct {
+	*crud.Service[dto.PermissionDto]
 	repo Repository
 }
 
 // NewService creates a new service struct
 func NewService(repo Repository) *service {
-	return &service{repo: repo}
+	return &service{Service: crud.NewService[dto.PermissionDto](repo), repo: repo}
 }
 
 func (svc *service) Create(ctx context.Context, payload *dto.PostPermissionDto) (res *dto.PermissionDto, err error) {
@@ -46,26 +51,26 @@ func (svc *service) Read(ctx context.Context, payload *dto.GetPermissionDto) ([]
 	return svc.repo.Select(
 		ctx,
 		filter,
+		&payload.FilterDto,
+		&payload.FieldsDto,
 		payload.PaginationDto,
 		payload.SortDto,
+		payload.DeletedDto,
 	)
 }
 
-func (svc *service) ReadById(ctx context.Context, id string) (*dto.PermissionDto, error) {
-	return svc.repo.SelectById(ctx, id)
-}
-
 func (svc *service) Update(ctx context.Context, payload *dto.PutPermissionDto) (res *dto.PermissionDto, err error) {
 	res = &dto.PermissionDto{
 		Id:          payload.Id,
 		Method:      payload.Method,
 		Module:      payload.Module,
 		Description: payload.Description,
+		Version:     payload.Version,
 	}
 	err = svc.repo.Update(ctx, res)
 	return
 }
 
-func (svc *service) DeleteById(ctx context.Context, id string) error {
-	return svc.repo.Delete(ctx, &dto.PermissionDto{Id: id})
+func (svc *service) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return svc.repo.Purge(ctx, before)
 }
@@ -107,7 +107,7 @@ func TestDeleteUserHandler(t *testing.T) {
 
 	id := "aasdasdasd"
 
-	err := svc.DeleteById(ctx, id)
+	err := svc.DeleteById(ctx, id, 0)
 
 	assert.NotEqual(t, err, nil)
 
@@ -1,9 +1,11 @@
 package role
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/ericmarcelinotju/gram/dto"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/ericmarcelinotju/gram/utils/request"
 	"github.com/ericmarcelinotju/gram/utils/response"
 	"github.com/gin-gonic/gin"
@@ -11,7 +13,7 @@ import (
 
 // GetRole godoc
 // @Summary     Get list of roles
-// @Description Get list of roles
+// @Description Get list of roles, filter with field[operator]=value e.g. name[ilike]=jo, select fields=id,name and include=permissions
 // @Tags        Role
 // @Accept      json
 // @Produce     json
@@ -33,12 +35,13 @@ func Get(service Service) func(c *gin.Context) {
 			return
 		}
 
-		result := dto.ListDto[dto.RoleDto]{
-			Data:  roles,
-			Total: total,
+		results, err := dto.Project(roles, &payload.FieldsDto, fieldsConfig)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
 		}
 
-		response.ResponseSuccess(c, result)
+		response.ResponseList(c, results, total, payload.PaginationDto)
 	}
 }
 
@@ -49,6 +52,7 @@ func Get(service Service) func(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id          path       string   true   "Role ID"
+// @Param       If-None-Match   header   string   false   "Entity tag of a cached version"
 // @Success     200         {object}   response.SetResponse{data=dto.RoleDto}
 // @Router      /role/{id}  [get]
 // @Security    Auth
@@ -65,7 +69,7 @@ func GetDetail(service Service) func(c *gin.Context) {
 			return
 		}
 
-		response.ResponseSuccess(c, result)
+		response.ResponseVersioned(c, result, result.Version)
 	}
 }
 
@@ -77,6 +81,7 @@ func GetDetail(service Service) func(c *gin.Context) {
 // @Produce     json
 // @Param       role   body       dto.PostRoleDto   true   "Role Data"
 // @Success     200    {object}   response.SetResponse{data=dto.RoleDto}
+// @Failure     422    {object}   response.SetResponse
 // @Router      /role  [post]
 // @Security    Auth
 func Post(service Service) func(c *gin.Context) {
@@ -87,6 +92,10 @@ func Post(service Service) func(c *gin.Context) {
 			return
 		}
 		res, err := service.Create(c, payload)
+		if errors.Is(err, customErrors.ErrValidationError) {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
 		if err != nil {
 			response.ResponseError(c, err, http.StatusInternalServerError)
 			return
@@ -103,8 +112,12 @@ func Post(service Service) func(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id     path       string           true   "Role ID"
+// @Param       If-Match   header   string   false   "Entity tag of the version being updated"
 // @Param       role   body       dto.PutRoleDto   true   "Role Data"
 // @Success     200    {object}   response.SetResponse{data=dto.RoleDto}
+// @Failure     400   {object}   response.SetResponse
+// @Failure     412   {object}   response.SetResponse
+// @Failure     422   {object}   response.SetResponse
 // @Router      /role/{id} [put]
 // @Security    Auth
 func Put(service Service) func(c *gin.Context) {
@@ -120,14 +133,31 @@ func Put(service Service) func(c *gin.Context) {
 			return
 		}
 		payload.Id = id
+		payload.Version, err = request.IfMatch(c)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusBadRequest)
+			return
+		}
 
 		res, err := service.Update(c, payload)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if errors.Is(err, customErrors.ErrValidationError) {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
 		if err != nil {
 			response.ResponseError(c, err, http.StatusInternalServerError)
 			return
 		}
 
-		response.ResponseSuccess(c, res)
+		response.ResponseVersioned(c, res, res.Version)
 	}
 }
 
@@ -138,7 +168,12 @@ func Put(service Service) func(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id    path       string   true   "Role ID"
+// @Param       If-Match   header   string   false   "Entity tag of the version being deleted"
 // @Success     200   {object}   response.SetResponse
+// @Failure     400   {object}   response.SetResponse
+// @Failure     404   {object}   response.SetResponse
+// @Failure     409   {object}   response.SetResponse
+// @Failure     412   {object}   response.SetResponse
 // @Router      /role/{id} [delete]
 // @Security    Auth
 func Delete(service Service) func(c *gin.Context) {
@@ -149,7 +184,57 @@ func Delete(service Service) func(c *gin.Context) {
 			return
 		}
 
-		err = service.DeleteById(c, id)
+		version, err := request.IfMatch(c)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusBadRequest)
+			return
+		}
+
+		err = service.DeleteById(c, id, version)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if errors.Is(err, customErrors.ErrNotFound) {
+			response.ResponseError(c, err, http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, customErrors.ErrValidationError) {
+			response.ResponseError(c, err, http.StatusConflict)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, nil)
+	}
+}
+
+// RestoreRole godoc
+// @Summary     Restore role by id
+// @Description Restore a deleted role by id
+// @Tags        Role
+// @Accept      json
+// @Produce     json
+// @Param       id    path       string   true   "Role ID"
+// @Success     200   {object}   response.SetResponse
+// @Router      /role/{id}/restore [post]
+// @Security    Auth
+func Restore(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		id, err := request.BindId(c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		err = service.RestoreById(c, id)
 		if err != nil {
 			response.ResponseError(c, err, http.StatusInternalServerError)
 			return
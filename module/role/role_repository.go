@@ -2,142 +2,187 @@ package role
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"time"
 
 	pkgErr "github.com/pkg/errors"
 
 	"github.com/ericmarcelinotju/gram/dto"
 	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/ericmarcelinotju/gram/model"
+	"github.com/ericmarcelinotju/gram/module/crud"
+	"github.com/ericmarcelinotju/gram/plugins/database"
 
 	"gorm.io/gorm"
 )
 
 const (
-	insertError = "Error in inserting new role"
-	updateError = "Error in updating role"
-	deleteError = "Error in deleting role"
-	selectError = "Error in selecting roles in the database"
+	insertError  = "Error in inserting new role"
+	updateError  = "Error in updating role"
+	deleteError  = "Error in deleting role"
+	restoreError = "Error in restoring role"
+	purgeError   = "Error in purging deleted roles"
+	selectError  = "Error in selecting roles in the database"
 )
 
+// filterConfig whitelists the fields roles can be filtered on
+var filterConfig = dto.FilterConfig{
+	Fields: map[string]dto.FilterField{
+		"name":        {Column: "name", Type: dto.FilterString},
+		"description": {Column: "description", Type: dto.FilterString},
+		"level":       {Column: "level", Type: dto.FilterNumber},
+		"created_at":  {Column: "created_at", Type: dto.FilterTime},
+		"updated_at":  {Column: "updated_at", Type: dto.FilterTime},
+	},
+	Search: []string{"name", "description"},
+}
+
+// sortConfig whitelists the fields roles can be sorted on
+var sortConfig = dto.SortConfig{
+	Fields: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"level":      "level",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Default: "created_at:desc",
+}
+
+// fieldsConfig whitelists the fields and relations role lists can select
+var fieldsConfig = dto.FieldsConfig{
+	Fields: map[string]string{
+		"id":          "id",
+		"name":        "name",
+		"description": "description",
+		"level":       "level",
+		"created_at":  "created_at",
+		"updated_at":  "updated_at",
+		"deleted_at":  "deleted_at",
+	},
+	Includes: map[string]dto.IncludeConfig{
+		"permissions": {Preload: "Permissions"},
+	},
+}
+
 // Repository provides an abstraction on top of the role data source
 type Repository interface {
 	Insert(context.Context, *dto.RoleDto) error
 	Update(context.Context, *dto.RoleDto) error
-	Select(context.Context, *dto.RoleDto, *dto.PaginationDto, *dto.SortDto) ([]dto.RoleDto, int64, error)
+	Select(context.Context, *dto.RoleDto, *dto.FilterDto, *dto.FieldsDto, *dto.PaginationDto, *dto.SortDto, *dto.DeletedDto) ([]dto.RoleDto, int64, error)
 	SelectById(context.Context, string) (*dto.RoleDto, error)
 	Delete(context.Context, *dto.RoleDto) error
+	DeleteById(context.Context, string, int64) error
+	Restore(context.Context, string) error
+	Purge(context.Context, time.Time) (int64, error)
 }
 
 type repository struct {
+	*crud.Repository[*model.RoleEntity, dto.RoleDto]
 	db *gorm.DB
 }
 
 // New creates a new Store struct
 func NewRepository(db *gorm.DB) *repository {
-	return &repository{db: db}
-}
-
-func (s *repository) Insert(ctx context.Context, payload *dto.RoleDto) error {
-	entity := model.NewRoleEntity(payload)
-
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Omit("Permissions").Create(entity).Error; err != nil {
-			appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.DatabaseError)
-			return appErr
-		}
-		if err := tx.Model(entity).Association("Permissions").Append(entity.Permissions); err != nil {
-			appErr := customErrors.NewAppError(pkgErr.Wrap(err, updateError), customErrors.DatabaseError)
-			return appErr
-		}
-		payload.Id = entity.Id.String()
-		return nil
-	})
-}
-
-func (s *repository) Update(ctx context.Context, payload *dto.RoleDto) error {
-	entity := model.NewRoleEntity(payload)
-
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Model(entity).Omit("Permissions").Updates(entity).Error; err != nil {
-			appErr := customErrors.NewAppError(pkgErr.Wrap(err, updateError), customErrors.DatabaseError)
-			return appErr
-		}
-
-		if err := tx.Model(entity).Association("Permissions").Replace(entity.Permissions); err != nil {
-			appErr := customErrors.NewAppError(pkgErr.Wrap(err, updateError), customErrors.DatabaseError)
-			return appErr
-		}
-
-		return nil
-	})
+	return &repository{
+		Repository: crud.NewRepository(db, crud.Config[*model.RoleEntity, dto.RoleDto]{
+			NewEntity: model.NewRoleEntity,
+			Errors: crud.Errors{
+				Insert:  insertError,
+				Update:  updateError,
+				Delete:  deleteError,
+				Restore: restoreError,
+				Select:  selectError,
+			},
+			Filter:       filterConfig,
+			Sort:         sortConfig,
+			Fields:       fieldsConfig,
+			Preloads:     []string{"Permissions"},
+			Omit:         []string{"Permissions"},
+			BeforeInsert: checkDeletedName,
+			AfterInsert:  appendPermissions,
+			BeforeUpdate: checkDeletedName,
+			AfterUpdate:  replacePermissions,
+			BeforeDelete: checkUnassigned,
+		}),
+		db: db,
+	}
 }
 
-func (s *repository) Select(
-	ctx context.Context,
-	filter *dto.RoleDto,
-	pagination *dto.PaginationDto,
-	sort *dto.SortDto,
-) ([]dto.RoleDto, int64, error) {
-
-	var total int64
-	var entities []model.RoleEntity
-
-	query := s.db.WithContext(ctx).
+// checkDeletedName rejects a name still held by a soft deleted role, the role has to be restored instead
+func checkDeletedName(tx *gorm.DB, entity *model.RoleEntity) error {
+	var deleted int64
+	query := tx.Unscoped().
 		Model(&model.RoleEntity{}).
-		Preload("Permissions")
-
-	if filter != nil {
-		query.Where(model.NewRoleEntity(filter))
-	}
-	query.Count(&total)
-	if pagination != nil {
-		pagination.Apply(query)
-	}
-	if sort != nil {
-		sort.Apply(query)
-	}
-	query.Find(&entities)
-
-	if errors.Is(query.Error, gorm.ErrRecordNotFound) {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, selectError), customErrors.NotFoundError)
-		return nil, total, appErr
-	}
-
+		Where("deleted_at IS NOT NULL").
+		Where("name = ?", entity.Name).
+		Count(&deleted)
 	if err := query.Error; err != nil {
 		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
-		return nil, total, appErr
+		return appErr
 	}
-
-	var results = make([]dto.RoleDto, len(entities))
-	for i, element := range entities {
-		results[i] = *element.ToDto()
+	if deleted > 0 {
+		appErr := customErrors.NewAppError(fmt.Errorf("name '%s' belongs to a deleted role, restore it", entity.Name), customErrors.ValidationError)
+		return appErr
 	}
-
-	return results, total, nil
+	return nil
 }
 
-func (s *repository) SelectById(ctx context.Context, id string) (*dto.RoleDto, error) {
-	var entity model.RoleEntity
-
-	if err := s.db.WithContext(ctx).
-		Model(&model.RoleEntity{}).
-		Preload("Permissions").
-		First(&entity, "id = ?", id).Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
-		return nil, appErr
+func appendPermissions(tx *gorm.DB, entity *model.RoleEntity) error {
+	if err := tx.Model(entity).Association("Permissions").Append(entity.Permissions); err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.DatabaseError)
+		return appErr
 	}
-
-	return entity.ToDto(), nil
+	return nil
 }
 
-func (s *repository) Delete(ctx context.Context, payload *dto.RoleDto) error {
-	entity := model.NewRoleEntity(payload)
+func replacePermissions(tx *gorm.DB, entity *model.RoleEntity) error {
+	if err := tx.Model(entity).Association("Permissions").Replace(entity.Permissions); err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, updateError), customErrors.DatabaseError)
+		return appErr
+	}
+	return nil
+}
 
-	if err := s.db.WithContext(ctx).Delete(entity).Error; err != nil {
+// checkUnassigned only lets roles that are no longer assigned to any user be deleted
+func checkUnassigned(tx *gorm.DB, entity *model.RoleEntity) error {
+	var users int64
+	if err := tx.Model(&model.UserEntity{}).Where("role_id = ?", entity.Id).Count(&users).Error; err != nil {
 		appErr := customErrors.NewAppError(pkgErr.Wrap(err, deleteError), customErrors.DatabaseError)
 		return appErr
 	}
-
+	if users > 0 {
+		appErr := customErrors.NewAppError(fmt.Errorf("role is still assigned to %d users", users), customErrors.ValidationError)
+		return appErr
+	}
 	return nil
 }
+
+// Purge permanently deletes roles deleted before the time, roles still referenced by users are kept
+func (s *repository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var entities []model.RoleEntity
+	query := database.WithContext(ctx, s.db).
+		Unscoped().
+		Where("deleted_at < ?", before).
+		Where("id NOT IN (?)", s.db.Unscoped().Model(&model.UserEntity{}).Select("role_id")).
+		Find(&entities)
+	if err := query.Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, purgeError), customErrors.DatabaseError)
+		return 0, appErr
+	}
+	if len(entities) == 0 {
+		return 0, nil
+	}
+
+	err := database.WithContext(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entities).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&entities).Error
+	})
+	if err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, purgeError), customErrors.DatabaseError)
+		return 0, appErr
+	}
+	return int64(len(entities)), nil
+}
@@ -2,8 +2,10 @@ package role
 
 import (
 	"context"
+	"time"
 
 	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/ericmarcelinotju/gram/module/crud"
 )
 
 // Service defines role service behavior.
@@ -12,16 +14,19 @@ type Service interface {
 	Read(context.Context, *dto.GetRoleDto) ([]dto.RoleDto, int64, error)
 	ReadById(context.Context, string) (*dto.RoleDto, error)
 	Update(context.Context, *dto.PutRoleDto) (*dto.RoleDto, error)
-	DeleteById(context.Context, string) error
+	DeleteById(context.Context, string, int64) error
+	RestoreById(context.Context, string) error
+	Purge(context.Context, time.Time) (int64, error)
 }
 
 type service struct {
+	*crud.Service[dto.RoleDto]
 	repo Repository
 }
 
 // NewService creates a new service struct
 func NewService(repo Repository) *service {
-	return &service{repo: repo}
+	return &service{Service: crud.NewService[dto.RoleDto](repo), repo: repo}
 }
 
 func (svc *service) Create(ctx context.Context, payload *dto.PostRoleDto) (res *dto.RoleDto, err error) {
@@ -47,15 +52,14 @@ func (svc *service) Read(ctx context.Context, payload *dto.GetRoleDto) ([]dto.Ro
 	return svc.repo.Select(
 		ctx,
 		filter,
+		&payload.FilterDto,
+		&payload.FieldsDto,
 		payload.PaginationDto,
 		payload.SortDto,
+		payload.DeletedDto,
 	)
 }
 
-func (svc *service) ReadById(ctx context.Context, id string) (*dto.RoleDto, error) {
-	return svc.repo.SelectById(ctx, id)
-}
-
 func (svc *service) Update(ctx context.Context, payload *dto.PutRoleDto) (res *dto.RoleDto, err error) {
 	var permissions []dto.PermissionDto = make([]dto.PermissionDto, len(payload.Permissions))
 	for i, item := range payload.Permissions {
@@ -66,11 +70,12 @@ func (svc *service) Update(ctx context.Context, payload *dto.PutRoleDto) (res *d
 		Name:        payload.Name,
 		Description: payload.Description,
 		Permissions: permissions,
+		Version:     payload.Version,
 	}
 	err = svc.repo.Update(ctx, res)
 	return
 }
 
-func (svc *service) DeleteById(ctx context.Context, id string) error {
-	return svc.repo.Delete(ctx, &dto.RoleDto{Id: id})
+func (svc *service) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return svc.repo.Purge(ctx, before)
 }
@@ -1,9 +1,12 @@
 package setting
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/ericmarcelinotju/gram/dto"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/ericmarcelinotju/gram/utils/request"
 	"github.com/ericmarcelinotju/gram/utils/response"
 	"github.com/gin-gonic/gin"
@@ -26,11 +29,7 @@ func Get(service Service) func(c *gin.Context) {
 			return
 		}
 
-		result := dto.ListDto[dto.SettingDto]{
-			Data: settings,
-		}
-
-		response.ResponseSuccess(c, result)
+		response.ResponseList(c, settings, int64(len(settings)), nil)
 	}
 }
 
@@ -52,6 +51,166 @@ func Save(service Service) func(c *gin.Context) {
 			return
 		}
 		err = service.Save(c, payload)
+		if errors.Is(err, customErrors.ErrValidationError) {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, nil)
+	}
+}
+
+// GetSchema godoc
+// @Summary     Get schema of settings
+// @Description Get JSON Schema of every setting with its type, default, validation rules, description and group
+// @Tags        Setting
+// @Accept      json
+// @Produce     json
+// @Success     200    {object}   response.SetResponse
+// @Router      /setting/schema  [get]
+// @Security    Auth
+func GetSchema() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		response.ResponseSuccess(c, Schema())
+	}
+}
+
+// GetSettingHistory godoc
+// @Summary     Get history of a setting
+// @Description Get the changes of a setting, latest first, with actor, date and old and new values, values of secrets are masked
+// @Tags        Setting
+// @Accept      json
+// @Produce     json
+// @Param       name   path       string   true   "Setting name"
+// @Success     200    {object}   response.SetResponse{data=[]dto.SettingHistoryDto}
+// @Router      /setting/{name}/history  [get]
+// @Security    Auth
+func GetHistory(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		history, err := service.History(c, c.Param("name"))
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseList(c, history, int64(len(history)), nil)
+	}
+}
+
+// RollbackSetting godoc
+// @Summary     Rollback a setting
+// @Description Save again the value a setting had after the change of a version of its history
+// @Tags        Setting
+// @Accept      json
+// @Produce     json
+// @Param       name      path       string   true   "Setting name"
+// @Param       version   path       int      true   "Version of the history"
+// @Success     200       {object}   response.SetResponse
+// @Router      /setting/{name}/rollback/{version}  [post]
+// @Security    Auth
+func Rollback(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		var payload dto.RollbackSettingDto
+		if err := c.ShouldBindUri(&payload); err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		err := service.Rollback(c, &payload)
+		if errors.Is(err, customErrors.ErrNotFound) {
+			response.ResponseError(c, err, http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, customErrors.ErrValidationError) {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, nil)
+	}
+}
+
+// GetSettingSnapshot godoc
+// @Summary     Get list of setting snapshots
+// @Description Get the snapshots of every setting, latest first, values of secrets are masked
+// @Tags        Setting
+// @Accept      json
+// @Produce     json
+// @Success     200    {object}   response.SetResponse{data=[]dto.SettingSnapshotDto}
+// @Router      /setting/snapshot  [get]
+// @Security    Auth
+func GetSnapshots(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		snapshots, err := service.ReadSnapshots(c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseList(c, snapshots, int64(len(snapshots)), nil)
+	}
+}
+
+// PostSettingSnapshot godoc
+// @Summary     Snapshot settings
+// @Description Keep the values of every setting so they can be restored together
+// @Tags        Setting
+// @Accept      json
+// @Produce     json
+// @Param       snapshot   body       dto.PostSettingSnapshotDto   true   "Snapshot Data"
+// @Success     200        {object}   response.SetResponse{data=dto.SettingSnapshotDto}
+// @Router      /setting/snapshot  [post]
+// @Security    Auth
+func PostSnapshot(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		payload, err := request.Bind[dto.PostSettingSnapshotDto](c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		snapshot, err := service.Snapshot(c, payload)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, snapshot)
+	}
+}
+
+// RestoreSettingSnapshot godoc
+// @Summary     Restore a setting snapshot
+// @Description Save every value of a snapshot at once, nothing is saved when one of them is no longer valid
+// @Tags        Setting
+// @Accept      json
+// @Produce     json
+// @Param       id   path       string   true   "Snapshot ID"
+// @Success     200  {object}   response.SetResponse
+// @Router      /setting/snapshot/{id}/restore  [post]
+// @Security    Auth
+func RestoreSnapshot(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		id, err := request.BindId(c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		err = service.RestoreSnapshot(c, id)
+		if errors.Is(err, customErrors.ErrNotFound) {
+			response.ResponseError(c, err, http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, customErrors.ErrValidationError) {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
 		if err != nil {
 			response.ResponseError(c, err, http.StatusInternalServerError)
 			return
@@ -60,3 +219,96 @@ func Save(service Service) func(c *gin.Context) {
 		response.ResponseSuccess(c, nil)
 	}
 }
+
+// maxImportSize bounds the settings files read by imports
+const maxImportSize = 1 << 20
+
+// ExportSetting godoc
+// @Summary     Export settings
+// @Description Download the value of every setting, defaults included, as yaml or json. Secrets are never exported by the API, use the settings command line
+// @Tags        Setting
+// @Accept      json
+// @Produce     octet-stream
+// @Param       format   query      string   false   "yaml (default) or json"
+// @Success     200      {file}     file
+// @Router      /setting/export  [get]
+// @Security    Auth
+func Export(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		payload, err := request.Bind[dto.ExportSettingDto](c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		if payload.Format == "" {
+			payload.Format = FormatYAML
+		}
+
+		values, err := service.Export(c, false)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+		content, err := MarshalSettings(values, payload.Format)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseFile(c, "settings."+payload.Format, content)
+	}
+}
+
+// ImportSetting godoc
+// @Summary     Import settings
+// @Description Save every setting of a yaml or json file at once and list the changes, nothing is saved when one of them is invalid or on dry run. Settings missing from the file are kept
+// @Tags        Setting
+// @Accept      mpfd
+// @Produce     json
+// @Param       file     formData   file     true    "Yaml or json object of setting names to values"
+// @Param       dry_run  formData   bool     false   "Only list the changes"
+// @Success     200      {object}   response.SetResponse{data=[]dto.SettingChangeDto}
+// @Router      /setting/import  [post]
+// @Security    Auth
+func Import(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		payload, err := request.Bind[dto.ImportSettingDto](c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		if payload.File.Size > maxImportSize {
+			response.ResponseError(c, errors.New("settings file is too large"), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		file, err := payload.File.Open()
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+		defer file.Close()
+		content, err := io.ReadAll(file)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+		values, err := UnmarshalSettings(content)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		changes, err := service.Import(c, values, payload.DryRun)
+		if errors.Is(err, customErrors.ErrValidationError) {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseList(c, changes, int64(len(changes)), nil)
+	}
+}
@@ -0,0 +1,217 @@
+package setting
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"github.com/ericmarcelinotju/gram/plugins/job"
+	"github.com/ericmarcelinotju/gram/utils/logger"
+	"github.com/ericmarcelinotju/gram/utils/request"
+)
+
+// eventChannel is the redis channel setting changes are published on
+const eventChannel = "setting-changes"
+
+// Event tells that a setting changed, its value is read back from the service so secrets never travel in events.
+// RequestId is the request that changed the setting, handlers log with it on every instance.
+type Event struct {
+	Name      string `json:"name"`
+	Group     string `json:"group"`
+	Instance  string `json:"instance"`
+	RequestId string `json:"request_id"`
+}
+
+// Handler receives the changes of the settings it subscribed to
+type Handler func(ctx context.Context, event Event)
+
+// Subscription selects the settings a handler is interested in, by name or by group
+type Subscription struct {
+	Names  []string
+	Groups []string
+}
+
+func (s Subscription) matches(event Event) bool {
+	for _, name := range s.Names {
+		if name == event.Name {
+			return true
+		}
+	}
+	for _, group := range s.Groups {
+		if group == event.Group {
+			return true
+		}
+	}
+	return false
+}
+
+type subscriber struct {
+	subscription Subscription
+	handler      Handler
+}
+
+// Events dispatches setting changes to the subscribers of this instance and publishes them to the other
+// instances through redis. Handlers run one at a time so components rebuild without racing each other.
+type Events struct {
+	client   *redis.Client
+	instance string
+
+	mutex       sync.Mutex
+	subscribers map[int]subscriber
+	next        int
+
+	queue  chan Event
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewEvents starts dispatching events, they stay local to the instance when client is nil
+func NewEvents(client *redis.Client) *Events {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Events{
+		client:      client,
+		instance:    uuid.NewString(),
+		subscribers: make(map[int]subscriber),
+		queue:       make(chan Event, 64),
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+	go e.dispatch(ctx)
+	if client != nil {
+		go e.receive(ctx)
+	}
+	return e
+}
+
+// Subscribe registers a handler and returns the function unregistering it
+func (e *Events) Subscribe(subscription Subscription, handler Handler) func() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	id := e.next
+	e.next++
+	e.subscribers[id] = subscriber{subscription: subscription, handler: handler}
+	return func() {
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		delete(e.subscribers, id)
+	}
+}
+
+// FollowSchedule moves the daily runs of scheduler to the time of the setting name every time it changes,
+// logging with tag, and returns the function to stop following it
+func FollowSchedule(svc Service, scheduler *job.Scheduler, name, tag string) func() {
+	return svc.Subscribe(Subscription{Names: []string{name}}, func(ctx context.Context, _ Event) {
+		hour, minute, err := svc.GetSchedulerTime(ctx, name)
+		if err != nil {
+			logger.Printf(ctx, "[%s] failed to read schedule time : %s", tag, err)
+			return
+		}
+		if err := scheduler.SetDaily(hour, minute); err != nil {
+			logger.Printf(ctx, "[%s] failed to reschedule : %s", tag, err)
+			return
+		}
+		logger.Printf(ctx, "[%s] rescheduled at %02d:%02d", tag, hour, minute)
+	})
+}
+
+// Publish dispatches the change of a setting to the subscribers of every instance
+func (e *Events) Publish(ctx context.Context, name string) error {
+	event := Event{Name: name, Instance: e.instance, RequestId: request.GetRequestId(ctx)}
+	if definition, ok := Lookup(name); ok {
+		event.Group = definition.Group
+	}
+	// a full queue drops the change rather than blocking the request saving it
+	select {
+	case e.queue <- event:
+	case <-e.done:
+	default:
+		logger.Printf(ctx, "[SETTING] dropped the change of %s, the event queue is full", name)
+	}
+
+	if e.client == nil {
+		return nil
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return e.client.Publish(ctx, eventChannel, payload).Err()
+}
+
+// Close stops dispatching events
+func (e *Events) Close() {
+	e.cancel()
+	<-e.done
+}
+
+func (e *Events) dispatch(ctx context.Context) {
+	defer close(e.done)
+	for {
+		select {
+		case event := <-e.queue:
+			e.handle(ctx, event)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Events) handle(ctx context.Context, event Event) {
+	ctx = request.WithRequestId(ctx, event.RequestId)
+	e.mutex.Lock()
+	var handlers []Handler
+	for _, subscriber := range e.subscribers {
+		if subscriber.subscription.matches(event) {
+			handlers = append(handlers, subscriber.handler)
+		}
+	}
+	e.mutex.Unlock()
+
+	for _, handler := range handlers {
+		func() {
+			// a failing component must not stop the others from reconfiguring
+			defer func() {
+				if r := recover(); r != nil {
+					logger.Printf(ctx, "[SETTING] handler of %s panicked : %v", event.Name, r)
+				}
+			}()
+			handler(ctx, event)
+		}()
+	}
+}
+
+// receive queues the changes published by the other instances
+func (e *Events) receive(ctx context.Context) {
+	pubsub := e.client.Subscribe(ctx, eventChannel)
+	defer pubsub.Close()
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+				log.Printf("[SETTING] invalid change event : %s", err)
+				continue
+			}
+			if event.Instance == e.instance {
+				continue
+			}
+			select {
+			case e.queue <- event:
+			case <-ctx.Done():
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package setting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/constant"
+)
+
+// Types of setting values, values are stored as strings in their canonical form
+const (
+	TypeString    = "string"
+	TypeInt       = "int"
+	TypeBool      = "bool"
+	TypeDuration  = "duration"
+	TypeTimeOfDay = "time_of_day"
+	TypeEnum      = "enum"
+	TypeJSON      = "json"
+	TypeSecret    = "secret"
+)
+
+// Definition declares a setting, its type and how its values are validated
+type Definition struct {
+	Name        string
+	Type        string
+	Default     string
+	Description string
+	Group       string
+	// Required rejects empty values
+	Required bool
+	// Min and Max bound int values
+	Min *int
+	Max *int
+	// Enum lists the values of an enum
+	Enum []string
+	// Schema describes the shape of a json value, merged into the JSON Schema of the setting
+	Schema map[string]interface{}
+	// Validate checks what the type alone cannot, like the shape of a json value
+	Validate func(value string) error
+}
+
+func bound(value int) *int {
+	return &value
+}
+
+var timeOfDay = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// Definitions lists every setting of the application, settings outside of it are rejected
+var Definitions = []Definition{
+	{Name: constant.SMTPHost, Type: TypeString, Default: "localhost", Group: "smtp", Required: true,
+		Description: "Host of the SMTP server sending emails"},
+	{Name: constant.SMTPPort, Type: TypeInt, Default: "25", Group: "smtp", Required: true, Min: bound(1), Max: bound(65535),
+		Description: "Port of the SMTP server"},
+	{Name: constant.SMTPEmail, Type: TypeString, Default: "noreply@localhost", Group: "smtp", Required: true,
+		Description: "Sender address of the emails",
+		Validate: func(value string) error {
+			if !strings.Contains(value, "@") {
+				return errors.New("must be an email address")
+			}
+			return nil
+		}},
+	{Name: constant.SMTPPassword, Type: TypeSecret, Group: "smtp",
+		Description: "Password of the SMTP sender"},
+
+	{Name: constant.SFTPHost, Type: TypeString, Group: "sftp",
+		Description: "Host of the SFTP server storing recordings and backups"},
+	{Name: constant.SFTPPort, Type: TypeInt, Default: "22", Group: "sftp", Required: true, Min: bound(1), Max: bound(65535),
+		Description: "Port of the SFTP server"},
+	{Name: constant.SFTPUsername, Type: TypeString, Group: "sftp",
+		Description: "Username on the SFTP server"},
+	{Name: constant.SFTPPassword, Type: TypeSecret, Group: "sftp",
+		Description: "Password on the SFTP server"},
+	{Name: constant.SFTPStorageFolder, Type: TypeString, Default: "recording", Group: "sftp",
+		Description: "Folder of the SFTP server files are stored in"},
+
+	{Name: constant.AuditRetention, Type: TypeJSON, Default: "{}", Group: "audit", Required: true,
+		Description: "Days audits are kept before being archived, keyed by entity name",
+		Schema: map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": "integer", "minimum": 1},
+		},
+		Validate: func(value string) error {
+			var retention map[string]int
+			if err := json.Unmarshal([]byte(value), &retention); err != nil {
+				return errors.New("must map entity names to days")
+			}
+			for entity, days := range retention {
+				if days < 1 {
+					return fmt.Errorf("retention of %s must be at least 1 day", entity)
+				}
+			}
+			return nil
+		}},
+	{Name: constant.AuditRetentionTime, Type: TypeTimeOfDay, Default: "01:00", Group: "audit", Required: true,
+		Description: "Time of day audits are archived"},
+
+	{Name: constant.SoftDeleteRetention, Type: TypeInt, Default: "30", Group: "soft_delete", Required: true, Min: bound(0),
+		Description: "Days deleted records are kept before being purged"},
+	{Name: constant.SoftDeletePurgeTime, Type: TypeTimeOfDay, Default: "02:00", Group: "soft_delete", Required: true,
+		Description: "Time of day deleted records are purged"},
+
+	{Name: constant.BackupTime, Type: TypeTimeOfDay, Default: "03:00", Group: "backup", Required: true,
+		Description: "Time of day the database is backed up"},
+	{Name: constant.BackupRetention, Type: TypeInt, Default: "30", Group: "backup", Required: true, Min: bound(0),
+		Description: "Days backups are kept, zero keeps them all"},
+	{Name: constant.BackupStorage, Type: TypeEnum, Default: "file", Group: "backup", Required: true, Enum: []string{"file", "sftp"},
+		Description: "Storage of the backups, the media storage or the SFTP server"},
+}
+
+// Lookup returns the definition of a setting
+func Lookup(name string) (*Definition, bool) {
+	for i := range Definitions {
+		if Definitions[i].Name == name {
+			return &Definitions[i], true
+		}
+	}
+	return nil, false
+}
+
+// ValidateSetting checks that a setting is defined and value is valid for it
+func ValidateSetting(name, value string) error {
+	definition, ok := Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown setting %s", name)
+	}
+	if err := definition.Check(value); err != nil {
+		return fmt.Errorf("invalid %s, %w", name, err)
+	}
+	return nil
+}
+
+// Check validates a value against the type and rules of the definition
+func (d *Definition) Check(value string) error {
+	if value == "" {
+		if d.Required {
+			return errors.New("must not be empty")
+		}
+		return nil
+	}
+
+	switch d.Type {
+	case TypeString, TypeSecret:
+	case TypeInt:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		if d.Min != nil && number < *d.Min {
+			return fmt.Errorf("must be at least %d", *d.Min)
+		}
+		if d.Max != nil && number > *d.Max {
+			return fmt.Errorf("must be at most %d", *d.Max)
+		}
+	case TypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return errors.New("must be true or false")
+		}
+	case TypeDuration:
+		if _, err := time.ParseDuration(value); err != nil {
+			return errors.New("must be a duration like 90s or 1h30m")
+		}
+	case TypeTimeOfDay:
+		if !timeOfDay.MatchString(value) {
+			return errors.New("must be a time of day like 03:00")
+		}
+	case TypeEnum:
+		found := false
+		for _, option := range d.Enum {
+			found = found || option == value
+		}
+		if !found {
+			return fmt.Errorf("must be one of %s", strings.Join(d.Enum, ", "))
+		}
+	case TypeJSON:
+		if !json.Valid([]byte(value)) {
+			return errors.New("must be json")
+		}
+	default:
+		return fmt.Errorf("has unknown type %s", d.Type)
+	}
+
+	if d.Validate != nil {
+		return d.Validate(value)
+	}
+	return nil
+}
+
+// JSONSchema describes the definition, values keep their natural json type so forms render the right inputs
+func (d *Definition) JSONSchema() map[string]interface{} {
+	schema := map[string]interface{}{
+		"description": d.Description,
+		"x-group":     d.Group,
+		"x-type":      d.Type,
+	}
+	switch d.Type {
+	case TypeInt:
+		schema["type"] = "integer"
+		if d.Min != nil {
+			schema["minimum"] = *d.Min
+		}
+		if d.Max != nil {
+			schema["maximum"] = *d.Max
+		}
+	case TypeBool:
+		schema["type"] = "boolean"
+	case TypeDuration:
+		schema["type"] = "string"
+		schema["pattern"] = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	case TypeTimeOfDay:
+		schema["type"] = "string"
+		schema["pattern"] = timeOfDay.String()
+	case TypeEnum:
+		schema["type"] = "string"
+		schema["enum"] = d.Enum
+	case TypeSecret:
+		schema["type"] = "string"
+		schema["format"] = "password"
+		schema["writeOnly"] = true
+	case TypeString:
+		schema["type"] = "string"
+	}
+	if d.Required && (d.Type == TypeString || d.Type == TypeSecret) {
+		schema["minLength"] = 1
+	}
+	for keyword, value := range d.Schema {
+		schema[keyword] = value
+	}
+	if d.Default != "" {
+		schema["default"] = d.natural(d.Default)
+	}
+	return schema
+}
+
+// natural converts a stored value to its json type
+func (d *Definition) natural(value string) interface{} {
+	switch d.Type {
+	case TypeInt:
+		if number, err := strconv.Atoi(value); err == nil {
+			return number
+		}
+	case TypeBool:
+		if boolean, err := strconv.ParseBool(value); err == nil {
+			return boolean
+		}
+	case TypeJSON:
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+			return decoded
+		}
+	}
+	return value
+}
+
+// Schema returns the JSON Schema of every setting as the properties of an object
+func Schema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(Definitions))
+	for i := range Definitions {
+		properties[Definitions[i].Name] = Definitions[i].JSONSchema()
+	}
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Settings",
+		"description":          "Settings are posted one at a time as strings, in their canonical form",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
@@ -2,8 +2,11 @@ package setting
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"sort"
 
+	"github.com/google/uuid"
 	pkgErr "github.com/pkg/errors"
 
 	"github.com/ericmarcelinotju/gram/config"
@@ -11,6 +14,8 @@ import (
 	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/ericmarcelinotju/gram/model"
 	"github.com/ericmarcelinotju/gram/plugins/cache"
+	"github.com/ericmarcelinotju/gram/plugins/database"
+	"github.com/ericmarcelinotju/gram/utils/request"
 
 	"gorm.io/gorm"
 )
@@ -22,12 +27,30 @@ const (
 	selectError = "Error in selecting settings in the database"
 )
 
+// Origins of the changes recorded in the history of settings
+const (
+	OriginSave      = "save"
+	OriginReencrypt = "reencrypt"
+	OriginRollback  = "rollback"
+	OriginRestore   = "restore"
+	OriginImport    = "import"
+	OriginSeed      = "seed"
+)
+
 // Repository provides an abstraction on top of the log data source
 type Repository interface {
-	Save(ctx context.Context, name, value string) error
+	Save(ctx context.Context, name, value, origin string) error
+	SaveAll(ctx context.Context, values map[string]string, origin string) ([]string, error)
 	Select(ctx context.Context) ([]dto.SettingDto, error)
 	SelectByName(ctx context.Context, name string) (string, error)
 	Delete(ctx context.Context, name string) error
+	Forget(ctx context.Context, names []string) error
+
+	SelectHistory(ctx context.Context, name string) ([]dto.SettingHistoryDto, error)
+	SelectHistoryVersion(ctx context.Context, name string, version int64) (*dto.SettingHistoryDto, error)
+	InsertSnapshot(ctx context.Context, name string) (*dto.SettingSnapshotDto, error)
+	SelectSnapshots(ctx context.Context) ([]dto.SettingSnapshotDto, error)
+	SelectSnapshotById(ctx context.Context, id string) (*dto.SettingSnapshotDto, error)
 }
 
 type repository struct {
@@ -40,33 +63,119 @@ func NewRepository(db *gorm.DB, cache cache.Cache) *repository {
 	return &repository{db: db, cache: cache}
 }
 
-func (s *repository) Save(ctx context.Context, name, value string) error {
-	setting := model.SettingEntity{
-		Name:  name,
-		Value: value,
-	}
-	query := s.db.WithContext(ctx).Model(&setting).Where("name = ?", name).Updates(&setting)
-	if err := query.Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.CacheError)
+// Save stores a setting and records the change in its history, origin tells what made the change
+func (s *repository) Save(ctx context.Context, name, value, origin string) error {
+	err := database.WithContext(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		_, err := save(ctx, tx, name, value, origin)
+		return err
+	})
+	if err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.DatabaseError)
 		return appErr
 	}
-	if query.RowsAffected == 0 {
-		if err := s.db.WithContext(ctx).Create(&setting).Error; err != nil {
-			appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.DatabaseError)
-			return appErr
+	return s.refresh(ctx, map[string]string{name: value})
+}
+
+// SaveAll stores every setting of values in one transaction and returns the names of the changed ones
+func (s *repository) SaveAll(ctx context.Context, values map[string]string, origin string) ([]string, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changed []string
+	saved := make(map[string]string)
+	err := database.WithContext(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		for _, name := range names {
+			ok, err := save(ctx, tx, name, values[name], origin)
+			if err != nil {
+				return err
+			}
+			if ok {
+				changed = append(changed, name)
+				saved[name] = values[name]
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.DatabaseError)
+		return nil, appErr
+	}
+	return changed, s.refresh(ctx, saved)
+}
+
+// save upserts a setting in tx and appends the change to its history, unchanged values are not recorded
+func save(ctx context.Context, tx *gorm.DB, name, value, origin string) (bool, error) {
+	var current []model.SettingEntity
+	if err := tx.Where("name = ?", name).Limit(1).Find(&current).Error; err != nil {
+		return false, err
 	}
 
+	history := model.SettingHistoryEntity{
+		Id:        uuid.New(),
+		Name:      name,
+		NewValue:  value,
+		Origin:    origin,
+		RequestId: request.GetRequestId(ctx),
+	}
+	if user, ok := ctx.Value("auth-user").(*dto.UserDto); ok && user != nil {
+		history.ActorId = user.Id
+		history.ActorName = user.Name
+	}
+
+	setting := model.SettingEntity{Name: name, Value: value}
+	if len(current) == 0 {
+		if err := tx.Create(&setting).Error; err != nil {
+			return false, err
+		}
+	} else {
+		if current[0].Value == value {
+			return false, nil
+		}
+		history.OldValue = &current[0].Value
+		if err := tx.Model(&setting).Where("name = ?", name).Update("value", value).Error; err != nil {
+			return false, err
+		}
+	}
+
+	var last []int64
+	if err := tx.Model(&model.SettingHistoryEntity{}).Where("name = ?", name).Order("version DESC").Limit(1).Pluck("version", &last).Error; err != nil {
+		return false, err
+	}
+	history.Version = 1
+	if len(last) > 0 {
+		history.Version = last[0] + 1
+	}
+	return true, tx.Create(&history).Error
+}
+
+// refresh drops the cached list of settings and caches the saved values
+func (s *repository) refresh(ctx context.Context, values map[string]string) error {
 	if err := s.cache.Del(ctx, "setting"); err != nil {
 		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.CacheError)
 		return appErr
 	}
+	for name, value := range values {
+		if err := s.cache.Set(ctx, "setting-"+name, value, config.Get().Cache.DefaultExpiry); err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.CacheError)
+			return appErr
+		}
+	}
+	return nil
+}
 
-	if err := s.cache.Set(ctx, "setting-"+name, value, config.Get().Cache.DefaultExpiry); err != nil {
+// Forget drops the cached list of settings and the cached values of names, saved by a transaction of another repository
+func (s *repository) Forget(ctx context.Context, names []string) error {
+	keys := []string{"setting"}
+	for _, name := range names {
+		keys = append(keys, "setting-"+name)
+	}
+	if err := s.cache.Del(ctx, keys...); err != nil {
 		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.CacheError)
 		return appErr
 	}
-
 	return nil
 }
 
@@ -84,7 +193,7 @@ func (s *repository) Select(ctx context.Context) ([]dto.SettingDto, error) {
 		return results, nil
 	}
 
-	query := s.db.WithContext(ctx).
+	query := database.WithContext(ctx, s.db).
 		Model(&model.SettingEntity{}).
 		Find(&entities)
 
@@ -121,7 +230,7 @@ func (s *repository) SelectByName(ctx context.Context, name string) (string, err
 	}
 
 	var entity model.SettingEntity
-	query := s.db.WithContext(ctx).
+	query := database.WithContext(ctx, s.db).
 		Model(&model.SettingEntity{}).
 		Where("name = ?", name).
 		First(&entity)
@@ -145,7 +254,7 @@ func (s *repository) SelectByName(ctx context.Context, name string) (string, err
 }
 
 func (s *repository) Delete(ctx context.Context, name string) error {
-	if err := s.db.WithContext(ctx).Where("name = ?", name).Delete(&model.SettingEntity{}).Error; err != nil {
+	if err := database.WithContext(ctx, s.db).Where("name = ?", name).Delete(&model.SettingEntity{}).Error; err != nil {
 		appErr := customErrors.NewAppError(pkgErr.Wrap(err, deleteError), customErrors.DatabaseError)
 		return appErr
 	}
@@ -162,3 +271,93 @@ func (s *repository) Delete(ctx context.Context, name string) error {
 
 	return nil
 }
+
+// SelectHistory returns the changes of a setting, latest first
+func (s *repository) SelectHistory(ctx context.Context, name string) ([]dto.SettingHistoryDto, error) {
+	var entities []model.SettingHistoryEntity
+	if err := database.WithContext(ctx, s.db).Where("name = ?", name).Order("version DESC").Find(&entities).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, appErr
+	}
+
+	results := make([]dto.SettingHistoryDto, len(entities))
+	for i, entity := range entities {
+		results[i] = *entity.ToDto()
+	}
+	return results, nil
+}
+
+func (s *repository) SelectHistoryVersion(ctx context.Context, name string, version int64) (*dto.SettingHistoryDto, error) {
+	var entity model.SettingHistoryEntity
+	err := database.WithContext(ctx, s.db).Where("name = ? AND version = ?", name, version).First(&entity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.NotFoundError)
+		return nil, appErr
+	}
+	if err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, appErr
+	}
+	return entity.ToDto(), nil
+}
+
+// InsertSnapshot copies the stored value of every setting, secrets stay sealed
+func (s *repository) InsertSnapshot(ctx context.Context, name string) (*dto.SettingSnapshotDto, error) {
+	var settings []model.SettingEntity
+	if err := database.WithContext(ctx, s.db).Find(&settings).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, appErr
+	}
+	values := make(map[string]string, len(settings))
+	for _, setting := range settings {
+		values[setting.Name] = setting.Value
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	entity := model.SettingSnapshotEntity{
+		Id:     uuid.New(),
+		Name:   name,
+		Values: string(encoded),
+	}
+	if user, ok := ctx.Value("auth-user").(*dto.UserDto); ok && user != nil {
+		entity.ActorId = user.Id
+		entity.ActorName = user.Name
+	}
+	if err := database.WithContext(ctx, s.db).Create(&entity).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.DatabaseError)
+		return nil, appErr
+	}
+	return entity.ToDto(), nil
+}
+
+// SelectSnapshots returns the snapshots, latest first
+func (s *repository) SelectSnapshots(ctx context.Context) ([]dto.SettingSnapshotDto, error) {
+	var entities []model.SettingSnapshotEntity
+	if err := database.WithContext(ctx, s.db).Order("created_at DESC").Find(&entities).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, appErr
+	}
+
+	results := make([]dto.SettingSnapshotDto, len(entities))
+	for i, entity := range entities {
+		results[i] = *entity.ToDto()
+	}
+	return results, nil
+}
+
+func (s *repository) SelectSnapshotById(ctx context.Context, id string) (*dto.SettingSnapshotDto, error) {
+	var entity model.SettingSnapshotEntity
+	err := database.WithContext(ctx, s.db).Where("id = ?", id).First(&entity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.NotFoundError)
+		return nil, appErr
+	}
+	if err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, appErr
+	}
+	return entity.ToDto(), nil
+}
@@ -9,7 +9,15 @@ func NewRoutesFactory(router *gin.RouterGroup) func(service Service) {
 	group := router.Group("/api/setting")
 	settingRoutesFactory := func(service Service) {
 		group.GET("", Get(service))
+		group.GET("/schema", GetSchema())
 		group.POST("", Save(service))
+		group.GET("/export", Export(service))
+		group.POST("/import", Import(service))
+		group.GET("/snapshot", GetSnapshots(service))
+		group.POST("/snapshot", PostSnapshot(service))
+		group.POST("/snapshot/:id/restore", RestoreSnapshot(service))
+		group.GET("/:name/history", GetHistory(service))
+		group.POST("/:name/rollback/:version", Rollback(service))
 	}
 	return settingRoutesFactory
 }
@@ -0,0 +1,62 @@
+package setting
+
+import (
+	"errors"
+
+	"github.com/ericmarcelinotju/gram/utils/crypt"
+)
+
+// Mask replaces the value of a secret setting that is set
+const Mask = "********"
+
+// ErrNoKeyring reports a secret that cannot be sealed or opened without setting keys
+var ErrNoKeyring = errors.New("secret settings need SETTING_KEYS")
+
+// Codec seals the values of secret settings before they are stored and opens them once read,
+// so the database, the cache and the backups only hold sealed secrets
+type Codec struct {
+	keyring *crypt.Keyring
+}
+
+// NewCodec creates a codec sealing with keyring, secrets cannot be saved without one
+func NewCodec(keyring *crypt.Keyring) *Codec {
+	return &Codec{keyring: keyring}
+}
+
+// IsSecret reports whether a setting is secret
+func IsSecret(name string) bool {
+	definition, ok := Lookup(name)
+	return ok && definition.Type == TypeSecret
+}
+
+// Encode returns the stored form of a value, empty secrets are stored empty
+func (c *Codec) Encode(name, value string) (string, error) {
+	if !IsSecret(name) || value == "" {
+		return value, nil
+	}
+	if c.keyring == nil {
+		return "", ErrNoKeyring
+	}
+	// the name binds the sealed value to its setting, copied to another one it does not open
+	return c.keyring.Seal(value, name)
+}
+
+// Decode returns the value of a stored form, secrets stored before they were sealed are returned as they are
+func (c *Codec) Decode(name, stored string) (string, error) {
+	if !crypt.IsSealed(stored) {
+		return stored, nil
+	}
+	if c.keyring == nil {
+		return "", ErrNoKeyring
+	}
+	return c.keyring.Open(stored, name)
+}
+
+// Stale reports whether a stored secret has to be sealed again, being plaintext, sealed with a rotated key
+// or not bound to its name
+func (c *Codec) Stale(name, stored string) bool {
+	if !IsSecret(name) || stored == "" || c.keyring == nil {
+		return false
+	}
+	return !crypt.IsSealed(stored) || crypt.IsUnbound(stored) || crypt.SealedWith(stored) != c.keyring.Primary()
+}
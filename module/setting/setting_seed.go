@@ -0,0 +1,55 @@
+package setting
+
+import (
+	"context"
+
+	pkgErr "github.com/pkg/errors"
+	"gorm.io/gorm"
+
+	customErrors "github.com/ericmarcelinotju/gram/errors"
+	"github.com/ericmarcelinotju/gram/model"
+)
+
+// Seeder saves the settings of the seed fixtures in the transaction of the seeding the way the service
+// saves them: validated, sealed and recorded in their history
+type Seeder struct {
+	codec *Codec
+}
+
+// NewSeeder creates a seeder sealing the secrets with codec
+func NewSeeder(codec *Codec) *Seeder {
+	return &Seeder{codec: codec}
+}
+
+// Seed saves value in tx and returns "create" or "update", or nothing when the stored value is the same once opened
+func (s *Seeder) Seed(ctx context.Context, tx *gorm.DB, name, value string) (string, error) {
+	if err := ValidateSetting(name, value); err != nil {
+		return "", customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+
+	var current []model.SettingEntity
+	if err := tx.Where("name = ?", name).Limit(1).Find(&current).Error; err != nil {
+		return "", customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+	}
+	action := "create"
+	if len(current) > 0 {
+		// sealed values differ on every seal so the stored value is compared once opened
+		old, err := s.codec.Decode(name, current[0].Value)
+		if err != nil {
+			return "", customErrors.NewAppError(pkgErr.Wrap(err, "Error in opening secret setting "+name), customErrors.UnknownError)
+		}
+		if old == value {
+			return "", nil
+		}
+		action = "update"
+	}
+
+	stored, err := s.codec.Encode(name, value)
+	if err != nil {
+		return "", customErrors.NewAppError(pkgErr.Wrap(err, "Error in sealing secret setting "+name), customErrors.UnknownError)
+	}
+	if _, err := save(ctx, tx, name, stored, OriginSeed); err != nil {
+		return "", customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.DatabaseError)
+	}
+	return action, nil
+}
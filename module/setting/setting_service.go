@@ -2,16 +2,21 @@ package setting
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
+	pkgErr "github.com/pkg/errors"
+
 	"github.com/ericmarcelinotju/gram/config"
 	"github.com/ericmarcelinotju/gram/constant"
 	"github.com/ericmarcelinotju/gram/dto"
-	"github.com/ericmarcelinotju/gram/plugins/job"
-	"github.com/ericmarcelinotju/gram/plugins/notifier"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
+	"github.com/ericmarcelinotju/gram/plugins/storage"
+	"github.com/ericmarcelinotju/gram/utils/logger"
 )
 
 // Service defines Setting service behavior.
@@ -19,71 +24,345 @@ type Service interface {
 	Read(context.Context) ([]dto.SettingDto, error)
 	ReadByName(context.Context, string) (string, error)
 	Save(context.Context, *dto.PostSettingDto) error
+	Reencrypt(context.Context) (int, error)
+	History(context.Context, string) ([]dto.SettingHistoryDto, error)
+	Rollback(context.Context, *dto.RollbackSettingDto) error
+	Snapshot(context.Context, *dto.PostSettingSnapshotDto) (*dto.SettingSnapshotDto, error)
+	ReadSnapshots(context.Context) ([]dto.SettingSnapshotDto, error)
+	RestoreSnapshot(context.Context, string) error
+	Export(ctx context.Context, secrets bool) (map[string]string, error)
+	Import(ctx context.Context, values map[string]string, dryRun bool) ([]dto.SettingChangeDto, error)
+	Refresh(ctx context.Context, names []string) error
+	Subscribe(Subscription, Handler) func()
 
 	GetSchedulerTime(context.Context, string) (int, int, error)
 
 	GetSFTPConfig(ctx context.Context) (*config.Storage, error)
 	GetSMTPConfig(ctx context.Context) (*config.Email, error)
+	GetAuditRetention(ctx context.Context) (map[string]int, error)
+	GetSoftDeleteRetention(ctx context.Context) (int, error)
+	GetBackupRetention(ctx context.Context) (int, error)
+	GetBackupStorage(ctx context.Context, media storage.Storage) (storage.Storage, error)
 }
 
 type service struct {
-	repo        Repository
-	scheduler   *job.Scheduler
-	forgotEmail *notifier.EmailNotifier
+	repo   Repository
+	codec  *Codec
+	events *Events
 }
 
 // NewService creates a new service struct
 func NewService(
 	repo Repository,
-	scheduler *job.Scheduler,
-	forgotEmail *notifier.EmailNotifier,
+	codec *Codec,
+	events *Events,
 ) *service {
 	return &service{
-		repo:        repo,
-		scheduler:   scheduler,
-		forgotEmail: forgotEmail,
+		repo:   repo,
+		codec:  codec,
+		events: events,
 	}
 }
 
+// Read returns every setting with the values of secrets masked
 func (svc *service) Read(ctx context.Context) ([]dto.SettingDto, error) {
-	return svc.repo.Select(ctx)
+	settings, err := svc.repo.Select(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range settings {
+		settings[i].Set = settings[i].Value != ""
+		if IsSecret(settings[i].Name) {
+			settings[i].Secret = true
+			if settings[i].Set {
+				settings[i].Value = Mask
+			}
+		}
+	}
+	return settings, nil
 }
 
+// ReadByName returns the value of a setting with secrets opened, the default of its definition when it was never saved,
+// only settings without a definition are not found
 func (svc *service) ReadByName(ctx context.Context, setting string) (string, error) {
-	return svc.repo.SelectByName(ctx, setting)
+	stored, err := svc.repo.SelectByName(ctx, setting)
+	if errors.Is(err, customErrors.ErrNotFound) {
+		if definition, ok := Lookup(setting); ok {
+			return definition.Default, nil
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	value, err := svc.codec.Decode(setting, stored)
+	if err != nil {
+		return "", customErrors.NewAppError(pkgErr.Wrap(err, "Error in opening secret setting "+setting), customErrors.UnknownError)
+	}
+	return value, nil
 }
 
+// Save stores a setting after validating it against its definition, unknown settings are rejected
 func (svc *service) Save(ctx context.Context, payload *dto.PostSettingDto) error {
-	err := svc.repo.Save(ctx, payload.Name, payload.Value)
+	if err := ValidateSetting(payload.Name, payload.Value); err != nil {
+		return customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+
+	stored, err := svc.codec.Encode(payload.Name, payload.Value)
+	if err != nil {
+		return customErrors.NewAppError(pkgErr.Wrap(err, "Error in sealing secret setting "+payload.Name), customErrors.UnknownError)
+	}
+	err = svc.repo.Save(ctx, payload.Name, stored, OriginSave)
 	if err != nil {
 		return err
 	}
 
-	if payload.Name == constant.SMTPHost {
-		svc.forgotEmail.Host = payload.Value
-		return nil
+	svc.publish(ctx, payload.Name)
+	return nil
+}
+
+// publish dispatches the change of a saved setting
+func (svc *service) publish(ctx context.Context, name string) {
+	// the change is saved already, subscribers of the other instances pick it up at their next restart at worst
+	if err := svc.events.Publish(ctx, name); err != nil {
+		logger.Printf(ctx, "[SETTING] failed to publish change of %s : %s", name, err)
+	}
+}
+
+// Refresh drops the cached values of settings saved outside the service, by the seeding, and publishes their changes
+func (svc *service) Refresh(ctx context.Context, names []string) error {
+	if err := svc.repo.Forget(ctx, names); err != nil {
+		return err
+	}
+	for _, name := range names {
+		svc.publish(ctx, name)
+	}
+	return nil
+}
+
+// Subscribe calls handler on every change of the settings of subscription, on any instance
+func (svc *service) Subscribe(subscription Subscription, handler Handler) func() {
+	return svc.events.Subscribe(subscription, handler)
+}
+
+// Reencrypt seals again the secrets stored in plaintext or sealed with a rotated key and returns how many were sealed
+func (svc *service) Reencrypt(ctx context.Context) (int, error) {
+	settings, err := svc.repo.Select(ctx)
+	if err != nil {
+		return 0, err
 	}
-	if payload.Name == constant.SMTPPort {
-		port, err := strconv.Atoi(payload.Value)
+	var total int
+	for _, setting := range settings {
+		if !svc.codec.Stale(setting.Name, setting.Value) {
+			continue
+		}
+		value, err := svc.codec.Decode(setting.Name, setting.Value)
+		if err != nil {
+			return total, fmt.Errorf("error when opening %s %w", setting.Name, err)
+		}
+		stored, err := svc.codec.Encode(setting.Name, value)
 		if err != nil {
+			return total, fmt.Errorf("error when sealing %s %w", setting.Name, err)
+		}
+		if err := svc.repo.Save(ctx, setting.Name, stored, OriginReencrypt); err != nil {
+			return total, err
+		}
+		total++
+	}
+	return total, nil
+}
+
+// History returns the changes of a setting, latest first, with the values of secrets masked
+func (svc *service) History(ctx context.Context, name string) ([]dto.SettingHistoryDto, error) {
+	history, err := svc.repo.SelectHistory(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if IsSecret(name) {
+		for i := range history {
+			if history[i].OldValue != nil && *history[i].OldValue != "" {
+				mask := Mask
+				history[i].OldValue = &mask
+			}
+			if history[i].NewValue != "" {
+				history[i].NewValue = Mask
+			}
+		}
+	}
+	return history, nil
+}
+
+// Rollback saves again the value a setting had after the change of the given version
+func (svc *service) Rollback(ctx context.Context, payload *dto.RollbackSettingDto) error {
+	change, err := svc.repo.SelectHistoryVersion(ctx, payload.Name, payload.Version)
+	if err != nil {
+		return err
+	}
+	// the definition may have changed since, old values must still be valid
+	if err := svc.check(change.Name, change.NewValue); err != nil {
+		return err
+	}
+
+	if err := svc.repo.Save(ctx, change.Name, change.NewValue, OriginRollback); err != nil {
+		return err
+	}
+	svc.publish(ctx, change.Name)
+	return nil
+}
+
+// Snapshot keeps the values of every setting so they can be restored together
+func (svc *service) Snapshot(ctx context.Context, payload *dto.PostSettingSnapshotDto) (*dto.SettingSnapshotDto, error) {
+	snapshot, err := svc.repo.InsertSnapshot(ctx, payload.Name)
+	if err != nil {
+		return nil, err
+	}
+	maskSnapshot(snapshot)
+	return snapshot, nil
+}
+
+// ReadSnapshots returns the snapshots, latest first, with the values of secrets masked
+func (svc *service) ReadSnapshots(ctx context.Context) ([]dto.SettingSnapshotDto, error) {
+	snapshots, err := svc.repo.SelectSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshots {
+		maskSnapshot(&snapshots[i])
+	}
+	return snapshots, nil
+}
+
+// RestoreSnapshot saves every value of a snapshot in one transaction, nothing is saved when one of them is invalid.
+// Settings saved after the snapshot are kept, settings no longer defined are skipped.
+func (svc *service) RestoreSnapshot(ctx context.Context, id string) error {
+	snapshot, err := svc.repo.SelectSnapshotById(ctx, id)
+	if err != nil {
+		return err
+	}
+	values := make(map[string]string, len(snapshot.Values))
+	for name, stored := range snapshot.Values {
+		if _, ok := Lookup(name); !ok {
+			continue
+		}
+		if err := svc.check(name, stored); err != nil {
 			return err
 		}
-		svc.forgotEmail.Port = port
-		return nil
+		values[name] = stored
+	}
+
+	changed, err := svc.repo.SaveAll(ctx, values, OriginRestore)
+	if err != nil {
+		return err
+	}
+	for _, name := range changed {
+		svc.publish(ctx, name)
+	}
+	return nil
+}
+
+// Export returns the value of every defined setting, defaults included, secrets are left out unless asked for
+func (svc *service) Export(ctx context.Context, secrets bool) (map[string]string, error) {
+	values := make(map[string]string, len(Definitions))
+	for _, definition := range Definitions {
+		if definition.Type == TypeSecret && !secrets {
+			continue
+		}
+		value, err := svc.ReadByName(ctx, definition.Name)
+		if err != nil {
+			return nil, err
+		}
+		values[definition.Name] = value
+	}
+	return values, nil
+}
+
+// Import saves every value in one transaction and returns the changes, secret values masked.
+// Every value is validated before anything is saved, settings missing from values are kept and
+// nothing is saved on a dry run.
+func (svc *service) Import(ctx context.Context, values map[string]string, dryRun bool) ([]dto.SettingChangeDto, error) {
+	names := make([]string, 0, len(values))
+	var invalid []error
+	for name, value := range values {
+		if err := ValidateSetting(name, value); err != nil {
+			invalid = append(invalid, err)
+		}
+		names = append(names, name)
+	}
+	if len(invalid) > 0 {
+		sort.Slice(invalid, func(i, j int) bool { return invalid[i].Error() < invalid[j].Error() })
+		return nil, customErrors.NewAppError(errors.Join(invalid...), customErrors.ValidationError)
+	}
+	sort.Strings(names)
+
+	var changes []dto.SettingChangeDto
+	stored := make(map[string]string)
+	for _, name := range names {
+		change := dto.SettingChangeDto{Name: name, Action: "create", NewValue: values[name]}
+		current, err := svc.repo.SelectByName(ctx, name)
+		if err != nil && !errors.Is(err, customErrors.ErrNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			old, err := svc.codec.Decode(name, current)
+			if err != nil {
+				return nil, customErrors.NewAppError(pkgErr.Wrap(err, "Error in opening secret setting "+name), customErrors.UnknownError)
+			}
+			if old == values[name] {
+				continue
+			}
+			change.Action = "update"
+			change.OldValue = &old
+		}
+
+		if stored[name], err = svc.codec.Encode(name, values[name]); err != nil {
+			return nil, customErrors.NewAppError(pkgErr.Wrap(err, "Error in sealing secret setting "+name), customErrors.UnknownError)
+		}
+		if IsSecret(name) {
+			if change.OldValue != nil && *change.OldValue != "" {
+				mask := Mask
+				change.OldValue = &mask
+			}
+			if change.NewValue != "" {
+				change.NewValue = Mask
+			}
+		}
+		changes = append(changes, change)
+	}
+	if dryRun || len(stored) == 0 {
+		return changes, nil
+	}
+
+	changed, err := svc.repo.SaveAll(ctx, stored, OriginImport)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range changed {
+		svc.publish(ctx, name)
 	}
-	if payload.Name == constant.SMTPEmail {
-		svc.forgotEmail.SenderEmail = payload.Value
-		return nil
+	return changes, nil
+}
+
+// check validates a stored value against the definition of its setting
+func (svc *service) check(name, stored string) error {
+	value, err := svc.codec.Decode(name, stored)
+	if err != nil {
+		return customErrors.NewAppError(pkgErr.Wrap(err, "Error in opening secret setting "+name), customErrors.UnknownError)
 	}
-	if payload.Name == constant.SMTPPassword {
-		svc.forgotEmail.SenderPassword = payload.Value
-		return nil
+	if err := ValidateSetting(name, value); err != nil {
+		return customErrors.NewAppError(err, customErrors.ValidationError)
 	}
 	return nil
 }
 
+func maskSnapshot(snapshot *dto.SettingSnapshotDto) {
+	for name, value := range snapshot.Values {
+		if IsSecret(name) && value != "" {
+			snapshot.Values[name] = Mask
+		}
+	}
+}
+
 func (svc *service) GetSchedulerTime(ctx context.Context, settingKey string) (int, int, error) {
-	backupTimeStr, err := svc.repo.SelectByName(ctx, settingKey)
+	backupTimeStr, err := svc.ReadByName(ctx, settingKey)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -103,23 +382,23 @@ func (svc *service) GetSchedulerTime(ctx context.Context, settingKey string) (in
 }
 
 func (svc *service) GetSFTPConfig(ctx context.Context) (*config.Storage, error) {
-	sftpHost, err := svc.repo.SelectByName(ctx, constant.SFTPHost)
+	sftpHost, err := svc.ReadByName(ctx, constant.SFTPHost)
 	if err != nil {
 		return nil, err
 	}
-	sftpPort, err := svc.repo.SelectByName(ctx, constant.SFTPPort)
+	sftpPort, err := svc.ReadByName(ctx, constant.SFTPPort)
 	if err != nil {
 		return nil, err
 	}
-	sftpUsername, err := svc.repo.SelectByName(ctx, constant.SFTPUsername)
+	sftpUsername, err := svc.ReadByName(ctx, constant.SFTPUsername)
 	if err != nil {
 		return nil, err
 	}
-	sftpPassword, err := svc.repo.SelectByName(ctx, constant.SFTPPassword)
+	sftpPassword, err := svc.ReadByName(ctx, constant.SFTPPassword)
 	if err != nil {
 		return nil, err
 	}
-	recordingFolder, err := svc.repo.SelectByName(ctx, constant.SFTPStorageFolder)
+	recordingFolder, err := svc.ReadByName(ctx, constant.SFTPStorageFolder)
 	if err != nil {
 		return nil, err
 	}
@@ -133,19 +412,19 @@ func (svc *service) GetSFTPConfig(ctx context.Context) (*config.Storage, error)
 }
 
 func (svc *service) GetSMTPConfig(ctx context.Context) (*config.Email, error) {
-	noreplyHost, err := svc.repo.SelectByName(ctx, constant.SMTPHost)
+	noreplyHost, err := svc.ReadByName(ctx, constant.SMTPHost)
 	if err != nil {
 		return nil, err
 	}
-	noreplyPort, err := svc.repo.SelectByName(ctx, constant.SMTPPort)
+	noreplyPort, err := svc.ReadByName(ctx, constant.SMTPPort)
 	if err != nil {
 		return nil, err
 	}
-	noreplyEmail, err := svc.repo.SelectByName(ctx, constant.SMTPEmail)
+	noreplyEmail, err := svc.ReadByName(ctx, constant.SMTPEmail)
 	if err != nil {
 		return nil, err
 	}
-	noreplyPassword, err := svc.repo.SelectByName(ctx, constant.SMTPPassword)
+	noreplyPassword, err := svc.ReadByName(ctx, constant.SMTPPassword)
 	if err != nil {
 		return nil, err
 	}
@@ -156,3 +435,52 @@ func (svc *service) GetSMTPConfig(ctx context.Context) (*config.Email, error) {
 		Password: noreplyPassword,
 	}, nil
 }
+
+// GetAuditRetention returns the audit retention in days keyed by entity name
+func (svc *service) GetAuditRetention(ctx context.Context) (map[string]int, error) {
+	retentionStr, err := svc.ReadByName(ctx, constant.AuditRetention)
+	if err != nil {
+		return nil, err
+	}
+	var retention map[string]int
+	if err := json.Unmarshal([]byte(retentionStr), &retention); err != nil {
+		return nil, err
+	}
+	return retention, nil
+}
+
+func (svc *service) GetSoftDeleteRetention(ctx context.Context) (int, error) {
+	retentionStr, err := svc.ReadByName(ctx, constant.SoftDeleteRetention)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(retentionStr)
+}
+
+// GetBackupRetention returns how many days backups are kept, zero keeps them all
+func (svc *service) GetBackupRetention(ctx context.Context) (int, error) {
+	retentionStr, err := svc.ReadByName(ctx, constant.BackupRetention)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(retentionStr)
+}
+
+// GetBackupStorage returns the SFTP storage when backups are stored on "sftp", the media storage otherwise
+func (svc *service) GetBackupStorage(ctx context.Context, media storage.Storage) (storage.Storage, error) {
+	kind, err := svc.ReadByName(ctx, constant.BackupStorage)
+	if err != nil {
+		return nil, err
+	}
+	if kind == "sftp" {
+		sftpConfig, err := svc.GetSFTPConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return storage.NewFtpStorage(sftpConfig)
+	}
+	if media == nil {
+		return nil, errors.New("media storage is not configured")
+	}
+	return media, nil
+}
@@ -0,0 +1,350 @@
+package setting
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	"github.com/ericmarcelinotju/gram/constant"
+	"github.com/ericmarcelinotju/gram/dto"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
+	"github.com/ericmarcelinotju/gram/model"
+	"github.com/ericmarcelinotju/gram/plugins/cache"
+	"github.com/ericmarcelinotju/gram/utils/crypt"
+)
+
+func TestValidateSetting(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{constant.SMTPPort, "587", true},
+		{constant.SMTPPort, "smtp", false},
+		{constant.SMTPPort, "70000", false},
+		{constant.SMTPEmail, "noreply", false},
+		{constant.SMTPPassword, "", true},
+		{constant.BackupTime, "03:30", true},
+		{constant.BackupTime, "24:00", false},
+		{constant.BackupStorage, "sftp", true},
+		{constant.BackupStorage, "s3", false},
+		{constant.AuditRetention, `{"users": 365}`, true},
+		{constant.AuditRetention, `{"users": 0}`, false},
+		{constant.AuditRetention, `[365]`, false},
+		{"smtp_prot", "587", false},
+	} {
+		err := ValidateSetting(test.name, test.value)
+		assert.Equal(t, err == nil, test.valid)
+	}
+}
+
+func TestFixturesMatchDefinitions(t *testing.T) {
+	content, err := os.ReadFile("../../fixtures/test/settings.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var settings map[string]string
+	assert.Equal(t, yaml.Unmarshal(content, &settings), nil)
+	for name, value := range settings {
+		assert.Equal(t, ValidateSetting(name, value), nil)
+	}
+	for _, definition := range Definitions {
+		if definition.Default != "" {
+			assert.Equal(t, definition.Check(definition.Default), nil)
+		}
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := json.Marshal(Schema())
+	assert.Equal(t, err, nil)
+
+	var decoded struct {
+		Properties map[string]map[string]interface{} `json:"properties"`
+	}
+	assert.Equal(t, json.Unmarshal(schema, &decoded), nil)
+	assert.Equal(t, len(decoded.Properties), len(Definitions))
+	assert.Equal(t, decoded.Properties[constant.SMTPPort]["type"], "integer")
+	assert.Equal(t, decoded.Properties[constant.SMTPPort]["default"], float64(25))
+	assert.Equal(t, decoded.Properties[constant.SMTPPassword]["writeOnly"], true)
+	assert.Equal(t, decoded.Properties[constant.AuditRetention]["type"], "object")
+}
+
+func TestCodec(t *testing.T) {
+	old, _ := crypt.ParseKeyring("old:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	stored, err := NewCodec(old).Encode(constant.SMTPPassword, "hunter2")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, crypt.IsSealed(stored), true)
+
+	// other settings and empty secrets are kept as they are
+	port, _ := NewCodec(old).Encode(constant.SMTPPort, "25")
+	assert.Equal(t, port, "25")
+	empty, _ := NewCodec(old).Encode(constant.SMTPPassword, "")
+	assert.Equal(t, empty, "")
+	_, err = NewCodec(nil).Encode(constant.SMTPPassword, "hunter2")
+	assert.Equal(t, err, ErrNoKeyring)
+
+	rotated, _ := crypt.ParseKeyring("new:" + base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef")) +
+		",old:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	codec := NewCodec(rotated)
+	assert.Equal(t, codec.Stale(constant.SMTPPassword, stored), true)
+	assert.Equal(t, codec.Stale(constant.SMTPPassword, "plaintext"), true)
+	assert.Equal(t, codec.Stale(constant.SMTPPort, "25"), false)
+	value, err := codec.Decode(constant.SMTPPassword, stored)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, value, "hunter2")
+
+	// a sealed secret copied to another setting does not open
+	_, err = codec.Decode(constant.SFTPPassword, stored)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestEvents(t *testing.T) {
+	events := NewEvents(nil)
+	defer events.Close()
+
+	received := make(chan Event, 4)
+	events.Subscribe(Subscription{Groups: []string{"smtp"}}, func(ctx context.Context, event Event) {
+		received <- event
+	})
+	unsubscribe := events.Subscribe(Subscription{Names: []string{constant.BackupTime}}, func(ctx context.Context, event Event) {
+		panic("handlers are isolated")
+	})
+	events.Subscribe(Subscription{Names: []string{constant.BackupTime}}, func(ctx context.Context, event Event) {
+		received <- event
+	})
+
+	assert.Equal(t, events.Publish(context.Background(), constant.SMTPPort), nil)
+	assert.Equal(t, events.Publish(context.Background(), constant.BackupTime), nil)
+	unsubscribe()
+	assert.Equal(t, events.Publish(context.Background(), constant.AuditRetentionTime), nil)
+
+	for _, expected := range []Event{
+		{Name: constant.SMTPPort, Group: "smtp"},
+		{Name: constant.BackupTime, Group: "backup"},
+	} {
+		select {
+		case event := <-received:
+			assert.Equal(t, event.Name, expected.Name)
+			assert.Equal(t, event.Group, expected.Group)
+		case <-time.After(time.Second):
+			t.Fatal("event not received")
+		}
+	}
+	select {
+	case event := <-received:
+		t.Fatalf("unexpected event %s", event.Name)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// publishing never waits on a busy dispatcher
+	blocked := make(chan struct{})
+	defer close(blocked)
+	events.Subscribe(Subscription{Names: []string{constant.SFTPHost}}, func(ctx context.Context, event Event) {
+		<-blocked
+	})
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			events.Publish(context.Background(), constant.SFTPHost)
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on a full queue")
+	}
+}
+
+// memoryCache misses every read so the repository always queries the database
+type memoryCache struct {
+	cache.Cache
+}
+
+func (memoryCache) Get(ctx context.Context, key string, data interface{}) error {
+	return errors.New("cache miss")
+}
+
+func (memoryCache) Set(ctx context.Context, key string, data interface{}, expiry time.Duration) error {
+	return nil
+}
+
+func (memoryCache) Del(ctx context.Context, keys ...string) error {
+	return nil
+}
+
+// newTestService creates a service on a sqlite database with a keyring of a zero key
+func newTestService(t *testing.T) (*service, *gorm.DB) {
+	// the repository reads the cache expiry from the configuration
+	for key, value := range map[string]string{
+		"ENV": "test", "HOST": "localhost", "PORT": "3000", "NET_TIMEOUT": "10",
+		"DB_DRIVER": "sqlite", "DB_HOST": "localhost", "DB_PORT": "0", "DB_USER": "test", "DB_PASSWORD": "test", "DB_NAME": "test",
+		"CACHE_DRIVER": "redis", "CACHE_HOST": "localhost", "CACHE_PORT": "6379", "CACHE_EXPIRY": "60",
+	} {
+		t.Setenv(key, value)
+	}
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "setting.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, db.AutoMigrate(&model.SettingEntity{}, &model.SettingHistoryEntity{}, &model.SettingSnapshotEntity{}), nil)
+
+	keyring, _ := crypt.ParseKeyring("key:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	events := NewEvents(nil)
+	t.Cleanup(events.Close)
+	return NewService(NewRepository(db, memoryCache{}), NewCodec(keyring), events), db
+}
+
+func TestHistory(t *testing.T) {
+	svc, db := newTestService(t)
+	ctx := context.WithValue(context.Background(), "auth-user", &dto.UserDto{Id: "actor", Name: "admin"})
+
+	for _, value := range []string{"25", "587", "587"} {
+		assert.Equal(t, svc.Save(ctx, &dto.PostSettingDto{Name: constant.SMTPPort, Value: value}), nil)
+	}
+	assert.Equal(t, svc.Save(ctx, &dto.PostSettingDto{Name: constant.SMTPPassword, Value: "hunter2"}), nil)
+
+	// saving the same value again is not a change
+	history, err := svc.History(ctx, constant.SMTPPort)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(history), 2)
+	assert.Equal(t, history[0].Version, int64(2))
+	assert.Equal(t, *history[0].OldValue, "25")
+	assert.Equal(t, history[0].NewValue, "587")
+	assert.Equal(t, history[0].ActorName, "admin")
+	assert.Equal(t, history[1].OldValue, (*string)(nil))
+
+	secrets, _ := svc.History(ctx, constant.SMTPPassword)
+	assert.Equal(t, secrets[0].NewValue, Mask)
+
+	snapshot, err := svc.Snapshot(ctx, &dto.PostSettingSnapshotDto{Name: "before"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, snapshot.Values[constant.SMTPPassword], Mask)
+
+	assert.Equal(t, svc.Save(ctx, &dto.PostSettingDto{Name: constant.SMTPPort, Value: "2525"}), nil)
+	assert.Equal(t, svc.Save(ctx, &dto.PostSettingDto{Name: constant.SMTPPassword, Value: "changed"}), nil)
+	assert.Equal(t, svc.RestoreSnapshot(ctx, snapshot.Id), nil)
+	port, _ := svc.ReadByName(ctx, constant.SMTPPort)
+	assert.Equal(t, port, "587")
+	password, _ := svc.ReadByName(ctx, constant.SMTPPassword)
+	assert.Equal(t, password, "hunter2")
+
+	assert.Equal(t, svc.Rollback(ctx, &dto.RollbackSettingDto{Name: constant.SMTPPort, Version: 1}), nil)
+	port, _ = svc.ReadByName(ctx, constant.SMTPPort)
+	assert.Equal(t, port, "25")
+	history, _ = svc.History(ctx, constant.SMTPPort)
+	assert.Equal(t, history[0].Origin, OriginRollback)
+	assert.Equal(t, history[1].Origin, OriginRestore)
+
+	err = svc.Rollback(ctx, &dto.RollbackSettingDto{Name: constant.SMTPPort, Version: 9})
+	assert.Equal(t, errors.Is(err, customErrors.ErrNotFound), true)
+
+	// a snapshot is restored entirely or not at all
+	invalid := model.SettingSnapshotEntity{Id: uuid.New(), Values: `{"smtp_host":"mail","smtp_port":"0"}`}
+	assert.Equal(t, db.Create(&invalid).Error, nil)
+	err = svc.RestoreSnapshot(ctx, invalid.Id.String())
+	assert.Equal(t, errors.Is(err, customErrors.ErrValidationError), true)
+	host, _ := svc.ReadByName(ctx, constant.SMTPHost)
+	assert.Equal(t, host, "localhost")
+}
+
+func TestTransfer(t *testing.T) {
+	content, err := MarshalSettings(map[string]string{
+		constant.SMTPPort:       "587",
+		constant.AuditRetention: `{"users":365}`,
+		constant.BackupTime:     "03:00",
+	}, FormatYAML)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, string(content), "audit_retention:\n    users: 365\nbackup_time: \"03:00\"\nsmtp_port: 587\n")
+
+	// yaml and json read back to the canonical strings
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		content, _ := MarshalSettings(map[string]string{constant.SMTPPort: "587", constant.AuditRetention: `{"users":365}`}, format)
+		values, err := UnmarshalSettings(content)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, values, map[string]string{constant.SMTPPort: "587", constant.AuditRetention: `{"users":365}`})
+	}
+
+	svc, _ := newTestService(t)
+	ctx := context.Background()
+	assert.Equal(t, svc.Save(ctx, &dto.PostSettingDto{Name: constant.SMTPPort, Value: "25"}), nil)
+	assert.Equal(t, svc.Save(ctx, &dto.PostSettingDto{Name: constant.SMTPPassword, Value: "hunter2"}), nil)
+
+	exported, err := svc.Export(ctx, false)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, exported[constant.SMTPPort], "25")
+	assert.Equal(t, exported[constant.SMTPHost], "localhost")
+	_, ok := exported[constant.SMTPPassword]
+	assert.Equal(t, ok, false)
+	exported, _ = svc.Export(ctx, true)
+	assert.Equal(t, exported[constant.SMTPPassword], "hunter2")
+
+	// a defined setting never saved and without a default is empty, only undefined ones are not found
+	host, err := svc.ReadByName(ctx, constant.SFTPHost)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, host, "")
+	_, err = svc.ReadByName(ctx, "unknown")
+	assert.Equal(t, errors.Is(err, customErrors.ErrNotFound), true)
+
+	values := map[string]string{constant.SMTPPort: "587", constant.SMTPPassword: "hunter2", constant.SMTPHost: "mail", constant.BackupStorage: "tape"}
+	_, err = svc.Import(ctx, values, true)
+	assert.Equal(t, errors.Is(err, customErrors.ErrValidationError), true)
+
+	values[constant.BackupStorage] = "sftp"
+	values[constant.SFTPPassword] = "secret"
+	changes, err := svc.Import(ctx, values, true)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(changes), 4)
+	assert.Equal(t, changes[0].Name, constant.BackupStorage)
+	assert.Equal(t, changes[0].Action, "create")
+	assert.Equal(t, changes[1].NewValue, Mask)
+	assert.Equal(t, *changes[3].OldValue, "25")
+	port, _ := svc.ReadByName(ctx, constant.SMTPPort)
+	assert.Equal(t, port, "25")
+
+	_, err = svc.Import(ctx, values, false)
+	assert.Equal(t, err, nil)
+	port, _ = svc.ReadByName(ctx, constant.SMTPPort)
+	assert.Equal(t, port, "587")
+	changes, _ = svc.Import(ctx, values, true)
+	assert.Equal(t, len(changes), 0)
+}
+
+func TestSeedRefresh(t *testing.T) {
+	svc, db := newTestService(t)
+	ctx := context.Background()
+
+	received := make(chan Event, 1)
+	svc.Subscribe(Subscription{Names: []string{constant.BackupTime}}, func(ctx context.Context, event Event) {
+		received <- event
+	})
+
+	action, err := NewSeeder(svc.codec).Seed(ctx, db, constant.BackupTime, "03:30")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, action, "create")
+
+	// the seeded settings are announced once committed
+	assert.Equal(t, svc.Refresh(ctx, []string{constant.BackupTime}), nil)
+	select {
+	case event := <-received:
+		assert.Equal(t, event.Name, constant.BackupTime)
+	case <-time.After(time.Second):
+		t.Fatal("event not received")
+	}
+	value, err := svc.ReadByName(ctx, constant.BackupTime)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, value, "03:30")
+}
@@ -0,0 +1,76 @@
+package setting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats settings are exported as
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// MarshalSettings writes values as a yaml or json object, values of defined settings keep their natural type
+// so exports read like hand written configuration
+func MarshalSettings(values map[string]string, format string) ([]byte, error) {
+	natural := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		natural[name] = value
+		if definition, ok := Lookup(name); ok && value != "" {
+			natural[name] = definition.natural(value)
+		}
+	}
+
+	switch format {
+	case FormatYAML, "":
+		return yaml.Marshal(natural)
+	case FormatJSON:
+		content, err := json.MarshalIndent(natural, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(content, '\n'), nil
+	}
+	return nil, fmt.Errorf("unknown format '%s', expected %s or %s", format, FormatYAML, FormatJSON)
+}
+
+// UnmarshalSettings reads a yaml or json object of settings back to their canonical strings,
+// numbers and booleans are written as is and objects and lists as json
+func UnmarshalSettings(content []byte) (map[string]string, error) {
+	// json is yaml, one decoder reads both
+	var natural map[string]interface{}
+	if err := yaml.Unmarshal(content, &natural); err != nil {
+		return nil, err
+	}
+	if natural == nil {
+		return nil, errors.New("settings must be an object of names to values")
+	}
+
+	values := make(map[string]string, len(natural))
+	for name, value := range natural {
+		switch typed := value.(type) {
+		case nil:
+			values[name] = ""
+		case string:
+			values[name] = typed
+		case bool:
+			values[name] = strconv.FormatBool(typed)
+		case int:
+			values[name] = strconv.Itoa(typed)
+		case float64:
+			values[name] = strconv.FormatFloat(typed, 'f', -1, 64)
+		default:
+			encoded, err := json.Marshal(typed)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %w", name, err)
+			}
+			values[name] = string(encoded)
+		}
+	}
+	return values, nil
+}
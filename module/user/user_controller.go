@@ -1,18 +1,23 @@
 package user
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/ericmarcelinotju/gram/dto"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/ericmarcelinotju/gram/utils/request"
 	"github.com/ericmarcelinotju/gram/utils/response"
+	"github.com/ericmarcelinotju/gram/utils/spreadsheet"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
 
 // GetUser godoc
 // @Summary     Get list of users
-// @Description Get list of users
+// @Description Get list of users, filter with field[operator]=value e.g. name[ilike]=jo, select fields=id,name and include=role,role.permissions
 // @Tags        User
 // @Accept      json
 // @Produce     json
@@ -34,12 +39,13 @@ func Get(service Service) func(c *gin.Context) {
 			return
 		}
 
-		result := dto.ListDto[dto.UserDto]{
-			Data:  users,
-			Total: total,
+		results, err := dto.Project(users, &payload.FieldsDto, fieldsConfig)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
 		}
 
-		response.ResponseSuccess(c, result)
+		response.ResponseList(c, results, total, payload.PaginationDto)
 	}
 }
 
@@ -50,6 +56,7 @@ func Get(service Service) func(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id          path       string   true   "User ID"
+// @Param       If-None-Match   header   string   false   "Entity tag of a cached version"
 // @Success     200         {object}   response.SetResponse{data=dto.UserDto}
 // @Router      /user/{id}  [get]
 // @Security    Auth
@@ -66,7 +73,7 @@ func GetDetail(service Service) func(c *gin.Context) {
 			return
 		}
 
-		response.ResponseSuccess(c, user)
+		response.ResponseVersioned(c, user, user.Version)
 	}
 }
 
@@ -78,6 +85,7 @@ func GetDetail(service Service) func(c *gin.Context) {
 // @Produce     json
 // @Param       user   body       dto.PostUserDto   true   "User Data"
 // @Success     200    {object}   response.SetResponse{data=dto.UserDto}
+// @Failure     422    {object}   response.SetResponse
 // @Router      /user  [post]
 // @Security    Auth
 func Post(service Service) func(c *gin.Context) {
@@ -88,6 +96,10 @@ func Post(service Service) func(c *gin.Context) {
 			return
 		}
 		res, err := service.Create(c, payload)
+		if errors.Is(err, customErrors.ErrValidationError) {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
 		if err != nil {
 			response.ResponseError(c, err, http.StatusInternalServerError)
 			return
@@ -104,8 +116,12 @@ func Post(service Service) func(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id		path			string			true	"User ID"
+// @Param       If-Match   header   string   false   "Entity tag of the version being updated"
 // @Param       user	body			dto.PutUserDto	true	"User Data"
 // @Success     200		{object}	response.SetResponse{data=dto.UserDto}
+// @Failure     400   {object}   response.SetResponse
+// @Failure     412   {object}   response.SetResponse
+// @Failure     422   {object}   response.SetResponse
 // @Router      /user/{id} [put]
 // @Security    Auth
 func Put(service Service) func(c *gin.Context) {
@@ -121,14 +137,31 @@ func Put(service Service) func(c *gin.Context) {
 			return
 		}
 		payload.Id = id
+		payload.Version, err = request.IfMatch(c)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusBadRequest)
+			return
+		}
 
 		res, err := service.Update(c, payload)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if errors.Is(err, customErrors.ErrValidationError) {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
 		if err != nil {
 			response.ResponseError(c, err, http.StatusInternalServerError)
 			return
 		}
 
-		response.ResponseSuccess(c, res)
+		response.ResponseVersioned(c, res, res.Version)
 	}
 }
 
@@ -139,7 +172,11 @@ func Put(service Service) func(c *gin.Context) {
 // @Accept      json
 // @Produce     json
 // @Param       id    path       string   true   "User ID"
+// @Param       If-Match   header   string   false   "Entity tag of the version being deleted"
 // @Success     200   {object}   response.SetResponse
+// @Failure     400   {object}   response.SetResponse
+// @Failure     404   {object}   response.SetResponse
+// @Failure     412   {object}   response.SetResponse
 // @Router      /user/{id} [delete]
 // @Security    Auth
 func Delete(service Service) func(c *gin.Context) {
@@ -150,7 +187,25 @@ func Delete(service Service) func(c *gin.Context) {
 			return
 		}
 
-		err = service.DeleteById(c, id)
+		version, err := request.IfMatch(c)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if err != nil {
+			response.ResponseError(c, err, http.StatusBadRequest)
+			return
+		}
+
+		err = service.DeleteById(c, id, version)
+		if errors.Is(err, customErrors.ErrPreconditionFailed) {
+			response.ResponseError(c, err, http.StatusPreconditionFailed)
+			return
+		}
+		if errors.Is(err, customErrors.ErrNotFound) {
+			response.ResponseError(c, err, http.StatusNotFound)
+			return
+		}
 		if err != nil {
 			response.ResponseError(c, err, http.StatusInternalServerError)
 			return
@@ -160,6 +215,135 @@ func Delete(service Service) func(c *gin.Context) {
 	}
 }
 
+// RestoreUser godoc
+// @Summary     Restore user by id
+// @Description Restore a deleted user by id
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       id    path       string   true   "User ID"
+// @Success     200   {object}   response.SetResponse
+// @Router      /user/{id}/restore [post]
+// @Security    Auth
+func Restore(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		id, err := request.BindId(c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		err = service.RestoreById(c, id)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, nil)
+	}
+}
+
+// ImportUser godoc
+// @Summary     Import users
+// @Description Validate a CSV or XLSX file of users, commit the valid rows as a background job unless dry run
+// @Tags        User
+// @Accept      mpfd
+// @Produce     json
+// @Param       file     formData   file     true    "CSV or XLSX file"
+// @Param       mapping  formData   string   false   "JSON object of user field to column header"
+// @Param       dry_run  formData   bool     false   "Only validate the file"
+// @Success     200      {object}   response.SetResponse{data=dto.UserImportDto}
+// @Router      /user/import  [post]
+// @Security    Auth
+func Import(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		payload, err := request.Bind[dto.ImportUserDto](c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		res, err := service.Import(c, payload)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, res)
+	}
+}
+
+// GetUserImport godoc
+// @Summary     Get user import's progress
+// @Description Get progress and row errors of a bulk user import
+// @Tags        User
+// @Accept      json
+// @Produce     json
+// @Param       id                 path       string   true   "Import ID"
+// @Success     200                {object}   response.SetResponse{data=dto.UserImportDto}
+// @Router      /user/import/{id}  [get]
+// @Security    Auth
+func GetImport(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		id, err := request.BindId(c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		res, err := service.ReadImportById(c, id)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+
+		response.ResponseSuccess(c, res)
+	}
+}
+
+// ExportUser godoc
+// @Summary     Export users
+// @Description Download users matching the list filters as CSV or XLSX
+// @Tags        User
+// @Accept      json
+// @Produce     octet-stream
+// @Param       item          query   dto.ExportUserDto   true   "Search, Filter & Format"
+// @Success     200
+// @Router      /user/export  [get]
+// @Security    Auth
+func Export(service Service) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		payload, err := request.Bind[dto.ExportUserDto](c)
+		if err != nil {
+			response.ResponseError(c, err, http.StatusUnprocessableEntity)
+			return
+		}
+		if payload.Format == "" {
+			payload.Format = spreadsheet.FormatCsv
+		}
+
+		contentType := "text/csv"
+		if payload.Format == spreadsheet.FormatXlsx {
+			contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		}
+		filename := fmt.Sprintf("users-%d.%s", time.Now().Unix(), payload.Format)
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+		err = service.Export(c, payload, c.Writer)
+		if err != nil {
+			if c.Writer.Written() {
+				// the download has started, the error is left to the access log
+				_ = c.Error(err)
+				return
+			}
+			c.Writer.Header().Del("Content-Type")
+			c.Writer.Header().Del("Content-Disposition")
+			response.ResponseError(c, err, http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
 func Connect(service Service) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		channel, err := request.Bind[dto.UserChannelDto](c)
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"mime/multipart"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -14,27 +16,108 @@ import (
 	"github.com/ericmarcelinotju/gram/dto"
 	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/ericmarcelinotju/gram/model"
+	"github.com/ericmarcelinotju/gram/module/crud"
+	"github.com/ericmarcelinotju/gram/plugins/database"
+	"github.com/ericmarcelinotju/gram/plugins/job"
 	"github.com/ericmarcelinotju/gram/plugins/storage"
 	ws "github.com/ericmarcelinotju/gram/plugins/websocket"
 	"github.com/ericmarcelinotju/gram/utils/crypt"
+	"github.com/ericmarcelinotju/gram/utils/logger"
 )
 
 const (
-	insertError = "Error in inserting new user"
-	updateError = "Error in updating user"
-	deleteError = "Error in deleting user"
-	selectError = "Error in selecting users in the database"
+	insertError  = "Error in inserting new user"
+	updateError  = "Error in updating user"
+	deleteError  = "Error in deleting user"
+	restoreError = "Error in restoring user"
+	purgeError   = "Error in purging deleted users"
+	selectError  = "Error in selecting users in the database"
+
+	insertImportError = "Error in inserting new user import"
+	updateImportError = "Error in updating user import"
+	selectImportError = "Error in selecting user imports in the database"
+	enqueueError      = "Error in queueing user import"
 )
 
+// filterConfig whitelists the fields users can be filtered on
+var filterConfig = dto.FilterConfig{
+	Fields: map[string]dto.FilterField{
+		"name":       {Column: "name", Type: dto.FilterString},
+		"email":      {Column: "email", Type: dto.FilterString},
+		"first_name": {Column: "firstname", Type: dto.FilterString},
+		"last_name":  {Column: "lastname", Type: dto.FilterString},
+		"title":      {Column: "title", Type: dto.FilterString},
+		"role_id":    {Column: "role_id", Type: dto.FilterUuid},
+		"role.name": {
+			Column:   "name",
+			Type:     dto.FilterString,
+			Relation: &dto.FilterRelation{Model: &model.RoleEntity{}, ForeignKey: "role_id"},
+		},
+		"last_login": {Column: "last_login", Type: dto.FilterTime},
+		"created_at": {Column: "created_at", Type: dto.FilterTime},
+		"updated_at": {Column: "updated_at", Type: dto.FilterTime},
+	},
+	Search: []string{"name", "email", "firstname", "lastname", "title"},
+}
+
+// sortConfig whitelists the fields users can be sorted on
+var sortConfig = dto.SortConfig{
+	Fields: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"email":      "email",
+		"first_name": "firstname",
+		"last_name":  "lastname",
+		"title":      "title",
+		"last_login": "last_login",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	Default: "created_at:desc",
+}
+
+// fieldsConfig whitelists the fields and relations user lists can select
+var fieldsConfig = dto.FieldsConfig{
+	Fields: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"email":      "email",
+		"first_name": "firstname",
+		"last_name":  "lastname",
+		"title":      "title",
+		"avatar":     "avatar",
+		"role_id":    "role_id",
+		"last_login": "last_login",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+		"deleted_at": "deleted_at",
+	},
+	Includes: map[string]dto.IncludeConfig{
+		"role":             {Preload: "Role", Columns: []string{"role_id"}},
+		"role.permissions": {Preload: "Role.Permissions", Columns: []string{"role_id"}},
+	},
+}
+
 // Repository provides an abstraction on top of the user data source
 type Repository interface {
 	Insert(context.Context, *dto.UserDto) error
 	Update(context.Context, *dto.UserDto) error
 	UpdatePassword(ctx context.Context, id string, password string) error
-	Select(context.Context, *dto.UserDto, *dto.PaginationDto, *dto.SortDto) ([]dto.UserDto, int64, error)
+	Select(context.Context, *dto.UserDto, *dto.FilterDto, *dto.FieldsDto, *dto.PaginationDto, *dto.SortDto, *dto.DeletedDto) ([]dto.UserDto, int64, error)
 	SelectById(context.Context, string) (*dto.UserDto, error)
 	SelectByUsername(context.Context, string) (*dto.UserDto, error)
 	Delete(context.Context, *dto.UserDto) error
+	DeleteById(context.Context, string, int64) error
+	Restore(context.Context, string) error
+	Purge(context.Context, time.Time) (int64, error)
+
+	SelectExisting(ctx context.Context, names, emails []string) (map[string]bool, map[string]bool, error)
+	SelectRoleIds(context.Context) (map[string]string, error)
+
+	InsertImport(context.Context, *dto.UserImportDto) error
+	UpdateImport(context.Context, *dto.UserImportDto) error
+	SelectImportById(context.Context, string) (*dto.UserImportDto, error)
+	EnqueueImport(context.Context, *dto.UserImportJobDto) error
 
 	SaveAvatar(file *multipart.File, filename string) error
 	RemoveAvatar(filename string) error
@@ -43,9 +126,11 @@ type Repository interface {
 }
 
 type repository struct {
+	*crud.Repository[*model.UserEntity, dto.UserDto]
 	db         *gorm.DB
 	storage    storage.Storage
 	dispatcher *ws.Dispatcher
+	queue      *job.Queue
 }
 
 // New creates a new repository struct
@@ -53,39 +138,79 @@ func NewRepository(
 	db *gorm.DB,
 	storage storage.Storage,
 	dispatcher *ws.Dispatcher,
+	queue *job.Queue,
 ) *repository {
 	return &repository{
+		Repository: crud.NewRepository(db, crud.Config[*model.UserEntity, dto.UserDto]{
+			NewEntity: model.NewUserEntity,
+			Errors: crud.Errors{
+				Insert:  insertError,
+				Update:  updateError,
+				Delete:  deleteError,
+				Restore: restoreError,
+				Select:  selectError,
+			},
+			Filter:       filterConfig,
+			Sort:         sortConfig,
+			Fields:       fieldsConfig,
+			Preloads:     []string{"Role", "Role.Permissions"},
+			BeforeInsert: beforeInsert,
+			BeforeUpdate: checkDeletedNames,
+		}),
 		db:         db,
 		storage:    storage,
 		dispatcher: dispatcher,
+		queue:      queue,
 	}
 }
 
-func (s *repository) Insert(ctx context.Context, payload *dto.UserDto) error {
-	entity := model.NewUserEntity(payload)
+// hashedPasswordKey marks a context inserting users whose password is hashed already
+type hashedPasswordKey struct{}
 
-	hashedPassword, err := crypt.Hash(entity.Password)
-	if err != nil {
+func withHashedPassword(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hashedPasswordKey{}, true)
+}
+
+func beforeInsert(tx *gorm.DB, entity *model.UserEntity) error {
+	if err := checkDeletedNames(tx, entity); err != nil {
 		return err
 	}
-	entity.Password = hashedPassword
+	return hashPassword(tx, entity)
+}
 
-	if err := s.db.WithContext(ctx).Create(entity).Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertError), customErrors.DatabaseError)
+// checkDeletedNames rejects a name or email still held by a soft deleted user, the user has to be restored instead
+func checkDeletedNames(tx *gorm.DB, entity *model.UserEntity) error {
+	var deleted []model.UserEntity
+	query := tx.Unscoped().
+		Model(&model.UserEntity{}).
+		Select("name", "email").
+		Where("deleted_at IS NOT NULL").
+		Where("name = ? OR LOWER(email) = ?", entity.Name, strings.ToLower(entity.Email)).
+		Find(&deleted)
+	if err := query.Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return appErr
+	}
+	for _, user := range deleted {
+		if user.Name == entity.Name {
+			appErr := customErrors.NewAppError(fmt.Errorf("name '%s' belongs to a deleted user, restore it", entity.Name), customErrors.ValidationError)
+			return appErr
+		}
+		appErr := customErrors.NewAppError(fmt.Errorf("email '%s' belongs to a deleted user, restore it", entity.Email), customErrors.ValidationError)
 		return appErr
 	}
-	payload.Id = entity.Id.String()
 	return nil
 }
 
-func (s *repository) Update(ctx context.Context, payload *dto.UserDto) error {
-	entity := model.NewUserEntity(payload)
-
-	if err := s.db.WithContext(ctx).Model(entity).Updates(entity).Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, updateError), customErrors.DatabaseError)
-		return appErr
+func hashPassword(tx *gorm.DB, entity *model.UserEntity) error {
+	if hashed, _ := tx.Statement.Context.Value(hashedPasswordKey{}).(bool); hashed {
+		return nil
 	}
-	payload.Id = entity.Id.String()
+	hashedPassword, err := crypt.Hash(entity.Password)
+	if err != nil {
+		return err
+	}
+	entity.Password = hashedPassword
 	return nil
 }
 
@@ -95,100 +220,184 @@ func (s *repository) UpdatePassword(ctx context.Context, id string, password str
 		return err
 	}
 
-	if err := s.db.WithContext(ctx).Model(&model.UserEntity{}).Where("id = ?", id).Update("password", hashedPassword).Error; err != nil {
+	if err := database.WithContext(ctx, s.db).Model(&model.UserEntity{}).Where("id = ?", id).Update("password", hashedPassword).Error; err != nil {
 		appErr := customErrors.NewAppError(pkgErr.Wrap(err, updateError), customErrors.DatabaseError)
 		return appErr
 	}
 	return nil
 }
 
-func (s *repository) Select(
-	ctx context.Context,
-	filter *dto.UserDto,
-	pagination *dto.PaginationDto,
-	sort *dto.SortDto,
-) ([]dto.UserDto, int64, error) {
-	var total int64
-	var entities []model.UserEntity
-
-	query := s.db.WithContext(ctx).
-		Model(&model.UserEntity{}).
+func (s *repository) SelectByUsername(ctx context.Context, id string) (*dto.UserDto, error) {
+	var result model.UserEntity
+	query := database.WithContext(ctx, s.db).
 		Preload("Role").
-		Preload("Role.Permissions")
+		Preload("Role.Permissions").
+		First(&result, "name = ?", id)
+
+	if errors.Is(query.Error, gorm.ErrRecordNotFound) {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, selectError), customErrors.NotFoundError)
+		return nil, appErr
+	}
+
+	if err := query.Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.RepositoryError)
+		return nil, appErr
+	}
+	return result.ToDto(), nil
+}
+
+// Restore undeletes a user, its role must not be deleted
+func (s *repository) Restore(ctx context.Context, id string) error {
+	return database.WithContext(ctx, s.db).Transaction(func(tx *gorm.DB) error {
+		var entity model.UserEntity
+		query := tx.Unscoped().First(&entity, "id = ? AND deleted_at IS NOT NULL", id)
+		if errors.Is(query.Error, gorm.ErrRecordNotFound) {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, restoreError), customErrors.NotFoundError)
+			return appErr
+		}
+		if err := query.Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, restoreError), customErrors.DatabaseError)
+			return appErr
+		}
+
+		var roles int64
+		if err := tx.Model(&model.RoleEntity{}).Where("id = ?", entity.RoleId).Count(&roles).Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, restoreError), customErrors.DatabaseError)
+			return appErr
+		}
+		if roles == 0 {
+			appErr := customErrors.NewAppError(errors.New("role of the user is deleted, restore the role first"), customErrors.ValidationError)
+			return appErr
+		}
+
+		if err := tx.Unscoped().Model(&entity).Update("deleted_at", nil).Error; err != nil {
+			appErr := customErrors.NewAppError(pkgErr.Wrap(err, restoreError), customErrors.DatabaseError)
+			return appErr
+		}
+		return nil
+	})
+}
+
+// Purge permanently deletes users deleted before the time along with their avatars
+func (s *repository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	var entities []model.UserEntity
+	if err := database.WithContext(ctx, s.db).Unscoped().Where("deleted_at < ?", before).Find(&entities).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, purgeError), customErrors.DatabaseError)
+		return 0, appErr
+	}
+	if len(entities) == 0 {
+		return 0, nil
+	}
 
-	if filter != nil {
-		query.Where(model.NewUserEntity(filter))
+	if err := database.WithContext(ctx, s.db).Unscoped().Delete(&entities).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, purgeError), customErrors.DatabaseError)
+		return 0, appErr
 	}
-	query.Count(&total)
-	if pagination != nil {
-		pagination.Apply(query)
+	for _, entity := range entities {
+		if entity.Avatar != nil && s.storage != nil {
+			if err := s.storage.Remove(*entity.Avatar); err != nil {
+				logger.Printf(ctx, "[USER PURGE] failed to remove avatar '%s' : %s", *entity.Avatar, err)
+			}
+		}
 	}
-	if sort != nil {
-		sort.Apply(query)
+	return int64(len(entities)), nil
+}
+
+// SelectExisting returns which of the names and emails are already taken, deleted users included, emails are compared lowercased
+func (s *repository) SelectExisting(ctx context.Context, names, emails []string) (map[string]bool, map[string]bool, error) {
+	existingNames := map[string]bool{}
+	existingEmails := map[string]bool{}
+	if len(names) == 0 && len(emails) == 0 {
+		return existingNames, existingEmails, nil
 	}
-	query.Find(&entities)
 
+	var entities []model.UserEntity
+	query := database.WithContext(ctx, s.db).
+		Unscoped().
+		Model(&model.UserEntity{}).
+		Select("name", "email").
+		Where("name IN ?", names).
+		Or("LOWER(email) IN ?", emails).
+		Find(&entities)
 	if err := query.Error; err != nil {
 		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
-		return nil, total, appErr
+		return nil, nil, appErr
+	}
+
+	for _, entity := range entities {
+		existingNames[entity.Name] = true
+		existingEmails[strings.ToLower(entity.Email)] = true
 	}
+	return existingNames, existingEmails, nil
+}
 
-	var results = make([]dto.UserDto, len(entities))
-	for i, element := range entities {
-		results[i] = *element.ToDto()
+// SelectRoleIds returns role ids keyed by both their id and their lowercased name
+func (s *repository) SelectRoleIds(ctx context.Context) (map[string]string, error) {
+	var entities []model.RoleEntity
+	if err := database.WithContext(ctx, s.db).Model(&model.RoleEntity{}).Select("id", "name").Find(&entities).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.DatabaseError)
+		return nil, appErr
 	}
 
-	return results, total, nil
+	roleIds := make(map[string]string, len(entities)*2)
+	for _, entity := range entities {
+		roleIds[entity.Id.String()] = entity.Id.String()
+		roleIds[strings.ToLower(entity.Name)] = entity.Id.String()
+	}
+	return roleIds, nil
 }
 
-func (s *repository) SelectById(ctx context.Context, id string) (*dto.UserDto, error) {
-	var result model.UserEntity
-	query := s.db.
-		WithContext(ctx).
-		Preload("Role").
-		Preload("Role.Permissions").
-		First(&result, "id = ?", id)
+func (s *repository) InsertImport(ctx context.Context, payload *dto.UserImportDto) error {
+	entity := model.NewUserImportEntity(payload)
 
-	if errors.Is(query.Error, gorm.ErrRecordNotFound) {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, selectError), customErrors.NotFoundError)
-		return nil, appErr
+	if err := database.WithContext(ctx, s.db).Create(entity).Error; err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, insertImportError), customErrors.DatabaseError)
+		return appErr
 	}
+	payload.Id = entity.Id.String()
+	payload.CreatedAt = entity.CreatedAt
+	payload.UpdatedAt = entity.UpdatedAt
+	return nil
+}
 
+func (s *repository) UpdateImport(ctx context.Context, payload *dto.UserImportDto) error {
+	entity := model.NewUserImportEntity(payload)
+
+	query := database.WithContext(ctx, s.db).
+		Model(entity).
+		Select("Status", "Processed", "Failed", "Rows", "FinishedAt").
+		Updates(entity)
 	if err := query.Error; err != nil {
-		return nil, err
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, updateImportError), customErrors.DatabaseError)
+		return appErr
 	}
-	return result.ToDto(), nil
+	return nil
 }
 
-func (s *repository) SelectByUsername(ctx context.Context, id string) (*dto.UserDto, error) {
-	var result model.UserEntity
-	query := s.db.
-		WithContext(ctx).
-		Preload("Role").
-		Preload("Role.Permissions").
-		First(&result, "name = ?", id)
+func (s *repository) SelectImportById(ctx context.Context, id string) (*dto.UserImportDto, error) {
+	var result model.UserImportEntity
+	query := database.WithContext(ctx, s.db).First(&result, "id = ?", id)
 
 	if errors.Is(query.Error, gorm.ErrRecordNotFound) {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, selectError), customErrors.NotFoundError)
+		appErr := customErrors.NewAppError(pkgErr.Wrap(query.Error, selectImportError), customErrors.NotFoundError)
 		return nil, appErr
 	}
-
 	if err := query.Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectError), customErrors.RepositoryError)
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, selectImportError), customErrors.DatabaseError)
 		return nil, appErr
 	}
 	return result.ToDto(), nil
 }
 
-func (s *repository) Delete(ctx context.Context, payload *dto.UserDto) error {
-	entity := model.NewUserEntity(payload)
-
-	if err := s.db.WithContext(ctx).First(entity).Delete(entity).Error; err != nil {
-		appErr := customErrors.NewAppError(pkgErr.Wrap(err, deleteError), customErrors.DatabaseError)
+func (s *repository) EnqueueImport(ctx context.Context, payload *dto.UserImportJobDto) error {
+	if s.queue == nil {
+		appErr := customErrors.NewAppError(errors.New("user import queue is not configured"), customErrors.RepositoryError)
+		return appErr
+	}
+	if err := s.queue.Client.PublishBytes(dto.CreateJob(ctx, payload).JSON()); err != nil {
+		appErr := customErrors.NewAppError(pkgErr.Wrap(err, enqueueError), customErrors.RepositoryError)
 		return appErr
 	}
-	payload.Avatar = entity.Avatar
-
 	return nil
 }
 
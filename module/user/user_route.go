@@ -9,10 +9,14 @@ func NewApiRoutesFactory(router *gin.RouterGroup) func(service Service) {
 	group := router.Group("/api/user")
 	userRoutesFactory := func(service Service) {
 		group.GET("", Get(service))
+		group.GET("/export", Export(service))
+		group.POST("/import", Import(service))
+		group.GET("/import/:id", GetImport(service))
 		group.GET("/:id", GetDetail(service))
 		group.POST("", Post(service))
 		group.PUT("/:id", Put(service))
 		group.DELETE("/:id", Delete(service))
+		group.POST("/:id/restore", Restore(service))
 	}
 	return userRoutesFactory
 }
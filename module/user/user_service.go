@@ -2,15 +2,42 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
+	"net/mail"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/ericmarcelinotju/gram/constant/enums"
 	"github.com/ericmarcelinotju/gram/dto"
 	customErrors "github.com/ericmarcelinotju/gram/errors"
+	"github.com/ericmarcelinotju/gram/module/crud"
+	"github.com/ericmarcelinotju/gram/plugins/database"
 	"github.com/ericmarcelinotju/gram/utils/crypt"
+	"github.com/ericmarcelinotju/gram/utils/spreadsheet"
 	"github.com/gorilla/websocket"
 )
 
+const (
+	maxImportRows      = 5000
+	importProgressStep = 50
+	exportBatchSize    = 500
+)
+
+// importFields are the user fields an import column can be mapped to
+var importFields = []string{"name", "email", "password", "first_name", "last_name", "title", "role_id", "role"}
+
+var exportInclude = "role"
+
+var exportHeader = []string{"id", "name", "email", "first_name", "last_name", "title", "role_id", "role", "last_login", "created_at"}
+
 // Service defines user service behavior.
 type Service interface {
 	Create(context.Context, *dto.PostUserDto) (*dto.UserDto, error)
@@ -20,46 +47,67 @@ type Service interface {
 	Update(context.Context, *dto.PutUserDto) (*dto.UserDto, error)
 	UpdatePassword(context.Context, *dto.ChangeUserPasswordDto) error
 
-	DeleteById(context.Context, string) error
+	DeleteById(context.Context, string, int64) error
+	RestoreById(context.Context, string) error
+	Purge(context.Context, time.Time) (int64, error)
+
+	Import(context.Context, *dto.ImportUserDto) (*dto.UserImportDto, error)
+	ReadImportById(context.Context, string) (*dto.UserImportDto, error)
+	ProcessImport(context.Context, *dto.UserImportJobDto) error
+	Export(context.Context, *dto.ExportUserDto, io.Writer) error
 
 	Connect(*websocket.Conn, *dto.UserChannelDto) error
 }
 
 type service struct {
+	*crud.Service[dto.UserDto]
 	repo Repository
+	uow  *database.UnitOfWork
 }
 
 // NewService creates a new service struct
-func NewService(repo Repository) *service {
-	return &service{repo: repo}
+func NewService(repo Repository, uow *database.UnitOfWork) *service {
+	return &service{Service: crud.NewService[dto.UserDto](repo), repo: repo, uow: uow}
 }
 
-func (svc *service) Create(ctx context.Context, payload *dto.PostUserDto) (res *dto.UserDto, err error) {
-	var avatar *string
-	if payload.Avatar != nil {
-		file, err := payload.Avatar.Open()
-		if err != nil {
-			return nil, err
-		}
+// saveAvatar uploads the avatar file under a new name once write succeeds, write runs in a transaction
+// so the record is rolled back when the upload fails
+func (svc *service) saveAvatar(ctx context.Context, header *multipart.FileHeader, write func(ctx context.Context, avatar *string) error) error {
+	if header == nil {
+		return write(ctx, nil)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
 
-		filename := "user/" + fmt.Sprintf("%d", time.Now().Unix())
-		if err := svc.repo.SaveAvatar(&file, filename); err != nil {
-			return nil, err
+	filename := "user/" + fmt.Sprintf("%d", time.Now().Unix())
+	return svc.uow.Do(ctx, func(ctx context.Context) error {
+		if err := write(ctx, &filename); err != nil {
+			return err
 		}
+		return svc.repo.SaveAvatar(&file, filename)
+	})
+}
 
-		avatar = &filename
-	}
-
-	res = &dto.UserDto{
-		Name:     payload.Name,
-		Lastname: payload.Lastname,
-		Title:    payload.Title,
-		Email:    payload.Email,
-		Password: payload.Password,
-		Avatar:   avatar,
-		RoleId:   payload.RoleId,
-	}
-	err = svc.repo.Insert(ctx, res)
+func (svc *service) Create(ctx context.Context, payload *dto.PostUserDto) (res *dto.UserDto, err error) {
+	err = svc.saveAvatar(ctx, payload.Avatar, func(ctx context.Context, avatar *string) error {
+		res = &dto.UserDto{
+			Name:     payload.Name,
+			Lastname: payload.Lastname,
+			Title:    payload.Title,
+			Email:    payload.Email,
+			Password: payload.Password,
+			Avatar:   avatar,
+			RoleId:   payload.RoleId,
+		}
+		if err := svc.repo.Insert(ctx, res); err != nil {
+			return err
+		}
+		res.Password = ""
+		return nil
+	})
 	return
 }
 
@@ -79,8 +127,11 @@ func (svc *service) Read(ctx context.Context, payload *dto.GetUserDto) ([]dto.Us
 	return svc.repo.Select(
 		ctx,
 		filter,
+		&payload.FilterDto,
+		&payload.FieldsDto,
 		payload.PaginationDto,
 		payload.SortDto,
+		payload.DeletedDto,
 	)
 }
 
@@ -88,36 +139,20 @@ func (svc *service) ReadByUsername(ctx context.Context, username string) (*dto.U
 	return svc.repo.SelectByUsername(ctx, username)
 }
 
-func (svc *service) ReadById(ctx context.Context, id string) (*dto.UserDto, error) {
-	return svc.repo.SelectById(ctx, id)
-}
-
 func (svc *service) Update(ctx context.Context, payload *dto.PutUserDto) (res *dto.UserDto, err error) {
-	var avatar *string
-	if payload.Avatar != nil {
-		file, err := payload.Avatar.Open()
-		if err != nil {
-			return nil, err
-		}
-
-		filename := "user/" + fmt.Sprintf("%d", time.Now().Unix())
-		if err := svc.repo.SaveAvatar(&file, filename); err != nil {
-			return nil, err
+	err = svc.saveAvatar(ctx, payload.Avatar, func(ctx context.Context, avatar *string) error {
+		res = &dto.UserDto{
+			Id:       payload.Id,
+			Name:     payload.Name,
+			Lastname: payload.Lastname,
+			Title:    payload.Title,
+			Email:    payload.Email,
+			Avatar:   avatar,
+			RoleId:   payload.RoleId,
+			Version:  payload.Version,
 		}
-
-		avatar = &filename
-	}
-
-	res = &dto.UserDto{
-		Id:       payload.Id,
-		Name:     payload.Name,
-		Lastname: payload.Lastname,
-		Title:    payload.Title,
-		Email:    payload.Email,
-		Avatar:   avatar,
-		RoleId:   payload.RoleId,
-	}
-	err = svc.repo.Update(ctx, res)
+		return svc.repo.Update(ctx, res)
+	})
 	return
 }
 
@@ -132,18 +167,356 @@ func (svc *service) UpdatePassword(ctx context.Context, payload *dto.ChangeUserP
 	return svc.repo.UpdatePassword(ctx, payload.Id, payload.NewPassword)
 }
 
-func (svc *service) DeleteById(ctx context.Context, id string) error {
-	payload := &dto.UserDto{Id: id}
-	err := svc.repo.Delete(ctx, payload)
+func (svc *service) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return svc.repo.Purge(ctx, before)
+}
+
+func (svc *service) Connect(conn *websocket.Conn, channel *dto.UserChannelDto) error {
+	return svc.repo.Connect(conn, channel)
+}
+
+// importColumns resolves the column index of every mapped user field from the header row
+func importColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	for field := range mapping {
+		known := false
+		for _, importField := range importFields {
+			known = known || field == importField
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown import field '%s'", field)
+		}
+	}
+
+	columns := map[string]int{}
+	for _, field := range importFields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), strings.TrimSpace(name)) {
+				columns[field] = i
+				break
+			}
+		}
+		if _, ok := columns[field]; !ok && mapped {
+			return nil, fmt.Errorf("column '%s' of field '%s' not found", name, field)
+		}
+	}
+
+	for _, field := range []string{"name", "email"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("column of field '%s' not found", field)
+		}
+	}
+	_, hasRoleId := columns["role_id"]
+	_, hasRole := columns["role"]
+	if !hasRoleId && !hasRole {
+		return nil, errors.New("column of field 'role_id' or 'role' not found")
+	}
+	return columns, nil
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func (svc *service) Import(ctx context.Context, payload *dto.ImportUserDto) (*dto.UserImportDto, error) {
+	format, err := spreadsheet.FormatOf(payload.File.Filename)
+	if err != nil {
+		return nil, customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+
+	mapping := map[string]string{}
+	if payload.Mapping != "" {
+		if err := json.Unmarshal([]byte(payload.Mapping), &mapping); err != nil {
+			return nil, customErrors.NewAppError(fmt.Errorf("invalid column mapping: %w", err), customErrors.ValidationError)
+		}
+	}
+
+	file, err := payload.File.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := spreadsheet.Read(file, format)
+	if err != nil {
+		return nil, customErrors.NewAppError(fmt.Errorf("invalid import file: %w", err), customErrors.ValidationError)
+	}
+	if len(rows) < 2 {
+		return nil, customErrors.NewAppError(errors.New("import file has no rows"), customErrors.ValidationError)
+	}
+	if len(rows)-1 > maxImportRows {
+		return nil, customErrors.NewAppError(fmt.Errorf("import file exceeds %d rows", maxImportRows), customErrors.ValidationError)
+	}
+
+	columns, err := importColumns(rows[0], mapping)
+	if err != nil {
+		return nil, customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+
+	roleIds, err := svc.repo.SelectRoleIds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// collect the rows first so duplicates are checked against the whole file and the database
+	var jobRows []dto.UserImportJobRowDto
+	var names, emails []string
+	nameRows := map[string]int{}
+	emailRows := map[string]int{}
+	for i, row := range rows[1:] {
+		value := func(field string) string {
+			if column, ok := columns[field]; ok && column < len(row) {
+				return strings.TrimSpace(row[column])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+
+		role := value("role_id")
+		if role == "" {
+			role = value("role")
+		}
+		jobRow := dto.UserImportJobRowDto{
+			Row: i + 2,
+			User: dto.UserDto{
+				Name:      value("name"),
+				Email:     value("email"),
+				Password:  value("password"),
+				Firstname: value("first_name"),
+				Lastname:  value("last_name"),
+				Title:     value("title"),
+				RoleName:  role,
+			},
+		}
+		jobRows = append(jobRows, jobRow)
+
+		email := strings.ToLower(jobRow.User.Email)
+		if _, ok := nameRows[jobRow.User.Name]; !ok {
+			nameRows[jobRow.User.Name] = jobRow.Row
+			names = append(names, jobRow.User.Name)
+		}
+		if _, ok := emailRows[email]; !ok {
+			emailRows[email] = jobRow.Row
+			emails = append(emails, email)
+		}
+	}
+
+	existingNames, existingEmails, err := svc.repo.SelectExisting(ctx, names, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.UserImportDto{
+		Filename: payload.File.Filename,
+		DryRun:   payload.DryRun,
+		Total:    len(jobRows),
+	}
+	validRows := make([]dto.UserImportJobRowDto, 0, len(jobRows))
+	for i := range jobRows {
+		jobRow := &jobRows[i]
+		user := &jobRow.User
+		email := strings.ToLower(user.Email)
+
+		var rowErrors []string
+		if len(user.Name) < 2 {
+			rowErrors = append(rowErrors, "name must be at least 2 characters")
+		} else if existingNames[user.Name] {
+			rowErrors = append(rowErrors, "name already exists")
+		} else if first := nameRows[user.Name]; first != jobRow.Row {
+			rowErrors = append(rowErrors, fmt.Sprintf("name duplicates row %d", first))
+		}
+		if address, err := mail.ParseAddress(user.Email); err != nil || address.Address != user.Email {
+			rowErrors = append(rowErrors, "email is invalid")
+		} else if existingEmails[email] {
+			rowErrors = append(rowErrors, "email already exists")
+		} else if first := emailRows[email]; first != jobRow.Row {
+			rowErrors = append(rowErrors, fmt.Sprintf("email duplicates row %d", first))
+		}
+		if roleId, ok := roleIds[strings.ToLower(user.RoleName)]; user.RoleName == "" {
+			rowErrors = append(rowErrors, "role is required")
+		} else if !ok {
+			rowErrors = append(rowErrors, fmt.Sprintf("role '%s' not found", user.RoleName))
+		} else {
+			user.RoleId = roleId
+		}
+
+		if len(rowErrors) > 0 {
+			report.Invalid++
+			report.Rows = append(report.Rows, dto.UserImportRowDto{
+				Row:    jobRow.Row,
+				Name:   user.Name,
+				Email:  user.Email,
+				Errors: rowErrors,
+			})
+			continue
+		}
+
+		if user.Password == "" {
+			// users without password are expected to reset it through forgot password
+			if user.Password, err = randomPassword(); err != nil {
+				return nil, err
+			}
+		}
+		report.Valid++
+		validRows = append(validRows, *jobRow)
+	}
+
+	if payload.DryRun || report.Valid == 0 {
+		return report, nil
+	}
+	// the job payload sits in the queue, only hashes may be written there
+	if err := hashImportPasswords(validRows); err != nil {
+		return nil, err
+	}
+
+	// commit the valid rows in the background, invalid rows are reported and skipped
+	report.Status = enums.JobStatusPending
+	if err := svc.repo.InsertImport(ctx, report); err != nil {
+		return nil, err
+	}
+	err = svc.repo.EnqueueImport(ctx, &dto.UserImportJobDto{
+		ImportId: report.Id,
+		Rows:     validRows,
+	})
+	if err != nil {
+		finishedAt := time.Now()
+		report.Status = enums.JobStatusFailed
+		report.FinishedAt = &finishedAt
+		if updateErr := svc.repo.UpdateImport(ctx, report); updateErr != nil {
+			return nil, updateErr
+		}
+		return nil, err
+	}
+	return report, nil
+}
+
+// hashImportPasswords replaces the passwords of the rows by their hash, rows are hashed in parallel as hashing is slow on purpose
+func hashImportPasswords(rows []dto.UserImportJobRowDto) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(rows))
+	slots := make(chan struct{}, runtime.NumCPU())
+	for i := range rows {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			rows[i].User.Password, errs[i] = crypt.Hash(rows[i].User.Password)
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (svc *service) ReadImportById(ctx context.Context, id string) (*dto.UserImportDto, error) {
+	return svc.repo.SelectImportById(ctx, id)
+}
+
+func (svc *service) ProcessImport(ctx context.Context, payload *dto.UserImportJobDto) error {
+	progress, err := svc.repo.SelectImportById(ctx, payload.ImportId)
 	if err != nil {
 		return err
 	}
-	if payload.Avatar != nil {
-		return svc.repo.RemoveAvatar(*payload.Avatar)
+	if progress.Status != enums.JobStatusPending {
+		// already processed by a previous delivery
+		return nil
+	}
+
+	progress.Status = enums.JobStatusRunning
+	if err := svc.repo.UpdateImport(ctx, progress); err != nil {
+		return err
+	}
+
+	// passwords were hashed before the rows were queued
+	insertCtx := withHashedPassword(ctx)
+	for _, row := range payload.Rows {
+		user := row.User
+		if err := svc.repo.Insert(insertCtx, &user); err != nil {
+			progress.Failed++
+			progress.Rows = append(progress.Rows, dto.UserImportRowDto{
+				Row:    row.Row,
+				Name:   user.Name,
+				Email:  user.Email,
+				Errors: []string{err.Error()},
+			})
+		}
+		progress.Processed++
+
+		if progress.Processed%importProgressStep == 0 {
+			if err := svc.repo.UpdateImport(ctx, progress); err != nil {
+				return err
+			}
+		}
 	}
-	return nil
+
+	finishedAt := time.Now()
+	progress.Status = enums.JobStatusSuccess
+	if progress.Failed == len(payload.Rows) {
+		progress.Status = enums.JobStatusFailed
+	}
+	progress.FinishedAt = &finishedAt
+	return svc.repo.UpdateImport(ctx, progress)
 }
 
-func (svc *service) Connect(conn *websocket.Conn, channel *dto.UserChannelDto) error {
-	return svc.repo.Connect(conn, channel)
+func (svc *service) Export(ctx context.Context, payload *dto.ExportUserDto, w io.Writer) error {
+	format := payload.Format
+	if format == "" {
+		format = spreadsheet.FormatCsv
+	}
+	writer, err := spreadsheet.NewWriter(w, format)
+	if err != nil {
+		return customErrors.NewAppError(err, customErrors.ValidationError)
+	}
+	if err := writer.Write(exportHeader); err != nil {
+		return err
+	}
+
+	// read in cursor batches so concurrent inserts do not shift the rows
+	filter := payload.GetUserDto
+	filter.FieldsDto = dto.FieldsDto{Include: &exportInclude}
+	limit := exportBatchSize
+	cursor := ""
+	for {
+		filter.PaginationDto = &dto.PaginationDto{Limit: &limit, Cursor: &cursor}
+
+		users, _, err := svc.Read(ctx, &filter)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			lastLogin := ""
+			if user.LastLogin != nil {
+				lastLogin = user.LastLogin.Format(time.RFC3339)
+			}
+			err := writer.Write([]string{
+				user.Id,
+				user.Name,
+				user.Email,
+				user.Firstname,
+				user.Lastname,
+				user.Title,
+				user.RoleId,
+				user.Role.Name,
+				lastLogin,
+				user.CreatedAt.Format(time.RFC3339),
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if filter.PaginationDto.NextCursor == nil {
+			break
+		}
+		cursor = *filter.PaginationDto.NextCursor
+	}
+	return writer.Close()
 }
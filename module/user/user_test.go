@@ -2,12 +2,16 @@ package user
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/ericmarcelinotju/gram/config"
 	"github.com/ericmarcelinotju/gram/dto"
 	"github.com/ericmarcelinotju/gram/plugins/database"
 	"github.com/ericmarcelinotju/gram/plugins/storage"
+	"github.com/ericmarcelinotju/gram/utils/crypt"
+	"github.com/gin-gonic/gin"
 	"github.com/go-playground/assert/v2"
 )
 
@@ -25,8 +29,8 @@ func setupService() (context.Context, Service) {
 		fileStorage, _ = storage.NewFileStorage(configuration.MediaStorage)
 	}
 
-	userRepo := NewRepository(db, fileStorage, nil)
-	return context.Background(), NewService(userRepo)
+	userRepo := NewRepository(db, fileStorage, nil, nil)
+	return context.Background(), NewService(userRepo, database.NewUnitOfWork(db))
 }
 
 func TestReadUserHandler(t *testing.T) {
@@ -119,7 +123,7 @@ func TestDeleteUserHandler(t *testing.T) {
 
 	id := "aasdasdasd"
 
-	err := svc.DeleteById(ctx, id)
+	err := svc.DeleteById(ctx, id, 0)
 
 	assert.NotEqual(t, err, nil)
 
@@ -127,3 +131,38 @@ func TestDeleteUserHandler(t *testing.T) {
 
 	assert.Equal(t, err, nil)
 }
+
+func TestHashImportPasswords(t *testing.T) {
+	rows := []dto.UserImportJobRowDto{
+		{Row: 2, User: dto.UserDto{Name: "ada", Password: "secret"}},
+		{Row: 3, User: dto.UserDto{Name: "alan", Password: "generated"}},
+	}
+	assert.Equal(t, hashImportPasswords(rows), nil)
+
+	// no password reaches the queue in clear
+	assert.Equal(t, crypt.CompareHash(rows[0].User.Password, "secret"), true)
+	assert.Equal(t, crypt.CompareHash(rows[1].User.Password, "generated"), true)
+}
+
+func TestDeleteMalformedIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/user/:id", Delete(nil))
+
+	for _, header := range []string{`"abc"`, `"1", "2"`} {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("DELETE", "/user/6f1c2d3e-4b5a-4c6d-8e7f-9a0b1c2d3e4f", nil)
+		req.Header.Set("If-Match", header)
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, recorder.Code, http.StatusBadRequest)
+	}
+
+	// a weak tag never matches strongly
+	recorder := httptest.NewRecorder()
+	req := httptest.NewRequest("DELETE", "/user/6f1c2d3e-4b5a-4c6d-8e7f-9a0b1c2d3e4f", nil)
+	req.Header.Set("If-Match", `W/"1"`)
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, recorder.Code, http.StatusPreconditionFailed)
+}
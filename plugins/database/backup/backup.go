@@ -0,0 +1,443 @@
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/ericmarcelinotju/gram/plugins/database/migration"
+	"github.com/ericmarcelinotju/gram/plugins/storage"
+)
+
+// Format is the version of the archive layout
+const Format = 1
+
+// Folder is the storage folder of the archives
+const Folder = "backup"
+
+const manifestName = "manifest.json"
+
+// batchSize is how many rows are inserted at once on restore
+const batchSize = 500
+
+var archiveName = regexp.MustCompile(`^backup-(\d+)\.tar\.gz$`)
+
+// Manifest describes an archive, it is the first file of the archive
+type Manifest struct {
+	Format        int             `json:"format"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Driver        string          `json:"driver"`
+	SchemaVersion int64           `json:"schema_version"`
+	Tables        []TableManifest `json:"tables"`
+}
+
+// TableManifest is a table of an archive, kept as <name>.jsonl with one json object per row
+type TableManifest struct {
+	Name     string `json:"name"`
+	Rows     int64  `json:"rows"`
+	Checksum string `json:"checksum"`
+}
+
+// Tables returns the tables of the models in order, each followed by its many to many join tables
+func Tables(db *gorm.DB, models ...interface{}) ([]string, error) {
+	seen := make(map[string]bool)
+	var tables []string
+	add := func(table string) {
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+
+	for _, model := range models {
+		statement := &gorm.Statement{DB: db}
+		if err := statement.Parse(model); err != nil {
+			return nil, err
+		}
+		add(statement.Schema.Table)
+		for _, relationship := range statement.Schema.Relationships.Relations {
+			if relationship.JoinTable != nil {
+				add(relationship.JoinTable.Table)
+			}
+		}
+	}
+	return tables, nil
+}
+
+// Backup dumps and restores tables as a portable archive of json lines
+type Backup struct {
+	db     *gorm.DB
+	tables []string
+}
+
+// NewBackup creates a backup of the tables, restored in the same order
+func NewBackup(db *gorm.DB, tables []string) *Backup {
+	return &Backup{db: db.Session(&gorm.Session{SkipHooks: true}), tables: tables}
+}
+
+// Create dumps the tables and uploads the archive to store, it returns the archive file name
+func (b *Backup) Create(ctx context.Context, store storage.Storage) (string, *Manifest, error) {
+	file, err := os.CreateTemp("", "backup-*.tar.gz")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	manifest, err := b.Dump(ctx, file)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", nil, err
+	}
+
+	filename := path.Join(Folder, fmt.Sprintf("backup-%d.tar.gz", manifest.CreatedAt.Unix()))
+	if err := store.UploadStream(file, filename); err != nil {
+		return "", nil, err
+	}
+	return filename, manifest, nil
+}
+
+// Restore downloads an archive from store and loads it
+func (b *Backup) Restore(ctx context.Context, store storage.Storage, filename string) (*Manifest, error) {
+	reader, err := store.DownloadStream(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return b.Load(ctx, reader)
+}
+
+// Prune removes the archives of store created before a time and returns their names
+func (b *Backup) Prune(ctx context.Context, store storage.Storage, before time.Time) ([]string, error) {
+	files, err := store.List(Folder)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, file := range files {
+		match := archiveName.FindStringSubmatch(file.Name())
+		if file.IsDir() || match == nil {
+			continue
+		}
+		created, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || !time.Unix(created, 0).Before(before) {
+			continue
+		}
+		filename := path.Join(Folder, file.Name())
+		if err := store.Remove(filename); err != nil {
+			return removed, err
+		}
+		removed = append(removed, filename)
+	}
+	return removed, nil
+}
+
+// Dump writes the tables to w as a gzipped tar of the manifest followed by a json lines file per table
+func (b *Backup) Dump(ctx context.Context, w io.Writer) (*Manifest, error) {
+	db := b.db.WithContext(ctx)
+
+	schemaVersion, err := migration.CurrentVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{
+		Format:        Format,
+		CreatedAt:     time.Now().UTC(),
+		Driver:        db.Dialector.Name(),
+		SchemaVersion: schemaVersion,
+	}
+
+	// tables are dumped to temporary files first, the manifest needs their checksums and tar their sizes
+	files := make([]*os.File, 0, len(b.tables))
+	defer func() {
+		for _, file := range files {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+	// the tables are read in one snapshot transaction so they are dumped from the same state
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range b.tables {
+			file, err := os.CreateTemp("", table+"-*.jsonl")
+			if err != nil {
+				return err
+			}
+			files = append(files, file)
+
+			tableManifest, err := dumpTable(tx, table, file)
+			if err != nil {
+				return fmt.Errorf("error when dumping %s %w", table, err)
+			}
+			manifest.Tables = append(manifest.Tables, *tableManifest)
+		}
+		return nil
+	}, snapshot(manifest.Driver))
+	if err != nil {
+		return nil, err
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tarWriter, manifestName, int64(len(content)), bytes.NewReader(content)); err != nil {
+		return nil, err
+	}
+	for i, file := range files {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if err := writeEntry(tarWriter, manifest.Tables[i].Name+".jsonl", info.Size(), file); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// snapshot returns the options of a transaction reading every table from the same state, sqlserver has no
+// read only transactions and only keeps the rows it read stable when serializable
+func snapshot(driver string) *sql.TxOptions {
+	if driver == "sqlserver" {
+		return &sql.TxOptions{Isolation: sql.LevelSerializable}
+	}
+	return &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+}
+
+func dumpTable(tx *gorm.DB, table string, w io.Writer) (*TableManifest, error) {
+	rows, err := tx.Table(table).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hash := sha256.New()
+	encoder := json.NewEncoder(io.MultiWriter(w, hash))
+	result := &TableManifest{Name: table}
+	for rows.Next() {
+		row := make(map[string]interface{})
+		if err := tx.ScanRows(rows, &row); err != nil {
+			return nil, err
+		}
+		for column, value := range row {
+			// text scanned as bytes by some drivers is kept readable
+			if value, ok := value.([]byte); ok {
+				row[column] = string(value)
+			}
+		}
+		if err := encoder.Encode(row); err != nil {
+			return nil, err
+		}
+		result.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	result.Checksum = hex.EncodeToString(hash.Sum(nil))
+	return result, nil
+}
+
+func writeEntry(w *tar.Writer, name string, size int64, content io.Reader) error {
+	if err := w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: size, ModTime: time.Now()}); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, content)
+	return err
+}
+
+// Load restores an archive into a database migrated to the schema version of the archive whose tables are empty,
+// the whole archive is restored in one transaction
+func (b *Backup) Load(ctx context.Context, r io.Reader) (*Manifest, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+
+	header, err := tarReader.Next()
+	if err != nil {
+		return nil, err
+	}
+	if header.Name != manifestName {
+		return nil, errors.New("archive does not start with a manifest")
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tarReader).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != Format {
+		return nil, fmt.Errorf("unsupported archive format %d", manifest.Format)
+	}
+
+	db := b.db.WithContext(ctx)
+	schemaVersion, err := migration.CurrentVersion(db)
+	if err != nil {
+		return nil, err
+	}
+	if schemaVersion != manifest.SchemaVersion {
+		return nil, fmt.Errorf("archive is at schema version %d but the database is at %d, migrate the database first", manifest.SchemaVersion, schemaVersion)
+	}
+
+	tables := make(map[string]TableManifest, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		var count int64
+		if err := db.Table(table.Name).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, fmt.Errorf("table %s is not empty, restore into a fresh database", table.Name)
+		}
+		tables[table.Name+".jsonl"] = table
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for {
+			header, err := tarReader.Next()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			table, ok := tables[header.Name]
+			if !ok {
+				return fmt.Errorf("archive has an unknown file %s", header.Name)
+			}
+			if err := loadTable(tx, table, tarReader); err != nil {
+				return fmt.Errorf("error when restoring %s %w", table.Name, err)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+func loadTable(tx *gorm.DB, table TableManifest, r io.Reader) error {
+	columnTypes, err := tx.Migrator().ColumnTypes(table.Name)
+	if err != nil {
+		return err
+	}
+	types := make(map[string]string, len(columnTypes))
+	for _, columnType := range columnTypes {
+		types[columnType.Name()] = strings.ToLower(columnType.DatabaseTypeName())
+	}
+
+	hash := sha256.New()
+	scanner := bufio.NewScanner(io.TeeReader(r, hash))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+
+	var rows int64
+	batch := make([]map[string]interface{}, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := tx.Table(table.Name).Create(&batch).Error; err != nil {
+			return err
+		}
+		batch = make([]map[string]interface{}, 0, batchSize)
+		return nil
+	}
+
+	for scanner.Scan() {
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+		var row map[string]interface{}
+		if err := decoder.Decode(&row); err != nil {
+			return err
+		}
+		for column, value := range row {
+			if row[column], err = convert(value, types[column]); err != nil {
+				return fmt.Errorf("column %s %w", column, err)
+			}
+		}
+		batch = append(batch, row)
+		rows++
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if rows != table.Rows || hex.EncodeToString(hash.Sum(nil)) != table.Checksum {
+		return errors.New("archive is corrupted, rows or checksum do not match the manifest")
+	}
+	return nil
+}
+
+// convert turns a json value into the type of the column it is restored to, whatever driver it was dumped from
+func convert(value interface{}, columnType string) (interface{}, error) {
+	switch value := value.(type) {
+	case json.Number:
+		if strings.Contains(columnType, "bool") || columnType == "bit" {
+			return value.String() != "0", nil
+		}
+		if integer, err := value.Int64(); err == nil {
+			return integer, nil
+		}
+		return value.Float64()
+	case bool:
+		if strings.Contains(columnType, "bool") || columnType == "bit" {
+			return value, nil
+		}
+		if value {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		if strings.Contains(columnType, "time") || strings.Contains(columnType, "date") {
+			if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				return parsed, nil
+			}
+		}
+		return value, nil
+	case map[string]interface{}, []interface{}:
+		// json columns are restored as their text
+		content, err := json.Marshal(value)
+		return string(content), err
+	}
+	return value, nil
+}
@@ -0,0 +1,153 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"path"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ericmarcelinotju/gram/plugins/database/migration"
+)
+
+type memoryStorage struct {
+	files fstest.MapFS
+}
+
+func (m *memoryStorage) Upload(file multipart.File, name string) error {
+	return m.UploadStream(file, name)
+}
+
+func (m *memoryStorage) UploadStream(reader io.Reader, name string) error {
+	data, err := io.ReadAll(reader)
+	m.files[name] = &fstest.MapFile{Data: data, ModTime: time.Now()}
+	return err
+}
+
+func (m *memoryStorage) Download(name string) ([]byte, error) {
+	return fs.ReadFile(m.files, name)
+}
+
+func (m *memoryStorage) DownloadStream(name string) (io.ReadCloser, error) {
+	return m.files.Open(name)
+}
+
+func (m *memoryStorage) Remove(name string) error {
+	delete(m.files, name)
+	return nil
+}
+
+func (m *memoryStorage) List(folder string) ([]fs.FileInfo, error) {
+	var infos []fs.FileInfo
+	for name := range m.files {
+		if path.Dir(name) == folder {
+			info, _ := fs.Stat(m.files, name)
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+func (m *memoryStorage) Path() string {
+	return ""
+}
+
+func openDatabase(t *testing.T, name string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name+".db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range []string{
+		`CREATE TABLE settings (name text PRIMARY KEY, value text)`,
+		`CREATE TABLE users (id text PRIMARY KEY, name text, active boolean, logins integer, last_login datetime, avatar text)`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, db.AutoMigrate(&migration.SchemaMigrationEntity{}), nil)
+	assert.Equal(t, db.Create(&migration.SchemaMigrationEntity{Version: 2, Name: "baseline"}).Error, nil)
+	return db
+}
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	source := openDatabase(t, "source")
+	lastLogin := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, source.Exec(`INSERT INTO settings VALUES ('smtp_host', 'localhost'), ('audit_retention', '{"users": 30}')`).Error, nil)
+	assert.Equal(t, source.Exec(`INSERT INTO users VALUES ('1', 'super', true, 3, ?, NULL), ('2', 'admin', false, 0, NULL, 'a.png')`, lastLogin).Error, nil)
+
+	store := &memoryStorage{files: fstest.MapFS{}}
+	filename, manifest, err := NewBackup(source, []string{"settings", "users"}).Create(ctx, store)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, manifest.SchemaVersion, int64(2))
+	assert.Equal(t, manifest.Tables[1].Rows, int64(2))
+
+	target := openDatabase(t, "target")
+	restorer := NewBackup(target, []string{"settings", "users"})
+	_, err = restorer.Restore(ctx, store, filename)
+	assert.Equal(t, err, nil)
+
+	type user struct {
+		Id        string
+		Name      string
+		Active    bool
+		Logins    int
+		LastLogin *time.Time
+		Avatar    *string
+	}
+	var users []user
+	assert.Equal(t, target.Table("users").Order("id").Find(&users).Error, nil)
+	assert.Equal(t, len(users), 2)
+	assert.Equal(t, users[0].Active, true)
+	assert.Equal(t, users[0].Logins, 3)
+	assert.Equal(t, users[0].LastLogin.Equal(lastLogin), true)
+	assert.Equal(t, users[0].Avatar == nil, true)
+	assert.Equal(t, *users[1].Avatar, "a.png")
+	var retention string
+	target.Table("settings").Where("name = ?", "audit_retention").Pluck("value", &retention)
+	assert.Equal(t, retention, `{"users": 30}`)
+
+	// restoring needs empty tables and the same schema version
+	_, err = restorer.Restore(ctx, store, filename)
+	assert.NotEqual(t, err, nil)
+	fresh := openDatabase(t, "fresh")
+	fresh.Create(&migration.SchemaMigrationEntity{Version: 3, Name: "later"})
+	_, err = NewBackup(fresh, []string{"settings", "users"}).Restore(ctx, store, filename)
+	assert.NotEqual(t, err, nil)
+
+	// a corrupted archive is rolled back
+	var archive bytes.Buffer
+	_, err = NewBackup(source, []string{"settings", "users"}).Dump(ctx, &archive)
+	assert.Equal(t, err, nil)
+	_, err = NewBackup(openDatabase(t, "other"), nil).Load(ctx, bytes.NewReader(archive.Bytes()[:archive.Len()/2]))
+	assert.NotEqual(t, err, nil)
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	name := func(created time.Time) string {
+		return path.Join(Folder, "backup-"+strconv.FormatInt(created.Unix(), 10)+".tar.gz")
+	}
+	store := &memoryStorage{files: fstest.MapFS{
+		name(now.AddDate(0, 0, -10)): {},
+		name(now.AddDate(0, 0, -1)):  {},
+		"backup/notes.txt":           {},
+	}}
+
+	removed, err := (&Backup{}).Prune(context.Background(), store, now.AddDate(0, 0, -7))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, removed, []string{name(now.AddDate(0, 0, -10))})
+	assert.Equal(t, len(store.files), 2)
+}
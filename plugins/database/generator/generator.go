@@ -0,0 +1,259 @@
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/ericmarcelinotju/gram/model"
+)
+
+// Options sets how much data is generated, the same seed against the same database generates the same data
+type Options struct {
+	Seed      int64
+	Users     int
+	Roles     int
+	Audits    int
+	BatchSize int
+	// Now ends the year of generated dates
+	Now time.Time
+}
+
+var firstNames = []string{
+	"Ada", "Alan", "Amara", "Bruno", "Chen", "Clara", "Diego", "Elena", "Farah", "Felix",
+	"Grace", "Hana", "Ivan", "Jonas", "Keiko", "Liam", "Lucia", "Mateo", "Maya", "Nadia",
+	"Noah", "Olga", "Omar", "Priya", "Rafael", "Sara", "Tariq", "Uma", "Viktor", "Yuki",
+}
+
+var lastNames = []string{
+	"Andersen", "Baker", "Costa", "Dubois", "Evans", "Fischer", "Garcia", "Hansen", "Ito", "Jovanovic",
+	"Kim", "Larsen", "Moreau", "Nakamura", "Okafor", "Petrov", "Quispe", "Rossi", "Santos", "Tanaka",
+	"Umarov", "Varga", "Wagner", "Xu", "Yilmaz", "Zhang",
+}
+
+var titles = []string{
+	"Engineer", "Senior Engineer", "Analyst", "Designer", "Product Manager", "Support Agent",
+	"Accountant", "Recruiter", "Sales Representative", "Operations Lead", "",
+}
+
+var roleNames = []string{
+	"Auditor", "Support", "Editor", "Viewer", "Operator", "Manager", "Reviewer", "Contributor",
+}
+
+// Generator inserts fake users, roles and audits for development and load tests
+type Generator struct {
+	db           *gorm.DB
+	options      Options
+	passwordHash string
+	random       *rand.Rand
+}
+
+// NewGenerator creates a generator giving every user passwordHash
+func NewGenerator(db *gorm.DB, options Options, passwordHash string) *Generator {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 500
+	}
+	return &Generator{
+		db:           db.Session(&gorm.Session{SkipHooks: true}),
+		options:      options,
+		passwordHash: passwordHash,
+		random:       rand.New(rand.NewSource(options.Seed)),
+	}
+}
+
+// Generate inserts the data in one transaction and returns the inserted rows per table.
+// Users are spread across the existing roles and the generated ones, generated roles
+// get a random subset of the existing permissions.
+func (g *Generator) Generate(ctx context.Context) (map[string]int64, error) {
+	results := make(map[string]int64)
+	err := g.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var permissions []model.PermissionEntity
+		if err := tx.Order("module, method").Find(&permissions).Error; err != nil {
+			return err
+		}
+
+		roles, err := g.generateRoles(tx, permissions)
+		if err != nil {
+			return fmt.Errorf("error when generating roles %w", err)
+		}
+		results["roles"] = int64(len(roles))
+
+		var existing []model.RoleEntity
+		if err := tx.Order("name").Find(&existing).Error; err != nil {
+			return err
+		}
+		if len(existing) == 0 && g.options.Users > 0 {
+			return errors.New("no roles to give the users, seed the database first")
+		}
+
+		users, err := g.generateUsers(tx, existing)
+		if err != nil {
+			return fmt.Errorf("error when generating users %w", err)
+		}
+		results["users"] = int64(len(users))
+
+		audits, err := g.generateAudits(tx, users, permissions)
+		if err != nil {
+			return fmt.Errorf("error when generating audits %w", err)
+		}
+		results["audits"] = int64(audits)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+func (g *Generator) generateRoles(tx *gorm.DB, permissions []model.PermissionEntity) ([]model.RoleEntity, error) {
+	if g.options.Roles <= 0 {
+		return nil, nil
+	}
+
+	roles := make([]model.RoleEntity, g.options.Roles)
+	var grants []map[string]interface{}
+	for i := range roles {
+		id := g.uuid()
+		createdAt := g.date()
+		roles[i] = model.RoleEntity{
+			Model:       model.Model{Id: id, CreatedAt: createdAt, UpdatedAt: createdAt, Version: 1},
+			Name:        fmt.Sprintf("%s %s", g.pick(roleNames), id.String()[:8]),
+			Description: "Generated role",
+			Level:       1 + g.random.Intn(5),
+		}
+		// each permission is granted with the same odds so roles range from narrow to broad
+		odds := g.random.Float64()
+		for _, permission := range permissions {
+			if g.random.Float64() < odds {
+				grants = append(grants, map[string]interface{}{
+					"role_entity_id":       id,
+					"permission_entity_id": permission.Id,
+				})
+			}
+		}
+	}
+
+	if err := tx.Omit("Permissions").CreateInBatches(roles, g.options.BatchSize).Error; err != nil {
+		return nil, err
+	}
+	if len(grants) > 0 {
+		if err := tx.Table("role_permissions").CreateInBatches(grants, g.options.BatchSize).Error; err != nil {
+			return nil, err
+		}
+	}
+	return roles, nil
+}
+
+func (g *Generator) generateUsers(tx *gorm.DB, roles []model.RoleEntity) ([]model.UserEntity, error) {
+	if g.options.Users <= 0 {
+		return nil, nil
+	}
+
+	users := make([]model.UserEntity, g.options.Users)
+	for i := range users {
+		id := g.uuid()
+		firstname := g.pick(firstNames)
+		lastname := g.pick(lastNames)
+		// the id keeps names unique however many users share a first and last name
+		name := strings.ToLower(fmt.Sprintf("%s.%s.%s", firstname, lastname, id.String()[:8]))
+		createdAt := g.date()
+
+		users[i] = model.UserEntity{
+			Model:     model.Model{Id: id, CreatedAt: createdAt, UpdatedAt: createdAt, Version: 1},
+			Name:      name,
+			Email:     name + "@example.com",
+			Password:  g.passwordHash,
+			Firstname: firstname,
+			Lastname:  lastname,
+			Title:     g.pick(titles),
+			RoleId:    roles[g.random.Intn(len(roles))].Id,
+		}
+		if g.random.Intn(4) > 0 {
+			lastLogin := createdAt.Add(time.Duration(g.random.Int63n(int64(g.options.Now.Sub(createdAt)) + 1)))
+			users[i].LastLogin = &lastLogin
+		}
+	}
+
+	if err := tx.Omit("Role").CreateInBatches(users, g.options.BatchSize).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// generateAudits records the creation and some updates and deletions of the generated users, made by random generated users
+func (g *Generator) generateAudits(tx *gorm.DB, users []model.UserEntity, permissions []model.PermissionEntity) (int, error) {
+	if g.options.Audits <= 0 || len(users) == 0 {
+		return 0, nil
+	}
+
+	audits := make([]*model.AuditEntity, g.options.Audits)
+	for i := range audits {
+		user := users[g.random.Intn(len(users))]
+		actor := users[g.random.Intn(len(users))]
+		value, _ := json.Marshal(map[string]interface{}{
+			"name":      user.Name,
+			"email":     user.Email,
+			"firstname": user.Firstname,
+			"lastname":  user.Lastname,
+			"title":     user.Title,
+		})
+
+		audit := &model.AuditEntity{
+			Id:         g.uuid(),
+			Date:       g.date(),
+			EntityName: "users",
+			EntityId:   user.Id.String(),
+			Origin:     "generator",
+			RequestId:  g.uuid().String(),
+			UserId:     actor.Id,
+		}
+		if len(permissions) > 0 {
+			audit.PermissionId = permissions[g.random.Intn(len(permissions))].Id
+		}
+		switch n := g.random.Intn(10); {
+		case n < 3:
+			audit.OperationType = "insert"
+			audit.NewValue = string(value)
+		case n < 9:
+			audit.OperationType = "update"
+			audit.OldValue = string(value)
+			audit.NewValue = string(value)
+		default:
+			audit.OperationType = "delete"
+			audit.OldValue = string(value)
+		}
+		audits[i] = audit
+	}
+	// the chain follows the dates like audits recorded as they happen
+	sort.SliceStable(audits, func(i, j int) bool {
+		return audits[i].Date.Before(audits[j].Date)
+	})
+
+	if err := model.CreateAudits(tx, audits, g.options.BatchSize); err != nil {
+		return 0, err
+	}
+	return len(audits), nil
+}
+
+func (g *Generator) uuid() uuid.UUID {
+	id, _ := uuid.NewRandomFromReader(g.random)
+	return id
+}
+
+func (g *Generator) pick(values []string) string {
+	return values[g.random.Intn(len(values))]
+}
+
+// date returns a date of the year before Now, truncated to the precision every driver stores
+func (g *Generator) date() time.Time {
+	year := int64(365 * 24 * time.Hour)
+	return g.options.Now.Add(-time.Duration(g.random.Int63n(year))).Truncate(time.Millisecond)
+}
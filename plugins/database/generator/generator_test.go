@@ -0,0 +1,94 @@
+package generator
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/ericmarcelinotju/gram/model"
+)
+
+func openDatabase(t *testing.T, name string) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name+".db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range []string{
+		`CREATE TABLE permission_entities (
+			id text PRIMARY KEY, created_at datetime, updated_at datetime, version integer NOT NULL DEFAULT 1,
+			method text, module text, description text, deleted_at datetime
+		)`,
+		`CREATE TABLE roles (
+			id text PRIMARY KEY, created_at datetime, updated_at datetime, version integer NOT NULL DEFAULT 1,
+			name text UNIQUE, description text, level integer, deleted_at datetime
+		)`,
+		`CREATE TABLE role_permissions (
+			role_entity_id text, permission_entity_id text, PRIMARY KEY (role_entity_id, permission_entity_id)
+		)`,
+		`CREATE TABLE users (
+			id text PRIMARY KEY, created_at datetime, updated_at datetime, version integer NOT NULL DEFAULT 1,
+			name text UNIQUE, email text UNIQUE, password text, firstname text, lastname text, title text, avatar text,
+			last_login datetime, role_id text, forgot_password_token text, deleted_at datetime
+		)`,
+		`CREATE TABLE audits (
+			id text PRIMARY KEY, sequence integer UNIQUE, date datetime, entity_name text, entity_id text,
+			old_value text, new_value text, operation_type text, origin text, request_id text,
+			user_id text, permission_id text, prev_hash text, hash text
+		)`,
+		`CREATE TABLE audit_tombstones (sequence integer PRIMARY KEY, hash text, archive_id text)`,
+		`INSERT INTO permission_entities (id, method, module) VALUES
+			('00000000-0000-0000-0000-000000000001', 'GET', 'USER'),
+			('00000000-0000-0000-0000-000000000002', 'POST', 'USER')`,
+		`INSERT INTO roles (id, name) VALUES ('00000000-0000-0000-0000-000000000003', 'Admin')`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func TestGenerate(t *testing.T) {
+	model.SetAuditKey("test-key")
+	options := Options{Seed: 42, Users: 30, Roles: 3, Audits: 50, BatchSize: 7, Now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	first := openDatabase(t, "first")
+	results, err := NewGenerator(first, options, "hash").Generate(context.Background())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, results, map[string]int64{"roles": 3, "users": 30, "audits": 50})
+
+	var count int64
+	first.Table("roles").Count(&count)
+	assert.Equal(t, count, int64(4))
+
+	// the audits continue a verifiable chain
+	var audits []model.AuditEntity
+	assert.Equal(t, first.Order("sequence").Find(&audits).Error, nil)
+	assert.Equal(t, len(audits), 50)
+	for i, audit := range audits {
+		assert.Equal(t, audit.Sequence, int64(i+1))
+		assert.Equal(t, audit.Hash, audit.ComputeHash())
+		if i > 0 {
+			assert.Equal(t, audit.PrevHash, audits[i-1].Hash)
+		}
+	}
+
+	// the same seed generates the same data
+	second := openDatabase(t, "second")
+	_, err = NewGenerator(second, options, "hash").Generate(context.Background())
+	assert.Equal(t, err, nil)
+	var firstUsers, secondUsers []model.UserEntity
+	first.Order("id").Find(&firstUsers)
+	second.Order("id").Find(&secondUsers)
+	assert.Equal(t, len(firstUsers), 30)
+	for i := range firstUsers {
+		assert.Equal(t, firstUsers[i].Name, secondUsers[i].Name)
+		assert.Equal(t, firstUsers[i].RoleId, secondUsers[i].RoleId)
+	}
+}
@@ -2,12 +2,84 @@ package database
 
 import (
 	"errors"
+	"fmt"
+	"log"
+	"time"
 
 	"github.com/ericmarcelinotju/gram/config"
+	"github.com/ericmarcelinotju/gram/model"
 	"gorm.io/gorm"
 )
 
+// maxConnectBackoff caps the wait between connection retries
+const maxConnectBackoff = 30 * time.Second
+
+// Connect opens the primary database and routes the reads to its replicas when there are any
 func Connect(configuration *config.Database) (*gorm.DB, error) {
+	db, err := open(configuration)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	register("primary", pool, nil)
+	if err := UseUuids(db, model.Entities...); err != nil {
+		return nil, err
+	}
+
+	if len(configuration.Replicas) > 0 {
+		replicas := make([]*Replica, len(configuration.Replicas))
+		for i, replicaConfiguration := range configuration.Replicas {
+			replicaDb, err := open(replicaConfiguration)
+			if err != nil {
+				return nil, fmt.Errorf("replica %s %w", replicaConfiguration.Host, err)
+			}
+			pool, err := replicaDb.DB()
+			if err != nil {
+				return nil, fmt.Errorf("replica %s %w", replicaConfiguration.Host, err)
+			}
+			replicas[i] = NewReplica(replicaConfiguration.Host+":"+replicaConfiguration.Port, pool)
+			register(replicas[i].Name, pool, replicas[i])
+		}
+
+		resolver := NewResolver(NewPolicy(configuration.ReplicaPolicy), replicas...)
+		if err := db.Use(resolver); err != nil {
+			return nil, err
+		}
+		if configuration.ReplicaCheckInterval > 0 {
+			resolver.Start(configuration.ReplicaCheckInterval)
+		}
+	}
+
+	if configuration.StatsInterval > 0 {
+		StartStatsLogger(configuration.StatsInterval)
+	}
+	return db, nil
+}
+
+// open connects with retries, waiting longer after each failure, and tunes the pool
+func open(configuration *config.Database) (*gorm.DB, error) {
+	backoff := configuration.ConnectBackoff
+	for attempt := 0; ; attempt++ {
+		db, err := dial(configuration)
+		if err == nil {
+			return db, configurePool(db, configuration)
+		}
+		if attempt >= configuration.ConnectRetries {
+			return nil, err
+		}
+
+		log.Printf("[DATABASE] : connecting to %s failed, retrying in %s : %s\n", configuration.Host, backoff, err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
+func dial(configuration *config.Database) (*gorm.DB, error) {
 	if configuration.Driver == "sqlite" {
 		return ConnectSqlite(configuration)
 	} else if configuration.Driver == "postgres" {
@@ -20,3 +92,28 @@ func Connect(configuration *config.Database) (*gorm.DB, error) {
 		return nil, errors.New("database driver unsupported")
 	}
 }
+
+func gormConfig(configuration *config.Database) *gorm.Config {
+	return &gorm.Config{Logger: NewSlowQueryLogger(configuration.SlowThreshold)}
+}
+
+// configurePool applies the pool limits, zero values keep the driver defaults
+func configurePool(db *gorm.DB, configuration *config.Database) error {
+	pool, err := db.DB()
+	if err != nil {
+		return err
+	}
+	if configuration.MaxOpenConns > 0 {
+		pool.SetMaxOpenConns(configuration.MaxOpenConns)
+	}
+	if configuration.MaxIdleConns > 0 {
+		pool.SetMaxIdleConns(configuration.MaxIdleConns)
+	}
+	if configuration.ConnMaxLifetime > 0 {
+		pool.SetConnMaxLifetime(configuration.ConnMaxLifetime)
+	}
+	if configuration.ConnMaxIdleTime > 0 {
+		pool.SetConnMaxIdleTime(configuration.ConnMaxIdleTime)
+	}
+	return nil
+}
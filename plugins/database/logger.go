@@ -0,0 +1,77 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"gorm.io/gorm/logger"
+
+	"github.com/ericmarcelinotju/gram/utils/request"
+)
+
+// slowQueryEntry is a structured slow query log line
+type slowQueryEntry struct {
+	Time      string  `json:"time"`
+	Type      string  `json:"type"`
+	RequestId string  `json:"request_id,omitempty"`
+	Latency   float64 `json:"latency_ms"`
+	Rows      int64   `json:"rows"`
+	SQL       string  `json:"sql"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// SlowQueryLogger is a gorm logger writing the queries slower than its threshold, other messages are discarded
+type SlowQueryLogger struct {
+	threshold time.Duration
+	writer    func() io.Writer
+}
+
+// NewSlowQueryLogger creates a logger of the queries slower than threshold, zero logs nothing
+func NewSlowQueryLogger(threshold time.Duration) *SlowQueryLogger {
+	return &SlowQueryLogger{threshold: threshold, writer: log.Writer}
+}
+
+func (l *SlowQueryLogger) LogMode(logger.LogLevel) logger.Interface {
+	return l
+}
+
+func (l *SlowQueryLogger) Info(context.Context, string, ...interface{}) {}
+
+func (l *SlowQueryLogger) Warn(context.Context, string, ...interface{}) {}
+
+func (l *SlowQueryLogger) Error(context.Context, string, ...interface{}) {}
+
+// ParamsFilter keeps the query parameters out of the log, they may hold passwords and personal data
+func (l *SlowQueryLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+func (l *SlowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	if l.threshold <= 0 || elapsed < l.threshold {
+		return
+	}
+
+	sql, rows := fc()
+	entry := slowQueryEntry{
+		Time:      begin.Format(time.RFC3339),
+		Type:      "slow_query",
+		RequestId: request.GetRequestId(ctx),
+		Latency:   float64(elapsed.Microseconds()) / 1000,
+		Rows:      rows,
+		SQL:       sql,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(l.writer(), string(line))
+}
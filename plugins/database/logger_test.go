@@ -0,0 +1,45 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+
+	"github.com/ericmarcelinotju/gram/utils/request"
+)
+
+func TestSlowQueryLogger(t *testing.T) {
+	var output bytes.Buffer
+	slowLogger := NewSlowQueryLogger(1)
+	slowLogger.writer = func() io.Writer { return &output }
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "logger.db")), &gorm.Config{Logger: slowLogger})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, db.AutoMigrate(&transactionItem{}), nil)
+
+	output.Reset()
+	ctx := request.WithRequestId(context.Background(), "request-1")
+	assert.Equal(t, db.WithContext(ctx).Create(&transactionItem{Name: "secret"}).Error, nil)
+
+	var entry slowQueryEntry
+	assert.Equal(t, json.Unmarshal(bytes.SplitN(output.Bytes(), []byte("\n"), 2)[0], &entry), nil)
+	assert.Equal(t, entry.Type, "slow_query")
+	assert.Equal(t, entry.RequestId, "request-1")
+	assert.Equal(t, entry.Rows, int64(1))
+	// parameters are not logged
+	assert.Equal(t, bytes.Contains(output.Bytes(), []byte("secret")), false)
+
+	output.Reset()
+	slowLogger.threshold = 0
+	db.Find(&[]transactionItem{})
+	assert.Equal(t, output.Len(), 0)
+}
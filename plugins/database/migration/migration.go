@@ -0,0 +1,185 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Dialects are the database drivers migration files are written for
+var Dialects = []string{"sqlite", "postgres", "mysql", "sqlserver"}
+
+// ErrIrreversible reports a rollback of a migration without a down step
+var ErrIrreversible = errors.New("migration has no down step")
+
+// Migration is a versioned schema change, either read from sql files or written in go
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+	// Checksum of the up and down sql, go migrations have none and are never reported as changed
+	Checksum string
+	// upChecksum is the checksum of the up sql alone, recorded by the runs before the down sql was checksummed
+	upChecksum string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Load reads the sql migrations of a dialect from files, named <version>_<name>.up.sql and <version>_<name>.down.sql,
+// and merges them with the go migrations sorted by version
+func Load(files fs.FS, dialect string, migrations ...Migration) ([]Migration, error) {
+	byVersion := make(map[int64]*Migration, len(migrations))
+	for i := range migrations {
+		migration := migrations[i]
+		if _, ok := byVersion[migration.Version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d", migration.Version)
+		}
+		byVersion[migration.Version] = &migration
+	}
+
+	if files != nil {
+		entries, err := fs.ReadDir(files, dialect)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		sqlVersions := make(map[int64]bool)
+		downs := make(map[int64][]byte)
+		ups := make(map[int64][]byte)
+		for _, entry := range entries {
+			match := fileName.FindStringSubmatch(entry.Name())
+			if entry.IsDir() || match == nil {
+				continue
+			}
+			version, err := strconv.ParseInt(match[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+			}
+
+			migration, ok := byVersion[version]
+			if ok && !sqlVersions[version] {
+				return nil, fmt.Errorf("duplicate migration version %d", version)
+			}
+			if !ok {
+				migration = &Migration{Version: version, Name: match[2]}
+				byVersion[version] = migration
+				sqlVersions[version] = true
+			}
+			if migration.Name != match[2] {
+				return nil, fmt.Errorf("migration %d is named both %s and %s", version, migration.Name, match[2])
+			}
+
+			content, err := fs.ReadFile(files, path.Join(dialect, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			statements := splitStatements(string(content))
+			if match[3] == "up" {
+				ups[version] = content
+				migration.Up = execStatements(statements)
+			} else {
+				downs[version] = content
+				migration.Down = execStatements(statements)
+			}
+		}
+		for version, up := range ups {
+			byVersion[version].Checksum = checksum(up, downs[version])
+			byVersion[version].upChecksum = checksum(up, nil)
+		}
+	}
+
+	results := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up step", migration.Version, migration.Name)
+		}
+		results = append(results, *migration)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Version < results[j].Version
+	})
+	return results, nil
+}
+
+// checksum hashes the up sql followed by the down sql, a migration without down sql keeps the hash of its up sql
+func checksum(up, down []byte) string {
+	hash := sha256.New()
+	hash.Write(up)
+	if down != nil {
+		hash.Write([]byte{0})
+		hash.Write(down)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// splitStatements splits sql on the lines ending with a semicolon, so drivers without multi statement support can run it
+func splitStatements(sql string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+func execStatements(statements []string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Create writes empty up and down files of a new migration for every dialect and returns their paths
+func Create(dir string, name string, now time.Time) ([]string, error) {
+	name = strings.Trim(nonWord.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name is empty")
+	}
+	version := now.UTC().Format("20060102150405")
+
+	var paths []string
+	for _, dialect := range Dialects {
+		if err := os.MkdirAll(filepath.Join(dir, dialect), 0755); err != nil {
+			return paths, err
+		}
+		for _, direction := range []string{"up", "down"} {
+			filePath := filepath.Join(dir, dialect, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+			content := fmt.Sprintf("-- %s %s (%s)\n", name, direction, dialect)
+			if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+				return paths, err
+			}
+			paths = append(paths, filePath)
+		}
+	}
+	return paths, nil
+}
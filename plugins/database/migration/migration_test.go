@@ -0,0 +1,143 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+func TestMigrator(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "migration.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	files := fstest.MapFS{
+		"sqlite/1_items.up.sql":        {Data: []byte("CREATE TABLE items (id integer PRIMARY KEY);\nCREATE INDEX idx_items ON items (id);\n")},
+		"sqlite/1_items.down.sql":      {Data: []byte("DROP TABLE items;\n")},
+		"sqlite/3_item_name.up.sql":    {Data: []byte("ALTER TABLE items ADD COLUMN name text;\n")},
+		"sqlite/3_item_name.down.sql":  {Data: []byte("ALTER TABLE items DROP COLUMN name;\n")},
+		"postgres/2_ignored.up.sql":    {Data: []byte("SELECT 1;\n")},
+		"postgres/2_ignored.down.sql":  {Data: []byte("SELECT 1;\n")},
+		"sqlite/notes.txt":             {Data: []byte("not a migration")},
+		"sqlite/4_irreversible.up.sql": {Data: []byte("INSERT INTO items (id, name) VALUES (1, 'a');\n")},
+	}
+	seeded := Migration{Version: 2, Name: "seed", Up: func(tx *gorm.DB) error {
+		return tx.Exec("INSERT INTO items (id) VALUES (2)").Error
+	}, Down: func(tx *gorm.DB) error {
+		return tx.Exec("DELETE FROM items WHERE id = 2").Error
+	}}
+
+	migrator, err := NewMigrator(db, files, seeded)
+	assert.Equal(t, err, nil)
+
+	applied, err := migrator.Up(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(applied), 4)
+	assert.Equal(t, db.Migrator().HasColumn("items", "name"), true)
+
+	// the last migration has no down step
+	_, err = migrator.Down(ctx, 1)
+	assert.Equal(t, errors.Is(err, ErrIrreversible), true)
+
+	delete(files, "sqlite/4_irreversible.up.sql")
+	migrator, err = NewMigrator(db, files, seeded)
+	assert.Equal(t, err, nil)
+	statuses, err := migrator.Status(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(statuses), 4)
+	assert.Equal(t, statuses[3].Missing, true)
+
+	db.Delete(&SchemaMigrationEntity{}, 4)
+	rolledBack, err := migrator.Down(ctx, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, rolledBack[0].Version, int64(3))
+	assert.Equal(t, rolledBack[1].Version, int64(2))
+	assert.Equal(t, db.Migrator().HasColumn("items", "name"), false)
+
+	redone, err := migrator.Redo(ctx)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, redone.Version, int64(1))
+
+	// an applied migration may not change
+	files["sqlite/1_items.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE items (id integer);\n")}
+	migrator, err = NewMigrator(db, files, seeded)
+	assert.Equal(t, err, nil)
+	_, err = migrator.Up(ctx)
+	assert.NotEqual(t, err, nil)
+	statuses, _ = migrator.Status(ctx)
+	assert.Equal(t, statuses[0].Changed, true)
+
+	// a held lock blocks other runs until it times out
+	assert.Equal(t, db.Create(&SchemaLockEntity{Id: 1, Owner: "other", LockedAt: time.Now()}).Error, nil)
+	_, err = migrator.Down(ctx, 1)
+	assert.Equal(t, errors.Is(err, ErrLocked), true)
+	db.Model(&SchemaLockEntity{}).Where("id = 1").Update("locked_at", time.Now().Add(-2*LockTimeout))
+	_, err = migrator.Down(ctx, 1)
+	assert.Equal(t, err, nil)
+
+	// a run longer than the timeout keeps its lock fresh
+	timeout := LockTimeout
+	LockTimeout = 30 * time.Millisecond
+	defer func() { LockTimeout = timeout }()
+	err = migrator.locked(ctx, func(db *gorm.DB) error {
+		start := time.Now()
+		time.Sleep(100 * time.Millisecond)
+		var lock SchemaLockEntity
+		assert.Equal(t, db.First(&lock, 1).Error, nil)
+		assert.Equal(t, lock.LockedAt.After(start), true)
+		return nil
+	})
+	assert.Equal(t, err, nil)
+}
+
+func TestChecksum(t *testing.T) {
+	files := fstest.MapFS{
+		"sqlite/1_items.up.sql":   {Data: []byte("CREATE TABLE items (id integer);\n")},
+		"sqlite/1_items.down.sql": {Data: []byte("DROP TABLE items;\n")},
+	}
+	applied, err := Load(files, "sqlite")
+	assert.Equal(t, err, nil)
+
+	files["sqlite/1_items.down.sql"] = &fstest.MapFile{Data: []byte("DELETE FROM items;\n")}
+	loaded, err := Load(files, "sqlite")
+	assert.Equal(t, err, nil)
+
+	// the down sql is part of the checksum
+	assert.Equal(t, changed(loaded[0], SchemaMigrationEntity{Checksum: applied[0].Checksum}), true)
+	// a checksum of the up sql alone still matches
+	assert.Equal(t, changed(loaded[0], SchemaMigrationEntity{Checksum: applied[0].upChecksum}), false)
+}
+
+func TestLoad(t *testing.T) {
+	_, err := Load(fstest.MapFS{"sqlite/1_a.down.sql": {Data: []byte("")}}, "sqlite")
+	assert.NotEqual(t, err, nil)
+
+	_, err = Load(fstest.MapFS{"sqlite/1_a.up.sql": {Data: []byte("")}}, "sqlite", Migration{Version: 1, Name: "b", Up: func(*gorm.DB) error { return nil }})
+	assert.NotEqual(t, err, nil)
+
+	assert.Equal(t, splitStatements("-- comment\nCREATE TABLE a (\n  id int\n);\n\nDROP TABLE b;\nSELECT 1"), []string{
+		"CREATE TABLE a (\n  id int\n);",
+		"DROP TABLE b;",
+		"SELECT 1",
+	})
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	paths, err := Create(dir, "Add user Phone", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(paths), len(Dialects)*2)
+	assert.Equal(t, paths[0], filepath.Join(dir, "sqlite", "20260102030405_add_user_phone.up.sql"))
+
+	_, err = Create(dir, "--", time.Now())
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,318 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LockTimeout is how long a lock is held before another run may take it over, so a crashed run does not block forever
+var LockTimeout = 15 * time.Minute
+
+// ErrLocked reports a run started while another one holds the lock
+var ErrLocked = errors.New("migrations are locked by another run")
+
+// SchemaMigrationEntity records an applied migration
+type SchemaMigrationEntity struct {
+	Version   int64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+func (SchemaMigrationEntity) TableName() string {
+	return "schema_migrations"
+}
+
+// SchemaLockEntity is the single row held by the running migration
+type SchemaLockEntity struct {
+	Id       int `gorm:"primaryKey;autoIncrement:false"`
+	Owner    string
+	LockedAt time.Time
+}
+
+func (SchemaLockEntity) TableName() string {
+	return "schema_migrations_lock"
+}
+
+// Status is a migration with its applied state
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt *time.Time
+	// Changed marks an applied migration whose sql differs from the applied one
+	Changed bool
+	// Missing marks an applied version that has no migration anymore
+	Missing bool
+}
+
+// Migrator applies and rolls back the migrations of a database
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the sql migrations of the database dialect from files together with the go migrations
+func NewMigrator(db *gorm.DB, files fs.FS, migrations ...Migration) (*Migrator, error) {
+	loaded, err := Load(files, db.Dialector.Name(), migrations...)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: loaded}, nil
+}
+
+// Up applies every pending migration in order and returns the applied ones
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		records, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if record, ok := records[migration.Version]; ok {
+				if changed(migration, record) {
+					return fmt.Errorf("applied migration %d_%s has changed", migration.Version, migration.Name)
+				}
+				if record.Checksum != migration.Checksum {
+					// recorded with the checksum of the up sql alone, the down sql is trusted from now on
+					if err := db.Model(&record).Update("checksum", migration.Checksum).Error; err != nil {
+						return err
+					}
+				}
+				continue
+			}
+			if err := m.apply(db, migration); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the last n applied migrations and returns the rolled back ones
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var rolledBack []Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		last, err := m.last(db, n)
+		if err != nil {
+			return err
+		}
+		for _, migration := range last {
+			if err := m.rollback(db, migration); err != nil {
+				return err
+			}
+			rolledBack = append(rolledBack, migration)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
+
+// Redo rolls back the last applied migration and applies it again
+func (m *Migrator) Redo(ctx context.Context) (*Migration, error) {
+	var redone *Migration
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		last, err := m.last(db, 1)
+		if err != nil {
+			return err
+		}
+		if len(last) == 0 {
+			return errors.New("no migration has been applied")
+		}
+		if err := m.rollback(db, last[0]); err != nil {
+			return err
+		}
+		if err := m.apply(db, last[0]); err != nil {
+			return err
+		}
+		redone = &last[0]
+		return nil
+	})
+	return redone, err
+}
+
+// Status lists every migration with its applied state, including applied versions that are missing
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	db := m.db.WithContext(ctx)
+	if err := db.AutoMigrate(&SchemaMigrationEntity{}); err != nil {
+		return nil, err
+	}
+	records, err := m.applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Status
+	for _, migration := range m.migrations {
+		status := Status{Migration: migration}
+		if record, ok := records[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Changed = changed(migration, record)
+			delete(records, migration.Version)
+		}
+		results = append(results, status)
+	}
+	for _, record := range records {
+		appliedAt := record.AppliedAt
+		results = append(results, Status{
+			Migration: Migration{Version: record.Version, Name: record.Name, Checksum: record.Checksum},
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Version < results[j].Version
+	})
+	return results, nil
+}
+
+// CurrentVersion returns the latest applied version, zero when none is
+func CurrentVersion(db *gorm.DB) (int64, error) {
+	if !db.Migrator().HasTable(&SchemaMigrationEntity{}) {
+		return 0, nil
+	}
+	var versions []int64
+	if err := db.Model(&SchemaMigrationEntity{}).Order("version DESC").Limit(1).Pluck("version", &versions).Error; err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, nil
+	}
+	return versions[0], nil
+}
+
+// locked runs fn while holding the migration lock
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB) error) error {
+	db := m.db.WithContext(ctx)
+	if err := db.AutoMigrate(&SchemaMigrationEntity{}, &SchemaLockEntity{}); err != nil {
+		return err
+	}
+
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d:%s", host, os.Getpid(), uuid.NewString())
+
+	if err := db.Where("locked_at < ?", time.Now().Add(-LockTimeout)).Delete(&SchemaLockEntity{}).Error; err != nil {
+		return err
+	}
+	// the primary key lets only one run insert the lock
+	if err := db.Create(&SchemaLockEntity{Id: 1, Owner: owner, LockedAt: time.Now()}).Error; err != nil {
+		var lock SchemaLockEntity
+		if db.First(&lock, 1).Error == nil {
+			return fmt.Errorf("%w since %s by %s", ErrLocked, lock.LockedAt.Format(time.RFC3339), lock.Owner)
+		}
+		return err
+	}
+	defer db.Where("id = ? AND owner = ?", 1, owner).Delete(&SchemaLockEntity{})
+
+	done := make(chan struct{})
+	defer close(done)
+	go heartbeat(db, owner, done)
+
+	return fn(db)
+}
+
+// heartbeat refreshes the lock of owner until done, so a run longer than LockTimeout is not taken over while alive
+func heartbeat(db *gorm.DB, owner string, done <-chan struct{}) {
+	ticker := time.NewTicker(LockTimeout / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			db.Model(&SchemaLockEntity{}).Where("id = ? AND owner = ?", 1, owner).Update("locked_at", time.Now())
+		}
+	}
+}
+
+// changed reports an applied migration whose sql differs from the recorded checksum,
+// the checksum of the up sql alone recorded by earlier runs still matches
+func changed(migration Migration, record SchemaMigrationEntity) bool {
+	if migration.Checksum == "" {
+		return false
+	}
+	return record.Checksum != migration.Checksum && record.Checksum != migration.upChecksum
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int64]SchemaMigrationEntity, error) {
+	var records []SchemaMigrationEntity
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	results := make(map[int64]SchemaMigrationEntity, len(records))
+	for _, record := range records {
+		results[record.Version] = record
+	}
+	return results, nil
+}
+
+// last returns the last n applied migrations, latest first
+func (m *Migrator) last(db *gorm.DB, n int) ([]Migration, error) {
+	var records []SchemaMigrationEntity
+	if err := db.Order("version DESC").Limit(n).Find(&records).Error; err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	results := make([]Migration, len(records))
+	for i, record := range records {
+		migration, ok := byVersion[record.Version]
+		if !ok {
+			return nil, fmt.Errorf("applied migration %d_%s is missing", record.Version, record.Name)
+		}
+		results[i] = migration
+	}
+	return results, nil
+}
+
+// apply runs the up step and records it in one transaction
+func (m *Migrator) apply(db *gorm.DB, migration Migration) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Up(tx); err != nil {
+			return err
+		}
+		return tx.Create(&SchemaMigrationEntity{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Checksum:  migration.Checksum,
+			AppliedAt: time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("error when applying migration %d_%s %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+// rollback runs the down step and forgets it in one transaction
+func (m *Migrator) rollback(db *gorm.DB, migration Migration) error {
+	if migration.Down == nil {
+		return fmt.Errorf("error when rolling back migration %d_%s %w", migration.Version, migration.Name, ErrIrreversible)
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := migration.Down(tx); err != nil {
+			return err
+		}
+		return tx.Delete(&SchemaMigrationEntity{}, migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("error when rolling back migration %d_%s %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
@@ -6,7 +6,6 @@ import (
 	"github.com/ericmarcelinotju/gram/config"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func ConnectMysql(configuration *config.Database) (*gorm.DB, error) {
@@ -18,7 +17,7 @@ func ConnectMysql(configuration *config.Database) (*gorm.DB, error) {
 		configuration.Port,
 		configuration.DB,
 	)
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	db, err := gorm.Open(mysql.Open(dsn), gormConfig(configuration))
 
 	if err != nil {
 		return nil, err
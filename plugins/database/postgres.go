@@ -6,7 +6,6 @@ import (
 	"github.com/ericmarcelinotju/gram/config"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func ConnectPostgres(configuration *config.Database) (*gorm.DB, error) {
@@ -18,7 +17,7 @@ func ConnectPostgres(configuration *config.Database) (*gorm.DB, error) {
 		configuration.DB,
 		configuration.Port,
 	)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	db, err := gorm.Open(postgres.Open(dsn), gormConfig(configuration))
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,222 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StickyKey is the context key of the Sticky marker of a request
+const StickyKey = "database-sticky"
+
+const primaryKey = "database-primary"
+
+// Sticky marks a request that has written, its later reads go to the primary so it reads its own writes
+type Sticky struct {
+	written atomic.Bool
+}
+
+// Written reports whether the request has written
+func (s *Sticky) Written() bool {
+	return s.written.Load()
+}
+
+// WithSticky returns a copy of ctx whose reads stick to the primary after its first write
+func WithSticky(ctx context.Context) context.Context {
+	return context.WithValue(ctx, StickyKey, &Sticky{})
+}
+
+// UsePrimary returns a copy of ctx whose reads always go to the primary
+func UsePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// Replica is a read only connection pool and its health
+type Replica struct {
+	Name    string
+	pool    *sql.DB
+	healthy atomic.Bool
+}
+
+// NewReplica wraps the pool of a replica, replicas start healthy
+func NewReplica(name string, pool *sql.DB) *Replica {
+	replica := &Replica{Name: name, pool: pool}
+	replica.healthy.Store(true)
+	return replica
+}
+
+// Healthy reports whether the last ping of the replica succeeded
+func (r *Replica) Healthy() bool {
+	return r.healthy.Load()
+}
+
+// Policy picks the replica of a read among the healthy ones
+type Policy interface {
+	Pick(replicas []*Replica) *Replica
+}
+
+// RoundRobinPolicy cycles through the replicas
+type RoundRobinPolicy struct {
+	next atomic.Uint64
+}
+
+func (p *RoundRobinPolicy) Pick(replicas []*Replica) *Replica {
+	return replicas[(p.next.Add(1)-1)%uint64(len(replicas))]
+}
+
+// RandomPolicy picks a random replica
+type RandomPolicy struct{}
+
+func (RandomPolicy) Pick(replicas []*Replica) *Replica {
+	return replicas[rand.Intn(len(replicas))]
+}
+
+// LeastConnectionsPolicy picks the replica with the fewest connections in use
+type LeastConnectionsPolicy struct{}
+
+func (LeastConnectionsPolicy) Pick(replicas []*Replica) *Replica {
+	picked := replicas[0]
+	for _, replica := range replicas[1:] {
+		if replica.pool.Stats().InUse < picked.pool.Stats().InUse {
+			picked = replica
+		}
+	}
+	return picked
+}
+
+// NewPolicy returns the policy of a name, round robin when the name is unknown
+func NewPolicy(name string) Policy {
+	switch name {
+	case "random":
+		return RandomPolicy{}
+	case "least-connections":
+		return LeastConnectionsPolicy{}
+	default:
+		return &RoundRobinPolicy{}
+	}
+}
+
+// Resolver is a gorm plugin sending the reads outside of transactions to the replicas and everything else to the primary
+type Resolver struct {
+	replicas []*Replica
+	policy   Policy
+	primary  gorm.ConnPool
+	stop     chan struct{}
+}
+
+// NewResolver creates a resolver balancing the reads between replicas with policy
+func NewResolver(policy Policy, replicas ...*Replica) *Resolver {
+	return &Resolver{replicas: replicas, policy: policy}
+}
+
+func (r *Resolver) Name() string {
+	return "gram:resolver"
+}
+
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	r.primary = db.ConnPool
+
+	if err := db.Callback().Query().Before("gorm:query").Register("gram:read", r.read); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("gram:read", r.read); err != nil {
+		return err
+	}
+	if err := db.Callback().Create().Before("gorm:begin_transaction").Register("gram:write", r.write); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:begin_transaction").Register("gram:write", r.write); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:begin_transaction").Register("gram:write", r.write); err != nil {
+		return err
+	}
+	return db.Callback().Raw().Before("gorm:raw").Register("gram:write", r.write)
+}
+
+// read routes a statement to a replica unless it runs in a transaction, the request has written or asked for the primary
+func (r *Resolver) read(db *gorm.DB) {
+	if _, ok := db.Statement.ConnPool.(*sql.DB); !ok {
+		return
+	}
+	ctx := db.Statement.Context
+	if primary, _ := ctx.Value(primaryKey).(bool); primary {
+		return
+	}
+	if sticky, ok := ctx.Value(StickyKey).(*Sticky); ok && sticky.Written() {
+		return
+	}
+	if replica := r.pick(); replica != nil {
+		db.Statement.ConnPool = replica.pool
+	}
+}
+
+// write routes a statement back to the primary when its chain has read from a replica and marks the request as written
+func (r *Resolver) write(db *gorm.DB) {
+	if pool, ok := db.Statement.ConnPool.(*sql.DB); ok && r.isReplica(pool) {
+		db.Statement.ConnPool = r.primary
+	}
+	if sticky, ok := db.Statement.Context.Value(StickyKey).(*Sticky); ok {
+		sticky.written.Store(true)
+	}
+}
+
+func (r *Resolver) isReplica(pool *sql.DB) bool {
+	for _, replica := range r.replicas {
+		if replica.pool == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns a healthy replica, nil falls back to the primary
+func (r *Resolver) pick() *Replica {
+	healthy := make([]*Replica, 0, len(r.replicas))
+	for _, replica := range r.replicas {
+		if replica.Healthy() {
+			healthy = append(healthy, replica)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+	return r.policy.Pick(healthy)
+}
+
+// Check pings every replica and records its health
+func (r *Resolver) Check(ctx context.Context, timeout time.Duration) {
+	for _, replica := range r.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		replica.healthy.Store(replica.pool.PingContext(pingCtx) == nil)
+		cancel()
+	}
+}
+
+// Start checks the health of the replicas every interval until Stop is called
+func (r *Resolver) Start(interval time.Duration) {
+	r.stop = make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.Check(context.Background(), interval)
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the health checks
+func (r *Resolver) Stop() {
+	if r.stop != nil {
+		close(r.stop)
+	}
+}
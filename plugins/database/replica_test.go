@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+type replicaItem struct {
+	Name string
+}
+
+func TestResolver(t *testing.T) {
+	open := func(name string) *gorm.DB {
+		db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), name+".db")), &gorm.Config{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, db.AutoMigrate(&replicaItem{}), nil)
+		assert.Equal(t, db.Create(&replicaItem{Name: name}).Error, nil)
+		return db
+	}
+	db := open("primary")
+	replicaPool, err := open("replica").DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := NewResolver(NewPolicy(""), NewReplica("replica", replicaPool))
+	assert.Equal(t, db.Use(resolver), nil)
+
+	names := func(ctx context.Context, db *gorm.DB) []string {
+		var names []string
+		assert.Equal(t, WithContext(ctx, db).Model(&replicaItem{}).Order("name").Pluck("name", &names).Error, nil)
+		return names
+	}
+
+	ctx := WithSticky(context.Background())
+	assert.Equal(t, names(ctx, db), []string{"replica"})
+	assert.Equal(t, names(UsePrimary(context.Background()), db), []string{"primary"})
+
+	// transactions stay on the primary
+	err = NewUnitOfWork(db).Do(context.Background(), func(ctx context.Context) error {
+		assert.Equal(t, names(ctx, db), []string{"primary"})
+		return nil
+	})
+	assert.Equal(t, err, nil)
+
+	// a request reads its own writes
+	assert.Equal(t, db.WithContext(ctx).Create(&replicaItem{Name: "written"}).Error, nil)
+	assert.Equal(t, names(ctx, db), []string{"primary", "written"})
+	assert.Equal(t, names(context.Background(), db), []string{"replica"})
+
+	// unhealthy replicas fall back to the primary
+	replicaPool.Close()
+	resolver.Check(context.Background(), time.Second)
+	assert.Equal(t, names(context.Background(), db), []string{"primary", "written"})
+}
+
+func TestPolicy(t *testing.T) {
+	replicas := []*Replica{NewReplica("a", nil), NewReplica("b", nil)}
+	policy := NewPolicy("round-robin")
+	assert.Equal(t, policy.Pick(replicas).Name, "a")
+	assert.Equal(t, policy.Pick(replicas).Name, "b")
+	assert.Equal(t, policy.Pick(replicas).Name, "a")
+}
@@ -0,0 +1,187 @@
+package scrub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// Strategies replacing a column value
+const (
+	Username  = "username"
+	Email     = "email"
+	FirstName = "first_name"
+	LastName  = "last_name"
+	FullName  = "full_name"
+	Password  = "password"
+	Blank     = "blank"
+	Null      = "null"
+)
+
+// batchSize is how many rows are rewritten per transaction
+const batchSize = 500
+
+// Rules maps a table to the strategy of each of its personal data columns
+type Rules map[string]map[string]string
+
+// DefaultRules scrub the personal data of users, the rows of their imports and the values recorded by audits
+var DefaultRules = Rules{
+	"users": {
+		"name":                  Username,
+		"email":                 Email,
+		"firstname":             FirstName,
+		"lastname":              LastName,
+		"title":                 Blank,
+		"avatar":                Null,
+		"password":              Password,
+		"forgot_password_token": Null,
+	},
+	"user_imports": {
+		"rows": Blank,
+	},
+	"audits": {
+		"old_value": Blank,
+		"new_value": Blank,
+	},
+}
+
+// LoadRules reads rules from a yaml or json file
+func LoadRules(path string) (Rules, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules Rules
+	if err := yaml.Unmarshal(content, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+var firstNames = []string{
+	"Alex", "Blake", "Casey", "Dana", "Eli", "Finley", "Gray", "Harper", "Indy", "Jordan",
+	"Kai", "Logan", "Morgan", "Noa", "Oakley", "Parker", "Quinn", "Reese", "Sage", "Taylor",
+}
+
+var lastNames = []string{
+	"Abbott", "Barnes", "Carter", "Dalton", "Ellis", "Fisher", "Garner", "Hayes", "Irwin", "Jensen",
+	"Keller", "Lawson", "Mercer", "Norris", "Owens", "Porter", "Quincy", "Rhodes", "Sutton", "Turner",
+}
+
+// Scrubber rewrites the personal data of a database with fake values derived from the row ids,
+// so the same row is always scrubbed the same way
+type Scrubber struct {
+	db           *gorm.DB
+	rules        Rules
+	passwordHash string
+}
+
+// NewScrubber creates a scrubber setting every password strategy to passwordHash
+func NewScrubber(db *gorm.DB, rules Rules, passwordHash string) *Scrubber {
+	return &Scrubber{db: db.Session(&gorm.Session{SkipHooks: true}), rules: rules, passwordHash: passwordHash}
+}
+
+// Scrub rewrites the columns of every table of the rules and returns the rewritten rows per table.
+// Tables must have an id column.
+func (s *Scrubber) Scrub(ctx context.Context) (map[string]int64, error) {
+	db := s.db.WithContext(ctx)
+
+	tables := make([]string, 0, len(s.rules))
+	for table, columns := range s.rules {
+		for column, strategy := range columns {
+			if _, err := s.value(strategy, ""); err != nil {
+				return nil, fmt.Errorf("%s.%s %w", table, column, err)
+			}
+			if !db.Migrator().HasColumn(table, column) {
+				return nil, fmt.Errorf("%s.%s does not exist", table, column)
+			}
+		}
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	results := make(map[string]int64, len(tables))
+	for _, table := range tables {
+		total, err := s.scrubTable(db, table, s.rules[table])
+		if err != nil {
+			return results, fmt.Errorf("error when scrubbing %s %w", table, err)
+		}
+		results[table] = total
+	}
+	return results, nil
+}
+
+func (s *Scrubber) scrubTable(db *gorm.DB, table string, columns map[string]string) (int64, error) {
+	var total int64
+	last := ""
+	for {
+		var ids []string
+		if err := db.Table(table).Where("id > ?", last).Order("id").Limit(batchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			for _, id := range ids {
+				values := make(map[string]interface{}, len(columns))
+				for column, strategy := range columns {
+					value, err := s.value(strategy, id)
+					if err != nil {
+						return err
+					}
+					values[column] = value
+				}
+				if err := tx.Table(table).Where("id = ?", id).Updates(values).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+		total += int64(len(ids))
+		last = ids[len(ids)-1]
+	}
+}
+
+// value returns the fake value of a strategy for a row id
+func (s *Scrubber) value(strategy, id string) (interface{}, error) {
+	hash := sha256.Sum256([]byte(id))
+	tag := hex.EncodeToString(hash[:6])
+	index := binary.BigEndian.Uint64(hash[8:16])
+	firstName := firstNames[index%uint64(len(firstNames))]
+	lastName := lastNames[(index/uint64(len(firstNames)))%uint64(len(lastNames))]
+
+	switch strategy {
+	case Username:
+		return "user-" + tag, nil
+	case Email:
+		return "user-" + tag + "@example.com", nil
+	case FirstName:
+		return firstName, nil
+	case LastName:
+		return lastName, nil
+	case FullName:
+		return firstName + " " + lastName, nil
+	case Password:
+		return s.passwordHash, nil
+	case Blank:
+		return "", nil
+	case Null:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown scrub strategy '%s', expected one of %s", strategy, strings.Join([]string{
+		Username, Email, FirstName, LastName, FullName, Password, Blank, Null,
+	}, ", "))
+}
@@ -0,0 +1,71 @@
+package scrub
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestScrub(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "scrub.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range []string{
+		`CREATE TABLE users (id text PRIMARY KEY, name text, email text, firstname text, password text, avatar text)`,
+		`CREATE TABLE audits (id text PRIMARY KEY, old_value text, new_value text)`,
+		`INSERT INTO users VALUES ('1', 'super', 'super@gram.io', 'Eric', 'secret', 'a.png'), ('2', 'admin', 'admin@gram.io', 'Ana', 'secret', NULL)`,
+		`INSERT INTO audits VALUES ('1', '{"name": "super"}', '{"name": "eric"}')`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := Rules{
+		"users":  {"name": Username, "email": Email, "firstname": FirstName, "password": Password, "avatar": Null},
+		"audits": {"old_value": Blank, "new_value": Blank},
+	}
+	results, err := NewScrubber(db, rules, "hash").Scrub(context.Background())
+	assert.Equal(t, err, nil)
+	assert.Equal(t, results["users"], int64(2))
+	assert.Equal(t, results["audits"], int64(1))
+
+	type user struct {
+		Id        string
+		Name      string
+		Email     string
+		Firstname string
+		Password  string
+		Avatar    *string
+	}
+	var users []user
+	assert.Equal(t, db.Table("users").Order("id").Find(&users).Error, nil)
+	assert.NotEqual(t, users[0].Name, "super")
+	assert.NotEqual(t, users[0].Name, users[1].Name)
+	assert.Equal(t, users[0].Email, users[0].Name+"@example.com")
+	assert.Equal(t, users[0].Password, "hash")
+	assert.Equal(t, users[0].Avatar == nil, true)
+
+	// scrubbing again gives the same values
+	_, err = NewScrubber(db, rules, "hash").Scrub(context.Background())
+	assert.Equal(t, err, nil)
+	var again []user
+	db.Table("users").Order("id").Find(&again)
+	assert.Equal(t, again, users)
+
+	var values []string
+	db.Table("audits").Pluck("old_value", &values)
+	assert.Equal(t, values, []string{""})
+
+	// unknown strategies and columns are refused before anything is written
+	_, err = NewScrubber(db, Rules{"users": {"name": "shuffle"}}, "hash").Scrub(context.Background())
+	assert.NotEqual(t, err, nil)
+	_, err = NewScrubber(db, Rules{"users": {"phone": Blank}}, "hash").Scrub(context.Background())
+	assert.NotEqual(t, err, nil)
+}
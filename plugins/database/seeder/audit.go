@@ -13,10 +13,22 @@ func NewAuditSeederService(db *gorm.DB) *AuditSeederService {
 	return &AuditSeederService{db: db}
 }
 
-func (s *AuditSeederService) Migrate() error {
-	return s.db.AutoMigrate(&model.AuditEntity{})
+func (s *AuditSeederService) Name() string {
+	return "audit"
 }
 
-func (s *AuditSeederService) Seed() error {
+func (s *AuditSeederService) Depends() []string {
 	return nil
 }
+
+func (s *AuditSeederService) Migrate() error {
+	return s.db.AutoMigrate(
+		&model.AuditEntity{},
+		&model.AuditArchiveEntity{},
+		&model.AuditTombstoneEntity{},
+	)
+}
+
+func (s *AuditSeederService) Seed(fixtures *Fixtures) ([]Change, error) {
+	return nil, nil
+}
@@ -0,0 +1,110 @@
+package seeder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Change is a difference between a fixture and the database that seeding writes
+type Change struct {
+	Action string
+	Kind   string
+	Key    string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s %s %s", c.Action, c.Kind, c.Key)
+}
+
+// Fixtures reads the seed files of an environment
+type Fixtures struct {
+	dir string
+}
+
+// LoadFixtures opens the fixtures of an environment, kept in dir/<environment>
+func LoadFixtures(dir, environment string) (*Fixtures, error) {
+	path := filepath.Join(dir, environment)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("no fixtures for environment '%s' %w", environment, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("fixtures of environment '%s' is not a directory", environment)
+	}
+	return &Fixtures{dir: path}, nil
+}
+
+// Decode reads the fixture name.yaml, name.yml or name.json into value with environment variables expanded,
+// it returns false when there is no such fixture
+func (f *Fixtures) Decode(name string, value interface{}) (bool, error) {
+	for _, ext := range []string{".yaml", ".yml", ".json"} {
+		content, err := os.ReadFile(filepath.Join(f.dir, name+ext))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		// yaml is a superset of json so both are decoded the same way
+		var document yaml.Node
+		if err := yaml.Unmarshal(content, &document); err != nil {
+			return false, fmt.Errorf("error when reading fixture %s%s %w", name, ext, err)
+		}
+		// variables are expanded in the parsed values so secrets need no quoting
+		if err := expandNode(&document); err != nil {
+			return false, fmt.Errorf("error when reading fixture %s%s %w", name, ext, err)
+		}
+		if err := document.Decode(value); err != nil {
+			return false, fmt.Errorf("error when reading fixture %s%s %w", name, ext, err)
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func expandNode(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		value, err := expandEnv(node.Value)
+		node.Value = value
+		return err
+	}
+	for _, child := range node.Content {
+		if err := expandNode(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::([-?])([^}]*))?\}`)
+
+// expandEnv replaces ${NAME} with the environment variable, ${NAME:-default} falls back to default when it is empty
+// and ${NAME:?} fails when it is empty, so secrets are kept out of the fixtures
+func expandEnv(content string) (string, error) {
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(content, func(reference string) string {
+		match := envReference.FindStringSubmatch(reference)
+		value := os.Getenv(match[1])
+		if value != "" {
+			return value
+		}
+		switch match[2] {
+		case "-":
+			return match[3]
+		case "?":
+			missing = append(missing, match[1])
+		}
+		return ""
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing environment variables %s", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
@@ -1,6 +1,9 @@
 package seeder
 
 import (
+	"errors"
+	"sort"
+
 	"github.com/ericmarcelinotju/gram/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -14,32 +17,63 @@ func NewPermissionSeederService(db *gorm.DB) *PermissionSeederService {
 	return &PermissionSeederService{db: db}
 }
 
+func (s *PermissionSeederService) Name() string {
+	return "permission"
+}
+
+func (s *PermissionSeederService) Depends() []string {
+	return nil
+}
+
 func (s *PermissionSeederService) Migrate() error {
 	return s.db.AutoMigrate(&model.PermissionEntity{})
 }
 
-func (s *PermissionSeederService) Seed() error {
-	permissionsMap := map[string][]string{
-		"STATISTIC":  {"GET"},
-		"LOG":        {"GET", "POST", "DELETE"},
-		"PERMISSION": {"GET", "POST", "PUT", "DELETE"},
-		"ROLE":       {"GET", "POST", "PUT", "DELETE"},
-		"SETTING":    {"GET", "POST"},
-		"USER":       {"GET", "POST", "PUT", "DELETE"},
+// Seed upserts the permissions fixture by module and method, a map of modules to their methods.
+// Deleted permissions are restored.
+func (s *PermissionSeederService) Seed(fixtures *Fixtures) ([]Change, error) {
+	var permissionsMap map[string][]string
+	if ok, err := fixtures.Decode("permissions", &permissionsMap); err != nil || !ok {
+		return nil, err
 	}
 
-	for module, methods := range permissionsMap {
-		for _, method := range methods {
-			entity := model.PermissionEntity{
-				Model:       model.Model{Id: uuid.New()},
-				Module:      module,
-				Method:      method,
-				Description: "Seeded permissions",
+	modules := make([]string, 0, len(permissionsMap))
+	for module := range permissionsMap {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+
+	var changes []Change
+	for _, module := range modules {
+		for _, method := range permissionsMap[module] {
+			key := module + ":" + method
+
+			var entity model.PermissionEntity
+			err := s.db.Unscoped().First(&entity, "module = ? AND method = ?", module, method).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				entity = model.PermissionEntity{
+					Model:       model.Model{Id: uuid.New()},
+					Module:      module,
+					Method:      method,
+					Description: "Seeded permissions",
+				}
+				if err := s.db.Create(&entity).Error; err != nil {
+					return changes, err
+				}
+				changes = append(changes, Change{Action: "create", Kind: "permission", Key: key})
+				continue
 			}
-			if err := s.db.Create(&entity).Error; err != nil {
-				return err
+			if err != nil {
+				return changes, err
 			}
+			if !entity.DeletedAt.Valid {
+				continue
+			}
+			if err := s.db.Unscoped().Model(&entity).Update("deleted_at", nil).Error; err != nil {
+				return changes, err
+			}
+			changes = append(changes, Change{Action: "restore", Kind: "permission", Key: key})
 		}
 	}
-	return nil
+	return changes, nil
 }
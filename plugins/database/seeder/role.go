@@ -1,6 +1,10 @@
 package seeder
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/ericmarcelinotju/gram/model"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -14,6 +18,14 @@ func NewRoleSeederService(db *gorm.DB) *RoleSeederService {
 	return &RoleSeederService{db: db}
 }
 
+func (s *RoleSeederService) Name() string {
+	return "role"
+}
+
+func (s *RoleSeederService) Depends() []string {
+	return []string{"permission"}
+}
+
 func (s *RoleSeederService) Migrate() error {
 	if err := s.db.AutoMigrate(&model.RoleEntity{}); err != nil {
 		return err
@@ -21,58 +33,125 @@ func (s *RoleSeederService) Migrate() error {
 	return s.db.AutoMigrate(&model.RolePermissionEntity{})
 }
 
-func (s *RoleSeederService) Seed() error {
-	var permissions []model.PermissionEntity
-	query := s.db.Model(&model.PermissionEntity{}).Find(&permissions)
+type roleFixture struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Level       int    `yaml:"level"`
+	// Permissions are MODULE:METHOD patterns where either part may be *
+	Permissions []string `yaml:"permissions"`
+}
 
-	if err := query.Error; err != nil {
-		return err
+// Seed upserts the roles fixture by name and replaces their permissions with the matching ones.
+// Deleted roles are restored.
+func (s *RoleSeederService) Seed(fixtures *Fixtures) ([]Change, error) {
+	var seedDatas []roleFixture
+	if ok, err := fixtures.Decode("roles", &seedDatas); err != nil || !ok {
+		return nil, err
 	}
 
-	var adminPermissionsMap map[string][]string = map[string][]string{
-		"USER":    {"GET", "POST", "PUT", "DELETE"},
-		"ROLE":    {"GET"},
-		"SETTING": {"GET", "POST"},
+	var permissions []model.PermissionEntity
+	if err := s.db.Model(&model.PermissionEntity{}).Find(&permissions).Error; err != nil {
+		return nil, err
 	}
 
-	var adminPermissions []model.PermissionEntity
+	var changes []Change
+	for _, seedData := range seedDatas {
+		rolePermissions, err := matchPermissions(permissions, seedData.Permissions)
+		if err != nil {
+			return changes, fmt.Errorf("role %s %w", seedData.Name, err)
+		}
+
+		var entity model.RoleEntity
+		err = s.db.Unscoped().Preload("Permissions").First(&entity, "name = ?", seedData.Name).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			entity = model.RoleEntity{
+				Model:       model.Model{Id: uuid.New()},
+				Name:        seedData.Name,
+				Description: seedData.Description,
+				Level:       seedData.Level,
+				Permissions: rolePermissions,
+			}
+			if err := s.db.Create(&entity).Error; err != nil {
+				return changes, err
+			}
+			changes = append(changes, Change{Action: "create", Kind: "role", Key: seedData.Name})
+			continue
+		}
+		if err != nil {
+			return changes, err
+		}
 
-	for _, permission := range permissions {
-		if isPermissionExistInMap(permission, adminPermissionsMap) {
-			adminPermissions = append(adminPermissions, permission)
+		changed := entity.DeletedAt.Valid ||
+			entity.Description != seedData.Description ||
+			entity.Level != seedData.Level
+		if changed {
+			if err := s.db.Unscoped().Model(&entity).Updates(map[string]interface{}{
+				"description": seedData.Description,
+				"level":       seedData.Level,
+				"deleted_at":  nil,
+				"version":     gorm.Expr("version + 1"),
+			}).Error; err != nil {
+				return changes, err
+			}
+		}
+		if !samePermissions(entity.Permissions, rolePermissions) {
+			changed = true
+			if err := s.db.Model(&entity).Association("Permissions").Replace(rolePermissions); err != nil {
+				return changes, err
+			}
+		}
+		if changed {
+			changes = append(changes, Change{Action: "update", Kind: "role", Key: seedData.Name})
 		}
 	}
-	seedDatas := []model.RoleEntity{
-		{
-			Model:       model.Model{Id: uuid.New()},
-			Name:        "Super Admin",
-			Description: "Super Administrator",
-			Permissions: permissions,
-		},
-		{
-			Model:       model.Model{Id: uuid.New()},
-			Name:        "Admin",
-			Description: "Administrator",
-			Permissions: adminPermissions,
-		},
+	return changes, nil
+}
+
+// matchPermissions returns the permissions matching any of the MODULE:METHOD patterns
+func matchPermissions(permissions []model.PermissionEntity, patterns []string) ([]model.PermissionEntity, error) {
+	matched := make([]bool, len(permissions))
+	for _, pattern := range patterns {
+		module, method, ok := strings.Cut(pattern, ":")
+		if pattern == "*" {
+			module, method, ok = "*", "*", true
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid permission '%s', expected MODULE:METHOD", pattern)
+		}
+
+		found := false
+		for i, permission := range permissions {
+			if (module == "*" || module == permission.Module) && (method == "*" || method == permission.Method) {
+				matched[i] = true
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown permission '%s'", pattern)
+		}
 	}
-	for _, seedData := range seedDatas {
-		if err := s.db.Create(&seedData).Error; err != nil {
-			return err
+
+	var results []model.PermissionEntity
+	for i, permission := range permissions {
+		if matched[i] {
+			results = append(results, permission)
 		}
 	}
-	return nil
+	return results, nil
 }
 
-func isPermissionExistInMap(permission model.PermissionEntity, permissionsMap map[string][]string) bool {
-	for module, methods := range permissionsMap {
-		if permission.Module == module {
-			for _, method := range methods {
-				if permission.Method == method {
-					return true
-				}
-			}
+func samePermissions(a, b []model.PermissionEntity) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ids := make(map[uuid.UUID]bool, len(a))
+	for _, permission := range a {
+		ids[permission.Id] = true
+	}
+	for _, permission := range b {
+		if !ids[permission.Id] {
+			return false
 		}
 	}
-	return false
+	return true
 }
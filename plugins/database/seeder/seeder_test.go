@@ -0,0 +1,124 @@
+package seeder
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	settingModule "github.com/ericmarcelinotju/gram/module/setting"
+	"github.com/ericmarcelinotju/gram/utils/crypt"
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSeedIdempotent(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "seed.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range []string{
+		`CREATE TABLE settings (name text PRIMARY KEY, value text)`,
+		`CREATE TABLE setting_histories (
+			id text PRIMARY KEY, name text, version integer, old_value text, new_value text, origin text,
+			actor_id text, actor_name text, request_id text, created_at datetime, UNIQUE (name, version)
+		)`,
+		`CREATE TABLE permission_entities (
+			id text PRIMARY KEY, created_at datetime, updated_at datetime, version integer NOT NULL DEFAULT 1,
+			method text, module text, description text, deleted_at datetime
+		)`,
+		`CREATE TABLE roles (
+			id text PRIMARY KEY, created_at datetime, updated_at datetime, version integer NOT NULL DEFAULT 1,
+			name text UNIQUE, description text, level integer, deleted_at datetime
+		)`,
+		`CREATE TABLE role_permissions (
+			role_entity_id text, permission_entity_id text, PRIMARY KEY (role_entity_id, permission_entity_id)
+		)`,
+		`CREATE TABLE users (
+			id text PRIMARY KEY, created_at datetime, updated_at datetime, version integer NOT NULL DEFAULT 1,
+			name text UNIQUE, email text, password text, firstname text, lastname text, title text, avatar text,
+			last_login datetime, role_id text, forgot_password_token text, deleted_at datetime
+		)`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	assert.Equal(t, os.MkdirAll(filepath.Join(dir, "test"), 0755), nil)
+	for name, content := range map[string]string{
+		"settings.yaml":    "smtp_host: localhost\nsmtp_password: ${SEED_TEST_PASSWORD:?}\n",
+		"permissions.json": `{"USER": ["GET", "POST"], "ROLE": ["GET"]}`,
+		"roles.yaml":       "- name: Admin\n  permissions: [\"USER:*\"]\n- name: Viewer\n  permissions: [\"*:GET\"]\n",
+		"users.yaml":       "- name: admin\n  email: admin@localhost\n  password: ${SEED_TEST_PASSWORD:?}\n  role: Admin\n",
+	} {
+		assert.Equal(t, os.WriteFile(filepath.Join(dir, "test", name), []byte(content), 0644), nil)
+	}
+	fixtures, err := LoadFixtures(dir, "test")
+	assert.Equal(t, err, nil)
+	keyring, _ := crypt.ParseKeyring("key:" + base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	codec := settingModule.NewCodec(keyring)
+	store := settingModule.NewSeeder(codec)
+
+	seed := func() int {
+		var total int
+		for _, service := range []interface {
+			Seed(*Fixtures) ([]Change, error)
+		}{
+			NewSettingSeederService(db, store),
+			NewPermissionSeederService(db),
+			NewRoleSeederService(db),
+			NewUserSeederService(db),
+		} {
+			changes, err := service.Seed(fixtures)
+			assert.Equal(t, err, nil)
+			total += len(changes)
+		}
+		return total
+	}
+
+	// secrets are required from the environment
+	_, err = NewSettingSeederService(db, store).Seed(fixtures)
+	assert.NotEqual(t, err, nil)
+
+	t.Setenv("SEED_TEST_PASSWORD", "secret")
+	assert.Equal(t, seed(), 2+3+2+1)
+	assert.Equal(t, seed(), 0)
+
+	// settings are sealed and recorded in their history like any other save
+	var setting string
+	db.Table("settings").Where("name = ?", "smtp_password").Pluck("value", &setting)
+	assert.Equal(t, crypt.IsSealed(setting), true)
+	value, _ := codec.Decode("smtp_password", setting)
+	assert.Equal(t, value, "secret")
+	var origins []string
+	db.Table("setting_histories").Where("name = ?", "smtp_password").Pluck("origin", &origins)
+	assert.Equal(t, origins, []string{settingModule.OriginSeed})
+	var viewerPermissions int64
+	db.Table("role_permissions").Joins("JOIN roles ON roles.id = role_permissions.role_entity_id").Where("roles.name = ?", "Viewer").Count(&viewerPermissions)
+	assert.Equal(t, viewerPermissions, int64(2))
+
+	db.Exec("UPDATE settings SET value = 'changed' WHERE name = 'smtp_host'")
+	db.Exec("DELETE FROM role_permissions")
+	assert.Equal(t, seed(), 1+2)
+
+	// values outside the definitions are rejected
+	assert.Equal(t, os.WriteFile(filepath.Join(dir, "test", "settings.yaml"), []byte("smtp_port: \"0\"\n"), 0644), nil)
+	fixtures, _ = LoadFixtures(dir, "test")
+	_, err = NewSettingSeederService(db, store).Seed(fixtures)
+	assert.NotEqual(t, err, nil)
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("SEED_TEST_VALUE", "value")
+
+	expanded, err := expandEnv("${SEED_TEST_VALUE} ${SEED_TEST_EMPTY} ${SEED_TEST_EMPTY:-default}")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, expanded, "value  default")
+
+	_, err = expandEnv("${SEED_TEST_EMPTY:?}")
+	assert.NotEqual(t, err, nil)
+}
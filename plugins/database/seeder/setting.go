@@ -1,67 +1,64 @@
 package seeder
 
 import (
-	"github.com/ericmarcelinotju/gram/constant"
+	"context"
+	"fmt"
+	"sort"
+
 	"github.com/ericmarcelinotju/gram/model"
 	"gorm.io/gorm"
 )
 
+// SettingStore saves a seeded setting in tx the way the settings are saved elsewhere: validated, sealed and
+// recorded in their history. It returns "create" or "update", or nothing when the stored value is the same.
+type SettingStore interface {
+	Seed(ctx context.Context, tx *gorm.DB, name, value string) (string, error)
+}
+
 type SettingSeederService struct {
-	db *gorm.DB
+	db    *gorm.DB
+	store SettingStore
+}
+
+// NewSettingSeederService creates a seeder saving the values through store
+func NewSettingSeederService(db *gorm.DB, store SettingStore) *SettingSeederService {
+	return &SettingSeederService{db: db, store: store}
+}
+
+func (s *SettingSeederService) Name() string {
+	return "setting"
 }
 
-func NewSettingSeederService(db *gorm.DB) *SettingSeederService {
-	return &SettingSeederService{db: db}
+func (s *SettingSeederService) Depends() []string {
+	return nil
 }
 
 func (s *SettingSeederService) Migrate() error {
 	return s.db.AutoMigrate(&model.SettingEntity{})
 }
 
-func (s *SettingSeederService) Seed() error {
-	seedDatas := []model.SettingEntity{
-		{
-			Name:  constant.SMTPHost,
-			Value: "smtp.zoho.com",
-		},
-		{
-			Name:  constant.SMTPPort,
-			Value: "587",
-		},
-		{
-			Name:  constant.SMTPEmail,
-			Value: "noreply@mail.apilogik.com",
-		},
-		{
-			Name:  constant.SMTPPassword,
-			Value: "G3iSHExStmBd",
-		},
-		{
-			Name:  constant.SFTPHost,
-			Value: "10.39.105.80",
-		},
-		{
-			Name:  constant.SFTPPort,
-			Value: "22",
-		},
-		{
-			Name:  constant.SFTPUsername,
-			Value: "sftpuser",
-		},
-		{
-			Name:  constant.SFTPPassword,
-			Value: "P@ssw0rd1!",
-		},
-		{
-			Name:  constant.SFTPStorageFolder,
-			Value: "recording",
-		},
+// Seed upserts the settings fixture by name, a map of setting names to values
+func (s *SettingSeederService) Seed(fixtures *Fixtures) ([]Change, error) {
+	var seedDatas map[string]string
+	if ok, err := fixtures.Decode("settings", &seedDatas); err != nil || !ok {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(seedDatas))
+	for name := range seedDatas {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	for _, seedData := range seedDatas {
-		if err := s.db.Create(&seedData).Error; err != nil {
-			return err
+	var changes []Change
+	for _, name := range names {
+		action, err := s.store.Seed(s.db.Statement.Context, s.db, name, seedDatas[name])
+		if err != nil {
+			return changes, fmt.Errorf("error when seeding setting %s %w", name, err)
+		}
+		if action != "" {
+			changes = append(changes, Change{Action: action, Kind: "setting", Key: name})
 		}
 	}
-	return nil
+	return changes, nil
 }
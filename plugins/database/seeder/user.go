@@ -1,6 +1,9 @@
 package seeder
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/ericmarcelinotju/gram/model"
 	"github.com/ericmarcelinotju/gram/utils/crypt"
 	"github.com/google/uuid"
@@ -15,46 +18,98 @@ func NewUserSeederService(db *gorm.DB) *UserSeederService {
 	return &UserSeederService{db: db}
 }
 
+func (s *UserSeederService) Name() string {
+	return "user"
+}
+
+func (s *UserSeederService) Depends() []string {
+	return []string{"role"}
+}
+
 func (s *UserSeederService) Migrate() error {
-	return s.db.AutoMigrate(&model.UserEntity{})
+	return s.db.AutoMigrate(&model.UserEntity{}, &model.UserImportEntity{})
 }
 
-func (s *UserSeederService) Seed() error {
-	var roles []model.RoleEntity
-	query := s.db.Model(&model.RoleEntity{}).Find(&roles)
-	if err := query.Error; err != nil {
-		return err
-	}
+type userFixture struct {
+	Name      string `yaml:"name"`
+	Email     string `yaml:"email"`
+	Password  string `yaml:"password"`
+	Firstname string `yaml:"firstname"`
+	Lastname  string `yaml:"lastname"`
+	Title     string `yaml:"title"`
+	Role      string `yaml:"role"`
+}
 
-	superAdminPassword, err := crypt.Hash("super")
-	if err != nil {
-		return err
-	}
-	adminPassword, err := crypt.Hash("admin")
-	if err != nil {
-		return err
+// Seed upserts the users fixture by name with the role of the given name.
+// The password is only set when the user is created so it can be changed afterwards.
+func (s *UserSeederService) Seed(fixtures *Fixtures) ([]Change, error) {
+	var seedDatas []userFixture
+	if ok, err := fixtures.Decode("users", &seedDatas); err != nil || !ok {
+		return nil, err
 	}
 
-	seedDatas := []model.UserEntity{
-		{
-			Model:    model.Model{Id: uuid.New()},
-			Name:     "super",
-			Email:    "eric@datis.co.id",
-			Password: superAdminPassword,
-			RoleId:   roles[0].Id,
-		},
-		{
-			Model:    model.Model{Id: uuid.New()},
-			Name:     "admin",
-			Email:    "admin@admin.com",
-			Password: adminPassword,
-			RoleId:   roles[1].Id,
-		},
-	}
+	var changes []Change
 	for _, seedData := range seedDatas {
-		if err := s.db.Create(&seedData).Error; err != nil {
-			return err
+		var role model.RoleEntity
+		err := s.db.First(&role, "name = ?", seedData.Role).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return changes, fmt.Errorf("user %s has unknown role '%s'", seedData.Name, seedData.Role)
+		}
+		if err != nil {
+			return changes, err
+		}
+
+		var entity model.UserEntity
+		err = s.db.Unscoped().First(&entity, "name = ?", seedData.Name).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if seedData.Password == "" {
+				return changes, fmt.Errorf("user %s has no password", seedData.Name)
+			}
+			password, err := crypt.Hash(seedData.Password)
+			if err != nil {
+				return changes, err
+			}
+			entity = model.UserEntity{
+				Model:     model.Model{Id: uuid.New()},
+				Name:      seedData.Name,
+				Email:     seedData.Email,
+				Password:  password,
+				Firstname: seedData.Firstname,
+				Lastname:  seedData.Lastname,
+				Title:     seedData.Title,
+				RoleId:    role.Id,
+			}
+			if err := s.db.Create(&entity).Error; err != nil {
+				return changes, err
+			}
+			changes = append(changes, Change{Action: "create", Kind: "user", Key: seedData.Name})
+			continue
+		}
+		if err != nil {
+			return changes, err
+		}
+
+		changed := entity.DeletedAt.Valid ||
+			entity.Email != seedData.Email ||
+			entity.Firstname != seedData.Firstname ||
+			entity.Lastname != seedData.Lastname ||
+			entity.Title != seedData.Title ||
+			entity.RoleId != role.Id
+		if !changed {
+			continue
+		}
+		if err := s.db.Unscoped().Model(&entity).Updates(map[string]interface{}{
+			"email":      seedData.Email,
+			"firstname":  seedData.Firstname,
+			"lastname":   seedData.Lastname,
+			"title":      seedData.Title,
+			"role_id":    role.Id,
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		}).Error; err != nil {
+			return changes, err
 		}
+		changes = append(changes, Change{Action: "update", Kind: "user", Key: seedData.Name})
 	}
-	return nil
+	return changes, nil
 }
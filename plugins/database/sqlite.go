@@ -8,7 +8,6 @@ import (
 	"github.com/ericmarcelinotju/gram/config"
 	"github.com/glebarez/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func MD5(val string) string {
@@ -41,5 +40,5 @@ func ConnectSqlite(configuration *config.Database) (*gorm.DB, error) {
 	// 	log.Fatal("Failed to open database:", err)
 	// }
 
-	return gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	return gorm.Open(sqlite.Open(dsn), gormConfig(configuration))
 }
@@ -6,7 +6,6 @@ import (
 	"github.com/ericmarcelinotju/gram/config"
 	"gorm.io/driver/sqlserver"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func ConnectSqlserver(configuration *config.Database) (*gorm.DB, error) {
@@ -19,7 +18,7 @@ func ConnectSqlserver(configuration *config.Database) (*gorm.DB, error) {
 		configuration.Instance,
 		configuration.DB,
 	)
-	db, err := gorm.Open(sqlserver.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	db, err := gorm.Open(sqlserver.Open(dsn), gormConfig(configuration))
 
 	if err != nil {
 		return nil, err
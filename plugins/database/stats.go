@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// PoolStats is the statistics of a connection pool
+type PoolStats struct {
+	Name              string  `json:"name"`
+	Healthy           bool    `json:"healthy"`
+	MaxOpen           int     `json:"max_open"`
+	Open              int     `json:"open"`
+	InUse             int     `json:"in_use"`
+	Idle              int     `json:"idle"`
+	WaitCount         int64   `json:"wait_count"`
+	WaitDuration      float64 `json:"wait_duration_ms"`
+	MaxIdleClosed     int64   `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64   `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64   `json:"max_lifetime_closed"`
+}
+
+type monitoredPool struct {
+	name    string
+	pool    *sql.DB
+	replica *Replica
+}
+
+var monitor struct {
+	sync.RWMutex
+	pools []monitoredPool
+}
+
+// register adds a pool to the statistics, replica tells its health
+func register(name string, pool *sql.DB, replica *Replica) {
+	monitor.Lock()
+	defer monitor.Unlock()
+	monitor.pools = append(monitor.pools, monitoredPool{name: name, pool: pool, replica: replica})
+}
+
+// Stats returns the statistics of the connected pools, the primary first
+func Stats() []PoolStats {
+	monitor.RLock()
+	defer monitor.RUnlock()
+
+	results := make([]PoolStats, len(monitor.pools))
+	for i, item := range monitor.pools {
+		stats := item.pool.Stats()
+		results[i] = PoolStats{
+			Name:              item.name,
+			Healthy:           item.replica == nil || item.replica.Healthy(),
+			MaxOpen:           stats.MaxOpenConnections,
+			Open:              stats.OpenConnections,
+			InUse:             stats.InUse,
+			Idle:              stats.Idle,
+			WaitCount:         stats.WaitCount,
+			WaitDuration:      float64(stats.WaitDuration.Microseconds()) / 1000,
+			MaxIdleClosed:     stats.MaxIdleClosed,
+			MaxIdleTimeClosed: stats.MaxIdleTimeClosed,
+			MaxLifetimeClosed: stats.MaxLifetimeClosed,
+		}
+	}
+	return results
+}
+
+// Ping checks the primary pool answers
+func Ping(ctx context.Context) error {
+	monitor.RLock()
+	defer monitor.RUnlock()
+
+	for _, item := range monitor.pools {
+		if item.replica == nil {
+			return item.pool.PingContext(ctx)
+		}
+	}
+	return errors.New("database is not connected")
+}
+
+// poolStatsEntry is a structured pool statistics log line
+type poolStatsEntry struct {
+	Time string `json:"time"`
+	Type string `json:"type"`
+	PoolStats
+}
+
+// StartStatsLogger writes the statistics of every pool to the log output each interval
+func StartStatsLogger(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			for _, stats := range Stats() {
+				line, err := json.Marshal(poolStatsEntry{Time: now.Format(time.RFC3339), Type: "db_pool", PoolStats: stats})
+				if err != nil {
+					continue
+				}
+				fmt.Fprintln(log.Writer(), string(line))
+			}
+		}
+	}()
+}
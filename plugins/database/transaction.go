@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type transactionKey struct{}
+
+// WithContext returns the transaction carried by ctx, or db when there is none, bound to ctx
+func WithContext(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(transactionKey{}).(*gorm.DB); ok && tx != nil {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// UnitOfWork composes repository calls into one transaction
+type UnitOfWork struct {
+	db *gorm.DB
+}
+
+// NewUnitOfWork creates a new unit of work on db
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// Do runs fn in a transaction carried by the context given to fn, which is rolled back when fn returns an error.
+// A unit of work started inside another one runs in a savepoint of the outer transaction.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return WithContext(ctx, u.db).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, transactionKey{}, tx))
+	})
+}
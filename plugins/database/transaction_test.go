@@ -0,0 +1,62 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/go-playground/assert/v2"
+	"gorm.io/gorm"
+)
+
+type transactionItem struct {
+	Name string
+}
+
+func TestUnitOfWork(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "transaction.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, db.AutoMigrate(&transactionItem{}), nil)
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+	insert := func(ctx context.Context, name string) error {
+		return WithContext(ctx, db).Create(&transactionItem{Name: name}).Error
+	}
+	names := func() []string {
+		var names []string
+		db.Model(&transactionItem{}).Order("name").Pluck("name", &names)
+		return names
+	}
+
+	// a failed nested unit only rolls back its savepoint
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		if err := insert(ctx, "a"); err != nil {
+			return err
+		}
+		nested := uow.Do(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, "b"); err != nil {
+				return err
+			}
+			return errors.New("nested failure")
+		})
+		assert.NotEqual(t, nested, nil)
+		return insert(ctx, "c")
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, names(), []string{"a", "c"})
+
+	// a failed unit rolls back every repository call made with its context
+	err = uow.Do(ctx, func(ctx context.Context) error {
+		if err := insert(ctx, "d"); err != nil {
+			return err
+		}
+		return errors.New("failure")
+	})
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, names(), []string{"a", "c"})
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"reflect"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// uuidTypes maps the drivers without a uuid column type to the column storing uuids as text
+var uuidTypes = map[string]schema.DataType{
+	"mysql":     "char(36)",
+	"sqlserver": "char(36)",
+}
+
+var uuidType = reflect.TypeOf(uuid.UUID{})
+
+// UseUuids generates the empty uuid primary keys on create and stores the uuid columns of entities
+// in a type the driver has, no database default is shared by every driver
+func UseUuids(db *gorm.DB, entities ...interface{}) error {
+	if dataType, ok := uuidTypes[db.Dialector.Name()]; ok {
+		for _, entity := range entities {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(entity); err != nil {
+				return err
+			}
+			retype(stmt.Schema, dataType)
+			for _, relationship := range stmt.Schema.Relationships.Relations {
+				if relationship.JoinTable != nil {
+					retype(relationship.JoinTable, dataType)
+				}
+			}
+		}
+	}
+	return db.Callback().Create().Before("gorm:before_create").Register("gram:uuid", assignIds)
+}
+
+// retype changes the column type of the uuid fields, the schema is cached so every statement sees it
+func retype(s *schema.Schema, dataType schema.DataType) {
+	for _, field := range s.Fields {
+		if field.IndirectFieldType == uuidType {
+			field.DataType = dataType
+		}
+	}
+}
+
+// assignIds sets a new uuid on the created records without a primary key, before the hooks auditing them run
+func assignIds(db *gorm.DB) {
+	if db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if field == nil || field.IndirectFieldType != uuidType {
+		return
+	}
+
+	ctx := db.Statement.Context
+	assign := func(value reflect.Value) {
+		if _, zero := field.ValueOf(ctx, value); zero {
+			db.AddError(field.Set(ctx, value, uuid.New()))
+		}
+	}
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			assign(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		assign(value)
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"time"
 
 	"github.com/ericmarcelinotju/gram/constant/enums"
 	"github.com/go-co-op/gocron"
 )
 
+// Scheduler runs a function monthly, daily or every few minutes, it may be rescheduled from any goroutine
 type Scheduler struct {
+	// mutex guards the schedule against the reschedules of setting changes
+	mutex          sync.Mutex
 	interval       enums.SchedulerInterval
 	monthlyDate    int
 	dailyHour      int
@@ -33,11 +37,14 @@ func NewScheduler(hour, minute int) (*Scheduler, error) {
 }
 
 func (s *Scheduler) SetMinutely(minute int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	s.interval = enums.SchedulerIntervalMinutely
 	s.minutelyMinute = minute
 
-	if s.IsRunning() {
-		err := s.Start()
+	if s.isRunning() {
+		err := s.start()
 		if err != nil {
 			return err
 		}
@@ -47,12 +54,15 @@ func (s *Scheduler) SetMinutely(minute int) error {
 }
 
 func (s *Scheduler) SetDaily(hour, minute int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	s.interval = enums.SchedulerIntervalDaily
 	s.dailyHour = hour
 	s.dailyMinute = minute
 
-	if s.IsRunning() {
-		err := s.Start()
+	if s.isRunning() {
+		err := s.start()
 		if err != nil {
 			return err
 		}
@@ -62,11 +72,14 @@ func (s *Scheduler) SetDaily(hour, minute int) error {
 }
 
 func (s *Scheduler) SetMonthly(monthlyDate int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	s.interval = enums.SchedulerIntervalMonthly
 	s.monthlyDate = monthlyDate
 
-	if s.IsRunning() {
-		err := s.Start()
+	if s.isRunning() {
+		err := s.start()
 		if err != nil {
 			return err
 		}
@@ -81,10 +94,13 @@ func (s *Scheduler) SetScheduleFunc(scheduleFunc interface{}) error {
 		return errors.New("not a function")
 	}
 
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	s.scheduleFunc = scheduleFunc
 
-	if s.IsRunning() {
-		err := s.Start()
+	if s.isRunning() {
+		err := s.start()
 		if err != nil {
 			return err
 		}
@@ -94,7 +110,14 @@ func (s *Scheduler) SetScheduleFunc(scheduleFunc interface{}) error {
 }
 
 func (s *Scheduler) Start() error {
-	s.Stop()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.start()
+}
+
+func (s *Scheduler) start() error {
+	s.stop()
 
 	scheduler := gocron.NewScheduler(time.Local)
 	s.scheduler = scheduler
@@ -131,11 +154,25 @@ func (s *Scheduler) Start() error {
 }
 
 func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stop()
+}
+
+func (s *Scheduler) stop() {
 	if s.scheduler != nil {
 		s.scheduler.Stop()
 	}
 }
 
 func (s *Scheduler) IsRunning() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.isRunning()
+}
+
+func (s *Scheduler) isRunning() bool {
 	return s.scheduler != nil && s.scheduler.IsRunning()
 }
@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ericmarcelinotju/gram/dto"
+)
+
+// ErrNotConfigured reports a notification sent before the notifier was configured
+var ErrNotConfigured = errors.New("notifier is not configured")
+
+// Reloadable is a notifier replaced when its configuration changes, without stopping the notifications being sent
+type Reloadable struct {
+	mutex    sync.RWMutex
+	notifier Notifier
+}
+
+// NewReloadable wraps notifier, which may be nil until the configuration is known
+func NewReloadable(notifier Notifier) *Reloadable {
+	return &Reloadable{notifier: notifier}
+}
+
+// Set replaces the notifier, the notifications being sent finish on the previous one
+func (r *Reloadable) Set(notifier Notifier) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.notifier = notifier
+}
+
+func (r *Reloadable) current() (Notifier, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.notifier == nil {
+		return nil, ErrNotConfigured
+	}
+	return r.notifier, nil
+}
+
+func (r *Reloadable) Notify(title string, content interface{}, recipient *dto.UserDto) error {
+	notifier, err := r.current()
+	if err != nil {
+		return err
+	}
+	return notifier.Notify(title, content, recipient)
+}
+
+func (r *Reloadable) Subscribe(identifier string, topic string) error {
+	notifier, err := r.current()
+	if err != nil {
+		return err
+	}
+	return notifier.Subscribe(identifier, topic)
+}
+
+func (r *Reloadable) Unsubscribe(identifier string, topic string) error {
+	notifier, err := r.current()
+	if err != nil {
+		return err
+	}
+	return notifier.Unsubscribe(identifier, topic)
+}
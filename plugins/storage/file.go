@@ -31,7 +31,10 @@ func NewFileStorage(configuration *config.Storage) (*FileStorage, error) {
 }
 
 func (f *FileStorage) Upload(file multipart.File, fileName string) error {
+	return f.UploadStream(file, fileName)
+}
 
+func (f *FileStorage) UploadStream(reader io.Reader, fileName string) error {
 	filePath := filepath.Join(f.path, fileName)
 
 	dir := filepath.Dir(filePath)
@@ -54,7 +57,7 @@ func (f *FileStorage) Upload(file multipart.File, fileName string) error {
 	defer out.Close()
 
 	// Write the body to file
-	_, err = io.Copy(out, file)
+	_, err = io.Copy(out, reader)
 	return err
 }
 
@@ -64,6 +67,12 @@ func (f *FileStorage) Download(fileName string) ([]byte, error) {
 	return os.ReadFile(filePath)
 }
 
+func (f *FileStorage) DownloadStream(fileName string) (io.ReadCloser, error) {
+	pwd, _ := os.Getwd()
+	filePath := filepath.Join(pwd, f.path, fileName)
+	return os.Open(filePath)
+}
+
 func (f *FileStorage) Remove(fileName string) error {
 	pwd, _ := os.Getwd()
 	filePath := filepath.Join(pwd, f.path, fileName)
@@ -97,7 +106,7 @@ func (f *FileStorage) List(folder string) ([]fs.FileInfo, error) {
 	}
 
 	for _, dirEntry := range dirEntries {
-		fileInfo, err := os.Stat(dirEntry.Name())
+		fileInfo, err := dirEntry.Info()
 		if err != nil {
 			return nil, err
 		}
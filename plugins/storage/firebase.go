@@ -68,6 +68,11 @@ func (s *FirebaseStorage) Download(fileName string) (object []byte, err error) {
 	return data, nil
 }
 
+// DownloadStream reads the file until the reader is closed, it is not bounded by the download timeout
+func (s *FirebaseStorage) DownloadStream(fileName string) (io.ReadCloser, error) {
+	return s.Bucket.Object(fileName).NewReader(context.Background())
+}
+
 func (s *FirebaseStorage) Remove(fileName string) (err error) {
 	ctx := context.Background()
 
@@ -82,13 +87,16 @@ func (s *FirebaseStorage) Remove(fileName string) (err error) {
 
 func (s *FirebaseStorage) Upload(file multipart.File, filename string) error {
 	defer file.Close()
+	return s.UploadStream(file, filename)
+}
 
+func (s *FirebaseStorage) UploadStream(reader io.Reader, filename string) error {
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, time.Second*50)
 	defer cancel()
 
 	wc := s.Bucket.Object(filename).NewWriter(ctx)
-	if _, err := io.Copy(wc, file); err != nil {
+	if _, err := io.Copy(wc, reader); err != nil {
 		return err
 	}
 	if err := wc.Close(); err != nil {
@@ -71,7 +71,16 @@ func NewFTPManager(basePath string, host, username, password string) (*FtpStorag
 	return &FtpStorage{path: basePath, ftpManager: ftpManager}, nil
 }
 
-func (f *FtpStorage) Upload(file multipart.File, fileName string) (err error) {
+// Close closes the connections to the server
+func (f *FtpStorage) Close() error {
+	return f.ftpManager.Close()
+}
+
+func (f *FtpStorage) Upload(file multipart.File, fileName string) error {
+	return f.UploadStream(file, fileName)
+}
+
+func (f *FtpStorage) UploadStream(reader io.Reader, fileName string) (err error) {
 	var conn *SFTPConn
 	var client *sftp.Client
 
@@ -100,7 +109,7 @@ func (f *FtpStorage) Upload(file multipart.File, fileName string) (err error) {
 	defer out.Close()
 
 	// Write the body to file
-	_, err = io.Copy(out, file)
+	_, err = io.Copy(out, reader)
 	return err
 }
 
@@ -131,6 +140,21 @@ func (f *FtpStorage) Download(fileName string) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+func (f *FtpStorage) DownloadStream(fileName string) (io.ReadCloser, error) {
+	conn, err := f.ftpManager.GetConnection()
+	if err != nil {
+		return nil, err
+	}
+	client := conn.GetClient()
+
+	pwd, err := client.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	filePath := filepath.Join(pwd, f.path, fileName)
+	return client.OpenFile(filePath, (os.O_RDONLY))
+}
+
 func (f *FtpStorage) Remove(fileName string) error {
 	var err error
 	var conn *SFTPConn
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"io"
 	"io/fs"
 	"mime/multipart"
 )
@@ -8,7 +9,9 @@ import (
 // Storage provides an abstraction on top of the file storage logic
 type Storage interface {
 	Upload(multipart.File, string) error
+	UploadStream(io.Reader, string) error
 	Download(string) ([]byte, error)
+	DownloadStream(string) (io.ReadCloser, error)
 	Remove(string) error
 	List(string) ([]fs.FileInfo, error)
 
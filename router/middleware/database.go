@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ericmarcelinotju/gram/plugins/database"
+)
+
+// ReadYourWrites sends the reads of a request to the primary database once the request has written
+func ReadYourWrites(c *gin.Context) {
+	sticky := &database.Sticky{}
+	c.Set(database.StickyKey, sticky)
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), database.StickyKey, sticky))
+
+	c.Next()
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/ericmarcelinotju/gram/utils/request"
+)
+
+const RequestIdHeader = "X-Request-ID"
+
+var requestIdPattern = regexp.MustCompile(`^[A-Za-z0-9\-_.:]{1,128}$`)
+
+// accessLogEntry is a structured access log line
+type accessLogEntry struct {
+	Time      string  `json:"time"`
+	RequestId string  `json:"request_id"`
+	UserId    string  `json:"user_id,omitempty"`
+	ClientIP  string  `json:"client_ip"`
+	Method    string  `json:"method"`
+	Route     string  `json:"route"`
+	Path      string  `json:"path"`
+	Status    int     `json:"status"`
+	Latency   float64 `json:"latency_ms"`
+	Bytes     int     `json:"bytes"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// RequestId accepts the X-Request-ID header of the client or assigns a new one,
+// the ID is put on the context and echoed back on the response
+func RequestId(c *gin.Context) {
+	requestId := c.GetHeader(RequestIdHeader)
+	if !requestIdPattern.MatchString(requestId) {
+		requestId = uuid.New().String()
+	}
+
+	c.Set("request-id", requestId)
+	c.Request = c.Request.WithContext(request.WithRequestId(c.Request.Context(), requestId))
+	c.Header(RequestIdHeader, requestId)
+
+	c.Next()
+}
+
+// AccessLog writes a structured access log entry to the log output for each request
+func AccessLog(c *gin.Context) {
+	start := time.Now()
+
+	c.Next()
+
+	entry := accessLogEntry{
+		Time:      start.Format(time.RFC3339),
+		RequestId: c.GetString("request-id"),
+		ClientIP:  c.ClientIP(),
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		Path:      c.Request.URL.Path,
+		Status:    c.Writer.Status(),
+		Latency:   float64(time.Since(start).Microseconds()) / 1000,
+		Bytes:     c.Writer.Size(),
+		Error:     c.Errors.ByType(gin.ErrorTypePrivate).String(),
+	}
+	if entry.Bytes < 0 {
+		entry.Bytes = 0
+	}
+	if user, ok := c.Value("auth-user").(*dto.UserDto); ok && user != nil {
+		entry.UserId = user.Id
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	fmt.Fprintln(log.Writer(), string(line))
+}
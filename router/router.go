@@ -10,6 +10,7 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
+	auditModule "github.com/ericmarcelinotju/gram/module/audit"
 	authModule "github.com/ericmarcelinotju/gram/module/auth"
 	healthModule "github.com/ericmarcelinotju/gram/module/health"
 	permissionModule "github.com/ericmarcelinotju/gram/module/permission"
@@ -34,6 +35,8 @@ func NewHTTPHandler(
 
 	settingSvc settingModule.Service,
 
+	auditSvc auditModule.Service,
+
 	queueConnection rmq.Connection,
 
 	backupQueue *job.Queue,
@@ -41,7 +44,11 @@ func NewHTTPHandler(
 
 	gin.DefaultWriter = log.Writer()
 
-	router := gin.Default()
+	router := gin.New()
+	router.Use(middleware.RequestId)
+	router.Use(middleware.AccessLog)
+	router.Use(middleware.ReadYourWrites)
+	router.Use(gin.Recovery())
 
 	config := cors.Config{
 		AllowOrigins: []string{
@@ -54,8 +61,8 @@ func NewHTTPHandler(
 			"https://10.224.171.167",
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "X-XSRF-TOKEN", "App-Name", "ResponseType"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "Set-Cookie"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "X-XSRF-TOKEN", "App-Name", "ResponseType", "If-Match", "If-None-Match", middleware.RequestIdHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition", "Set-Cookie", "Link", "ETag", middleware.RequestIdHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}
@@ -82,6 +89,8 @@ func NewHTTPHandler(
 		roleModule.NewRoutesFactory(authGroup)(roleSvc)
 		permissionModule.NewRoutesFactory(authGroup)(permissionSvc)
 		settingModule.NewRoutesFactory(authGroup)(settingSvc)
+		auditModule.NewRoutesFactory(authGroup)(auditSvc)
+		healthModule.NewStatisticRoutesFactory(authGroup)()
 	}
 
 	swaggerRoutes.Init(router.Group("swagger"))()
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/ericmarcelinotju/gram/constant"
+	"github.com/ericmarcelinotju/gram/dto"
+	auditModule "github.com/ericmarcelinotju/gram/module/audit"
+	settingModule "github.com/ericmarcelinotju/gram/module/setting"
+	"github.com/ericmarcelinotju/gram/plugins/job"
+	"github.com/ericmarcelinotju/gram/utils/logger"
+	"github.com/ericmarcelinotju/gram/utils/request"
+	"github.com/google/uuid"
+)
+
+// Scheduler enforces the audit retention policy daily
+type Scheduler struct {
+	ctx        context.Context
+	scheduler  *job.Scheduler
+	auditSvc   auditModule.Service
+	settingSvc settingModule.Service
+	// unsubscribe stops following the changes of the settings
+	unsubscribe func()
+}
+
+// NewScheduler creates the retention scheduler at the time configured in settings
+func NewScheduler(auditSvc auditModule.Service, settingSvc settingModule.Service) (*Scheduler, error) {
+	ctx := context.Background()
+
+	hour, minute, err := settingSvc.GetSchedulerTime(ctx, constant.AuditRetentionTime)
+	if err != nil {
+		return nil, err
+	}
+	scheduler, err := job.NewScheduler(hour, minute)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scheduler{
+		ctx:        ctx,
+		scheduler:  scheduler,
+		auditSvc:   auditSvc,
+		settingSvc: settingSvc,
+	}, nil
+}
+
+// Start start scheduler
+func (w *Scheduler) Start() error {
+	err := w.scheduler.SetScheduleFunc(w.OnSchedule)
+	if err != nil {
+		return err
+	}
+	w.unsubscribe = w.subscribe()
+	return w.scheduler.Start()
+}
+
+func (w *Scheduler) Stop() error {
+	if w.unsubscribe != nil {
+		w.unsubscribe()
+	}
+	w.scheduler.Stop()
+	return nil
+}
+
+func (w *Scheduler) subscribe() func() {
+	return settingModule.FollowSchedule(w.settingSvc, w.scheduler, constant.AuditRetentionTime, "AUDIT ARCHIVE")
+}
+
+func (w *Scheduler) OnSchedule() {
+	// every run gets its own correlation ID to follow it through the logs
+	ctx := request.WithRequestId(w.ctx, uuid.NewString())
+
+	retention, err := w.settingSvc.GetAuditRetention(ctx)
+	if err != nil {
+		logger.Printf(ctx, "[AUDIT ARCHIVE] failed to read retention policy : %s", err)
+		return
+	}
+
+	for entityName, days := range retention {
+		if days <= 0 {
+			continue
+		}
+		_, err := w.auditSvc.Archive(ctx, &dto.ArchiveAuditDto{
+			EntityName:    entityName,
+			RetentionDays: days,
+		})
+		if err != nil {
+			logger.Printf(ctx, "[AUDIT ARCHIVE] failed to archive '%s' : %s", entityName, err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/constant"
+	settingModule "github.com/ericmarcelinotju/gram/module/setting"
+	"github.com/ericmarcelinotju/gram/plugins/database/backup"
+	"github.com/ericmarcelinotju/gram/plugins/job"
+	"github.com/ericmarcelinotju/gram/plugins/storage"
+	"github.com/ericmarcelinotju/gram/utils/logger"
+	"github.com/ericmarcelinotju/gram/utils/request"
+	"github.com/google/uuid"
+)
+
+// Scheduler backs the database up daily and removes the backups older than the retention window
+type Scheduler struct {
+	ctx          context.Context
+	scheduler    *job.Scheduler
+	backup       *backup.Backup
+	settingSvc   settingModule.Service
+	mediaStorage storage.Storage
+	// unsubscribe stops following the changes of the settings
+	unsubscribe func()
+
+	// store is opened on the first run and again after the storage settings changed
+	mutex sync.Mutex
+	store storage.Storage
+	stale bool
+}
+
+// NewScheduler creates the backup scheduler at the time configured in settings
+func NewScheduler(backup *backup.Backup, settingSvc settingModule.Service, mediaStorage storage.Storage) (*Scheduler, error) {
+	ctx := context.Background()
+
+	hour, minute, err := settingSvc.GetSchedulerTime(ctx, constant.BackupTime)
+	if err != nil {
+		return nil, err
+	}
+	scheduler, err := job.NewScheduler(hour, minute)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scheduler{
+		ctx:          ctx,
+		scheduler:    scheduler,
+		backup:       backup,
+		settingSvc:   settingSvc,
+		mediaStorage: mediaStorage,
+	}, nil
+}
+
+// Start start scheduler
+func (w *Scheduler) Start() error {
+	err := w.scheduler.SetScheduleFunc(w.OnSchedule)
+	if err != nil {
+		return err
+	}
+	w.unsubscribe = w.subscribe()
+	return w.scheduler.Start()
+}
+
+func (w *Scheduler) Stop() error {
+	if w.unsubscribe != nil {
+		w.unsubscribe()
+	}
+	w.scheduler.Stop()
+	return nil
+}
+
+func (w *Scheduler) subscribe() func() {
+	unsubscribeTime := settingModule.FollowSchedule(w.settingSvc, w.scheduler, constant.BackupTime, "BACKUP")
+	unsubscribeStorage := w.settingSvc.Subscribe(settingModule.Subscription{
+		Names:  []string{constant.BackupStorage},
+		Groups: []string{"sftp"},
+	}, w.reopen)
+	return func() {
+		unsubscribeTime()
+		unsubscribeStorage()
+	}
+}
+
+// reopen marks the storage to be opened again at the next run, a run in progress keeps its storage
+func (w *Scheduler) reopen(context.Context, settingModule.Event) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.stale = true
+}
+
+// openStore returns the storage of the backups, closing the previous one when the settings changed
+func (w *Scheduler) openStore(ctx context.Context) (storage.Storage, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.store != nil && !w.stale {
+		return w.store, nil
+	}
+	if closer, ok := w.store.(io.Closer); ok && w.store != w.mediaStorage {
+		closer.Close()
+	}
+	w.store = nil
+
+	store, err := w.settingSvc.GetBackupStorage(ctx, w.mediaStorage)
+	if err != nil {
+		return nil, err
+	}
+	w.store = store
+	w.stale = false
+	return store, nil
+}
+
+func (w *Scheduler) OnSchedule() {
+	ctx := request.WithRequestId(w.ctx, uuid.NewString())
+
+	store, err := w.openStore(ctx)
+	if err != nil {
+		logger.Printf(ctx, "[BACKUP] failed to open storage : %s", err)
+		return
+	}
+
+	filename, manifest, err := w.backup.Create(ctx, store)
+	if err != nil {
+		logger.Printf(ctx, "[BACKUP] failed to back up : %s", err)
+		return
+	}
+	logger.Printf(ctx, "[BACKUP] backed up %d tables to '%s'", len(manifest.Tables), filename)
+
+	days, err := w.settingSvc.GetBackupRetention(ctx)
+	if err != nil {
+		logger.Printf(ctx, "[BACKUP] failed to read retention : %s", err)
+		return
+	}
+	if days <= 0 {
+		return
+	}
+	removed, err := w.backup.Prune(ctx, store, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		logger.Printf(ctx, "[BACKUP] failed to remove old backups : %s", err)
+	}
+	for _, filename := range removed {
+		logger.Printf(ctx, "[BACKUP] removed '%s'", filename)
+	}
+}
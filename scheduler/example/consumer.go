@@ -9,6 +9,8 @@ import (
 	"github.com/adjust/rmq/v4"
 	"github.com/ericmarcelinotju/gram/constant/enums"
 	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/ericmarcelinotju/gram/utils/logger"
+	"github.com/ericmarcelinotju/gram/utils/request"
 )
 
 type Consumer struct {
@@ -69,24 +71,27 @@ func (c *Consumer) Consume(delivery rmq.Delivery) {
 		return
 	}
 
-	err := NewJobProcessor()(c.ctx, job)
+	// carry the request ID of the producer so job logs can be correlated
+	ctx := request.WithRequestId(c.ctx, job.RequestId)
+
+	err := NewJobProcessor()(ctx, job)
 	if err != nil {
 		// handle error
-		CreateConsumeLog(c.ctx, "Process job error", err.Error(), enums.LogLevelDanger)
+		CreateConsumeLog(ctx, "Process job error", err.Error(), enums.LogLevelDanger)
 		if err := delivery.Reject(); err != nil {
 			// handle reject error
-			CreateConsumeLog(c.ctx, "Reject job error", err.Error(), enums.LogLevelWarning)
+			CreateConsumeLog(ctx, "Reject job error", err.Error(), enums.LogLevelWarning)
 		}
 		return
 	}
 
-	CreateConsumeLog(c.ctx, "Performing task", fmt.Sprintf("Process recording: %v", job.Value), enums.LogLevelInfo)
+	CreateConsumeLog(ctx, "Performing task", fmt.Sprintf("Process recording: %v", job.Value), enums.LogLevelInfo)
 	if err := delivery.Ack(); err != nil {
 		// handle ack error
-		CreateConsumeLog(c.ctx, "Acknowledge job error", err.Error(), enums.LogLevelWarning)
+		CreateConsumeLog(ctx, "Acknowledge job error", err.Error(), enums.LogLevelWarning)
 	}
 }
 
 func CreateConsumeLog(ctx context.Context, subject, content string, level enums.LogLevel) {
-	// Log here
+	logger.Printf(ctx, "[%s] %s : %s", level, subject, content)
 }
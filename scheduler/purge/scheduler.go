@@ -0,0 +1,111 @@
+package purge
+
+import (
+	"context"
+	"time"
+
+	"github.com/ericmarcelinotju/gram/constant"
+	permissionModule "github.com/ericmarcelinotju/gram/module/permission"
+	roleModule "github.com/ericmarcelinotju/gram/module/role"
+	settingModule "github.com/ericmarcelinotju/gram/module/setting"
+	userModule "github.com/ericmarcelinotju/gram/module/user"
+	"github.com/ericmarcelinotju/gram/plugins/job"
+	"github.com/ericmarcelinotju/gram/utils/logger"
+	"github.com/ericmarcelinotju/gram/utils/request"
+	"github.com/google/uuid"
+)
+
+// Purger permanently deletes rows soft deleted before a time
+type Purger interface {
+	Purge(context.Context, time.Time) (int64, error)
+}
+
+// Scheduler purges soft deleted rows older than the retention window daily
+type Scheduler struct {
+	ctx        context.Context
+	scheduler  *job.Scheduler
+	settingSvc settingModule.Service
+	purgers    map[string]Purger
+	order      []string
+	// unsubscribe stops following the changes of the settings
+	unsubscribe func()
+}
+
+// NewScheduler creates the purge scheduler at the time configured in settings
+func NewScheduler(
+	userSvc userModule.Service,
+	roleSvc roleModule.Service,
+	permissionSvc permissionModule.Service,
+	settingSvc settingModule.Service,
+) (*Scheduler, error) {
+	ctx := context.Background()
+
+	hour, minute, err := settingSvc.GetSchedulerTime(ctx, constant.SoftDeletePurgeTime)
+	if err != nil {
+		return nil, err
+	}
+	scheduler, err := job.NewScheduler(hour, minute)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scheduler{
+		ctx:        ctx,
+		scheduler:  scheduler,
+		settingSvc: settingSvc,
+		purgers: map[string]Purger{
+			"users":       userSvc,
+			"roles":       roleSvc,
+			"permissions": permissionSvc,
+		},
+		// users go first so their roles are no longer referenced
+		order: []string{"users", "roles", "permissions"},
+	}, nil
+}
+
+// Start start scheduler
+func (w *Scheduler) Start() error {
+	err := w.scheduler.SetScheduleFunc(w.OnSchedule)
+	if err != nil {
+		return err
+	}
+	w.unsubscribe = w.subscribe()
+	return w.scheduler.Start()
+}
+
+func (w *Scheduler) Stop() error {
+	if w.unsubscribe != nil {
+		w.unsubscribe()
+	}
+	w.scheduler.Stop()
+	return nil
+}
+
+func (w *Scheduler) subscribe() func() {
+	return settingModule.FollowSchedule(w.settingSvc, w.scheduler, constant.SoftDeletePurgeTime, "PURGE")
+}
+
+func (w *Scheduler) OnSchedule() {
+	ctx := request.WithRequestId(w.ctx, uuid.NewString())
+
+	days, err := w.settingSvc.GetSoftDeleteRetention(ctx)
+	if err != nil {
+		logger.Printf(ctx, "[PURGE] failed to read retention : %s", err)
+		return
+	}
+	if days <= 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -days)
+
+	for _, name := range w.order {
+		total, err := w.purgers[name].Purge(ctx, before)
+		if err != nil {
+			logger.Printf(ctx, "[PURGE] failed to purge '%s' : %s", name, err)
+			continue
+		}
+		if total > 0 {
+			logger.Printf(ctx, "[PURGE] purged %d '%s' deleted before %s", total, name, before.Format(time.RFC3339))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/adjust/rmq/v4"
+	"github.com/ericmarcelinotju/gram/constant/enums"
+	"github.com/ericmarcelinotju/gram/dto"
+	userModule "github.com/ericmarcelinotju/gram/module/user"
+	"github.com/ericmarcelinotju/gram/utils/logger"
+	"github.com/ericmarcelinotju/gram/utils/request"
+)
+
+type Consumer struct {
+	name    string
+	userSvc userModule.Service
+
+	ctx context.Context
+}
+
+// NewConsumerFactory create and returns a factory to create user import consumers
+func NewConsumerFactory(
+	ctx context.Context,
+	userSvc userModule.Service,
+) func(tag int, reportBatchSize int) rmq.Consumer {
+	consumerFactory := func(tag int, reportBatchSize int) rmq.Consumer {
+		return &Consumer{
+			name:    fmt.Sprintf("user-import-consumer-%d", tag),
+			userSvc: userSvc,
+
+			ctx: ctx,
+		}
+	}
+	return consumerFactory
+}
+
+func (c *Consumer) Consume(delivery rmq.Delivery) {
+	var payload dto.UserImportJobDto
+	job := dto.JobDto{Value: &payload}
+	if err := json.Unmarshal([]byte(delivery.Payload()), &job); err != nil {
+		CreateConsumeLog(c.ctx, "Format job error", err.Error(), enums.LogLevelDanger)
+		if err := delivery.Reject(); err != nil {
+			CreateConsumeLog(c.ctx, "Reject job error", err.Error(), enums.LogLevelWarning)
+		}
+		return
+	}
+
+	// carry the request ID of the import request so job logs can be correlated
+	ctx := request.WithRequestId(c.ctx, job.RequestId)
+
+	if err := c.userSvc.ProcessImport(ctx, &payload); err != nil {
+		CreateConsumeLog(ctx, "Process user import error", err.Error(), enums.LogLevelDanger)
+		if err := delivery.Reject(); err != nil {
+			CreateConsumeLog(ctx, "Reject job error", err.Error(), enums.LogLevelWarning)
+		}
+		return
+	}
+
+	CreateConsumeLog(ctx, "User import", fmt.Sprintf("%s imported %s", c.name, payload.ImportId), enums.LogLevelInfo)
+	if err := delivery.Ack(); err != nil {
+		CreateConsumeLog(ctx, "Acknowledge job error", err.Error(), enums.LogLevelWarning)
+	}
+}
+
+func CreateConsumeLog(ctx context.Context, subject, content string, level enums.LogLevel) {
+	logger.Printf(ctx, "[%s] %s : %s", level, subject, content)
+}
@@ -0,0 +1,37 @@
+package user
+
+import (
+	"context"
+
+	"github.com/adjust/rmq/v4"
+	userModule "github.com/ericmarcelinotju/gram/module/user"
+	"github.com/ericmarcelinotju/gram/plugins/job"
+)
+
+// Worker commits queued bulk user imports
+type Worker struct {
+	ctx     context.Context
+	queue   *job.Queue
+	userSvc userModule.Service
+}
+
+// NewWorker creates the user import worker consuming the queue
+func NewWorker(queue *job.Queue, userSvc userModule.Service) (*Worker, error) {
+	return &Worker{
+		ctx:     context.Background(),
+		queue:   queue,
+		userSvc: userSvc,
+	}, nil
+}
+
+// Start starts consuming user imports
+func (w *Worker) Start() error {
+	err := w.queue.StartConsuming()
+	if err != nil {
+		if err != rmq.ErrorAlreadyConsuming {
+			return err
+		}
+	}
+
+	return w.queue.AddConsumer(NewConsumerFactory(w.ctx, w.userSvc))
+}
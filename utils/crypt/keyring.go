@@ -0,0 +1,179 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// sealedPrefix starts every value sealed by a keyring
+const sealedPrefix = "enc:v2:"
+
+// unboundPrefix starts the values sealed before they were bound to additional data
+const unboundPrefix = "enc:v1:"
+
+// ErrUnknownKey reports a value sealed with a key missing from the keyring
+var ErrUnknownKey = errors.New("value is sealed with an unknown key")
+
+// Keyring seals values with envelope encryption: every value is encrypted with its own data key,
+// the data key is encrypted with the primary key of the keyring and stored along the value.
+// Older keys are kept to open values sealed before a rotation.
+type Keyring struct {
+	keys    map[string][]byte
+	primary string
+}
+
+// ParseKeyring reads keys written as id:base64 separated by commas or new lines, the first key is the primary.
+// Keys are 32 bytes, e.g. generated by `openssl rand -base64 32`.
+func ParseKeyring(spec string) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string][]byte)}
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || id == "" {
+			return nil, errors.New("keys must be written as id:base64")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %s is not base64 %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %s must be 32 bytes", id)
+		}
+		if _, ok := keyring.keys[id]; ok {
+			return nil, fmt.Errorf("key %s is given twice", id)
+		}
+		keyring.keys[id] = key
+		if keyring.primary == "" {
+			keyring.primary = id
+		}
+	}
+	if keyring.primary == "" {
+		return nil, errors.New("keyring has no key")
+	}
+	return keyring, nil
+}
+
+// Primary returns the id of the key sealing new values
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// IsSealed reports whether a value was sealed by a keyring
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, sealedPrefix) || IsUnbound(value)
+}
+
+// IsUnbound reports whether a sealed value is not bound to additional data, it opens in any place it is copied to
+func IsUnbound(value string) bool {
+	return strings.HasPrefix(value, unboundPrefix)
+}
+
+// SealedWith returns the id of the key a sealed value was sealed with
+func SealedWith(value string) string {
+	id, _, _ := strings.Cut(trimPrefix(value), ":")
+	return id
+}
+
+func trimPrefix(value string) string {
+	if IsUnbound(value) {
+		return strings.TrimPrefix(value, unboundPrefix)
+	}
+	return strings.TrimPrefix(value, sealedPrefix)
+}
+
+// Seal encrypts plaintext with a new data key sealed by the primary key, the value only opens with the same
+// additionalData, e.g. the name it is stored under so it cannot be moved to another one
+func (k *Keyring) Seal(plaintext, additionalData string) (string, error) {
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	sealedKey, err := seal(k.keys[k.primary], dataKey, nil)
+	if err != nil {
+		return "", err
+	}
+	sealedValue, err := seal(dataKey, []byte(plaintext), []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return sealedPrefix + k.primary + ":" +
+		base64.RawURLEncoding.EncodeToString(sealedKey) + ":" +
+		base64.RawURLEncoding.EncodeToString(sealedValue), nil
+}
+
+// Open decrypts a value sealed by any key of the keyring with the same additionalData,
+// values sealed before they were bound to additional data open with any
+func (k *Keyring) Open(value, additionalData string) (string, error) {
+	if !IsSealed(value) {
+		return "", errors.New("value is not sealed")
+	}
+	parts := strings.Split(trimPrefix(value), ":")
+	if len(parts) != 3 {
+		return "", errors.New("sealed value is malformed")
+	}
+	key, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("%w %s", ErrUnknownKey, parts[0])
+	}
+	sealedKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", err
+	}
+	sealedValue, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := open(key, sealedKey, nil)
+	if err != nil {
+		return "", err
+	}
+	data := []byte(additionalData)
+	if IsUnbound(value) {
+		data = nil
+	}
+	plaintext, err := open(dataKey, sealedValue, data)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// seal encrypts with AES-GCM, the nonce is prepended to the ciphertext
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("sealed value too short")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
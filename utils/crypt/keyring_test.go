@@ -0,0 +1,62 @@
+package crypt
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func key(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), 32)))
+}
+
+func TestKeyringRotation(t *testing.T) {
+	old, err := ParseKeyring("2023:" + key('a'))
+	assert.Equal(t, err, nil)
+	sealed, err := old.Seal("hunter2", "password")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, IsSealed(sealed), true)
+	assert.Equal(t, strings.Contains(sealed, "hunter2"), false)
+
+	// the new primary seals, the old key still opens
+	rotated, err := ParseKeyring("2024:" + key('b') + "\n2023:" + key('a'))
+	assert.Equal(t, err, nil)
+	value, err := rotated.Open(sealed, "password")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, value, "hunter2")
+	resealed, err := rotated.Seal(value, "password")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, SealedWith(resealed), "2024")
+
+	// once the old key is dropped its values cannot be opened
+	current, _ := ParseKeyring("2024:" + key('b'))
+	_, err = current.Open(sealed, "password")
+	assert.Equal(t, errors.Is(err, ErrUnknownKey), true)
+
+	// tampered values are rejected
+	_, err = current.Open(resealed[:len(resealed)-2]+"AA", "password")
+	assert.NotEqual(t, err, nil)
+
+	// values only open with the additional data they were sealed with
+	_, err = current.Open(resealed, "other")
+	assert.NotEqual(t, err, nil)
+
+	// values sealed before the additional data open with any
+	unbound, _ := current.Seal("hunter2", "")
+	unbound = "enc:v1:" + strings.TrimPrefix(unbound, "enc:v2:")
+	assert.Equal(t, IsUnbound(unbound), true)
+	assert.Equal(t, SealedWith(unbound), "2024")
+	value, err = current.Open(unbound, "password")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, value, "hunter2")
+}
+
+func TestParseKeyring(t *testing.T) {
+	for _, spec := range []string{"", "2024", "2024:short", "2024:" + key('a') + ",2024:" + key('b')} {
+		_, err := ParseKeyring(spec)
+		assert.NotEqual(t, err, nil)
+	}
+}
@@ -1,15 +1,8 @@
 package crypt
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
 	"crypto/md5"
-	"crypto/rand"
-	"encoding/base64"
 	"encoding/hex"
-	"errors"
-	"fmt"
-	"io"
 	mathRand "math/rand"
 	"net/http"
 	"strconv"
@@ -19,11 +12,6 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-//Private Const
-const (
-	constantKey = "238497335fe5bc226b0d1b86da1a51c4"
-)
-
 const (
 	// 2mb
 	MaxFileSize                    = 1097152
@@ -46,51 +34,6 @@ func CompareHash(hash, password string) bool {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
 }
 
-//Encrypt string to base64 crypto using AES
-func Encrypt(password string) (data string, err error) {
-	key := []byte(constantKey)
-	encryptPass := []byte(password)
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return data, err
-	}
-
-	ciphertext := make([]byte, aes.BlockSize+len(encryptPass))
-
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return data, err
-	}
-
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], encryptPass)
-
-	// convert to base64
-	return base64.URLEncoding.EncodeToString(ciphertext), nil
-}
-
-//Decrypt from base64 to decrypted string
-func Decrypt(cryptoText string) (data string, err error) {
-	ciphertext, _ := base64.URLEncoding.DecodeString(cryptoText)
-	key := []byte(constantKey)
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return data, err
-	}
-	if len(ciphertext) < aes.BlockSize {
-		return data, errors.New("ciphertext too short")
-	}
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-
-	stream := cipher.NewCFBDecrypter(block, iv)
-
-	stream.XORKeyStream(ciphertext, ciphertext)
-
-	return fmt.Sprintf("%s", ciphertext), nil
-}
-
 var (
 	letterRunes                = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 	newRand     *mathRand.Rand = mathRand.New(mathRand.NewSource(time.Now().UnixNano()))
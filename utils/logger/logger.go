@@ -0,0 +1,27 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ericmarcelinotju/gram/utils/request"
+)
+
+func prefix(ctx context.Context) string {
+	requestId := request.GetRequestId(ctx)
+	if requestId == "" {
+		return ""
+	}
+	return "[" + requestId + "] "
+}
+
+// Printf writes a log line prefixed with the request ID of the context
+func Printf(ctx context.Context, format string, v ...interface{}) {
+	log.Print(prefix(ctx) + fmt.Sprintf(format, v...))
+}
+
+// Println writes a log line prefixed with the request ID of the context
+func Println(ctx context.Context, v ...interface{}) {
+	log.Print(prefix(ctx) + fmt.Sprintln(v...))
+}
@@ -0,0 +1,19 @@
+package request
+
+import (
+	"context"
+)
+
+// GetRequestId returns the correlation ID of the request carried by the context
+func GetRequestId(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestId, _ := ctx.Value("request-id").(string)
+	return requestId
+}
+
+// WithRequestId returns a copy of the context carrying the correlation ID
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, "request-id", requestId)
+}
@@ -1,15 +1,31 @@
 package request
 
 import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
 	"github.com/ericmarcelinotju/gram/dto"
+	customErrors "github.com/ericmarcelinotju/gram/errors"
 	"github.com/gin-gonic/gin"
 )
 
+// queryBinder is implemented by payloads reading query keys the form binding cannot, like dto.FilterDto
+type queryBinder interface {
+	BindQuery(url.Values) error
+}
+
 func Bind[K any](c *gin.Context) (*K, error) {
 	var payload K
 	if err := c.ShouldBind(&payload); err != nil {
 		return nil, err
 	}
+	if binder, ok := any(&payload).(queryBinder); ok {
+		if err := binder.BindQuery(c.Request.URL.Query()); err != nil {
+			return nil, err
+		}
+	}
 
 	return &payload, nil
 }
@@ -22,3 +38,24 @@ func BindId(c *gin.Context) (string, error) {
 
 	return payload.Id, nil
 }
+
+// IfMatch returns the version required by the If-Match header, zero when any version is accepted.
+// Weak tags fail the precondition since If-Match uses the strong comparison
+func IfMatch(c *gin.Context) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if strings.Contains(header, ",") {
+		return 0, fmt.Errorf("If-Match accepts a single entity tag")
+	}
+	if strings.HasPrefix(header, "W/") {
+		return 0, customErrors.NewAppError(fmt.Errorf("If-Match does not accept the weak entity tag '%s'", header), customErrors.PreconditionFailedError)
+	}
+	tag, _, _ := strings.Cut(strings.Trim(header, `"`), "-")
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid entity tag '%s'", header)
+	}
+	return version, nil
+}
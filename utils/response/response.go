@@ -1,10 +1,16 @@
 package response
 
 import (
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ericmarcelinotju/gram/dto"
 	"github.com/gin-gonic/gin"
 )
 
@@ -14,6 +20,7 @@ type SetResponse struct {
 	Data       interface{} `json:"data,omitempty"`
 	Code       int         `json:"code"`
 	AccessTime string      `json:"accessTime"`
+	RequestId  string      `json:"requestId,omitempty"`
 }
 
 // ResponseSuccess for endpoint success
@@ -23,11 +30,42 @@ func ResponseSuccess(c *gin.Context, data interface{}) {
 		AccessTime: time.Now().Format("02-01-2006 15:04:05"),
 		Data:       data,
 		Code:       http.StatusOK,
+		RequestId:  c.GetString("request-id"),
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// ETag formats a record version and a hash of its body as an entity tag, so a change of the embedded
+// records changes the tag too, If-Match only compares the version before the dash
+func ETag(version int64, data interface{}) string {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Sprintf(`"%d"`, version)
+	}
+	hash := fnv.New64a()
+	hash.Write(body)
+	return fmt.Sprintf(`"%d-%x"`, version, hash.Sum64())
+}
+
+// ResponseVersioned for endpoint success of a versioned record, answering 304 when If-None-Match already has the tag
+func ResponseVersioned(c *gin.Context, data interface{}, version int64) {
+	etag := ETag(version, data)
+	c.Header("ETag", etag)
+
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		for _, match := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+			match = strings.TrimPrefix(strings.TrimSpace(match), "W/")
+			if match == etag || match == "*" {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+	}
+
+	ResponseSuccess(c, data)
+}
+
 // ResponseError for endpoint error
 func ResponseError(c *gin.Context, err error, code int) {
 	response := SetResponse{
@@ -35,7 +73,9 @@ func ResponseError(c *gin.Context, err error, code int) {
 		AccessTime: time.Now().Format("02-01-2006 15:04:05"),
 		Data:       err.Error(),
 		Code:       code,
+		RequestId:  c.GetString("request-id"),
 	}
+	c.Error(err)
 	c.JSON(code, response)
 }
 
@@ -46,7 +86,9 @@ func ResponseAbort(c *gin.Context, err error, code int) {
 		AccessTime: time.Now().Format("02-01-2006 15:04:05"),
 		Data:       err.Error(),
 		Code:       code,
+		RequestId:  c.GetString("request-id"),
 	}
+	c.Error(err)
 	c.AbortWithStatusJSON(code, response)
 }
 
@@ -67,3 +109,60 @@ func ResponseFile(c *gin.Context, filename string, file []byte) {
 func ResponseHTML(c *gin.Context, html string) {
 	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
 }
+
+// pageLink returns the request URL with the page or cursor query replaced
+func pageLink(requestUrl *url.URL, key, value string) string {
+	query := requestUrl.Query()
+	query.Del("page")
+	query.Del("cursor")
+	query.Set(key, value)
+
+	link := url.URL{Path: requestUrl.Path, RawQuery: query.Encode()}
+	return link.String()
+}
+
+// ResponseList for list endpoint success, with page metadata, links and RFC 8288 Link headers
+func ResponseList[T any](c *gin.Context, data []T, total int64, pagination *dto.PaginationDto) {
+	result := dto.NewListDto(data, total, pagination)
+
+	requestUrl := c.Request.URL
+	links := &dto.LinksDto{Self: requestUrl.RequestURI()}
+	if pagination.IsCursor() {
+		links.First = pageLink(requestUrl, "cursor", "")
+		if result.PrevCursor != nil {
+			links.Prev = pageLink(requestUrl, "cursor", *result.PrevCursor)
+		}
+		if result.NextCursor != nil {
+			links.Next = pageLink(requestUrl, "cursor", *result.NextCursor)
+		}
+	} else if result.Limit != nil {
+		page := *result.Page
+		links.First = pageLink(requestUrl, "page", "1")
+		if page > 1 {
+			links.Prev = pageLink(requestUrl, "page", strconv.Itoa(page-1))
+		}
+		if result.HasNext {
+			links.Next = pageLink(requestUrl, "page", strconv.Itoa(page+1))
+		}
+		if result.TotalPages != nil && *result.TotalPages > 0 {
+			links.Last = pageLink(requestUrl, "page", strconv.FormatInt(*result.TotalPages, 10))
+		}
+	}
+	result.Links = links
+
+	var header []string
+	for _, link := range []struct{ rel, url string }{
+		{"self", links.Self},
+		{"first", links.First},
+		{"prev", links.Prev},
+		{"next", links.Next},
+		{"last", links.Last},
+	} {
+		if link.url != "" {
+			header = append(header, fmt.Sprintf("<%s>; rel=\"%s\"", link.url, link.rel))
+		}
+	}
+	c.Header("Link", strings.Join(header, ", "))
+
+	ResponseSuccess(c, result)
+}
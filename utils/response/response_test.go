@@ -0,0 +1,82 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ericmarcelinotju/gram/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/assert/v2"
+)
+
+func TestResponseListPage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("GET", "/api/user?limit=2&page=2&name[like]=jo", nil)
+
+	limit, page := 2, 2
+	ResponseList(c, []string{"c", "d"}, 5, &dto.PaginationDto{Limit: &limit, Page: &page})
+
+	var body struct {
+		Data dto.ListDto[string] `json:"data"`
+	}
+	assert.Equal(t, json.Unmarshal(recorder.Body.Bytes(), &body), nil)
+	assert.Equal(t, *body.Data.TotalPages, int64(3))
+	assert.Equal(t, body.Data.HasNext, true)
+	assert.Equal(t, body.Data.Links.Prev, "/api/user?limit=2&name%5Blike%5D=jo&page=1")
+	assert.Equal(t, body.Data.Links.Next, "/api/user?limit=2&name%5Blike%5D=jo&page=3")
+	assert.Equal(t, body.Data.Links.Last, "/api/user?limit=2&name%5Blike%5D=jo&page=3")
+	assert.Equal(t, recorder.Header().Get("Link"),
+		`</api/user?limit=2&page=2&name[like]=jo>; rel="self", `+
+			`</api/user?limit=2&name%5Blike%5D=jo&page=1>; rel="first", `+
+			`</api/user?limit=2&name%5Blike%5D=jo&page=1>; rel="prev", `+
+			`</api/user?limit=2&name%5Blike%5D=jo&page=3>; rel="next", `+
+			`</api/user?limit=2&name%5Blike%5D=jo&page=3>; rel="last"`)
+}
+
+func TestResponseListCursor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("GET", "/api/audit?cursor=", nil)
+
+	next := "abc"
+	ResponseList(c, []string{"a"}, 0, &dto.PaginationDto{Cursor: new(string), NextCursor: &next})
+
+	var body struct {
+		Data dto.ListDto[string] `json:"data"`
+	}
+	assert.Equal(t, json.Unmarshal(recorder.Body.Bytes(), &body), nil)
+	assert.Equal(t, body.Data.Total, (*int64)(nil))
+	assert.Equal(t, body.Data.HasNext, true)
+	assert.Equal(t, body.Data.Links.Next, "/api/audit?cursor=abc")
+	assert.Equal(t, body.Data.Links.Prev, "")
+}
+
+func TestResponseVersioned(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("GET", "/api/role/1", nil)
+
+	ResponseVersioned(c, "role", 3)
+	etag := recorder.Header().Get("ETag")
+	assert.Equal(t, recorder.Code, http.StatusOK)
+	assert.Equal(t, strings.HasPrefix(etag, `"3-`), true)
+
+	// the embedded records change the tag of the same version
+	assert.NotEqual(t, ETag(3, "admin"), etag)
+
+	recorder = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("GET", "/api/role/1", nil)
+	c.Request.Header.Set("If-None-Match", `"2", W/`+etag)
+
+	ResponseVersioned(c, "role", 3)
+	assert.Equal(t, c.Writer.Status(), http.StatusNotModified)
+	assert.Equal(t, recorder.Body.Len(), 0)
+}
@@ -0,0 +1,287 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatCsv  = "csv"
+	FormatXlsx = "xlsx"
+)
+
+// FormatOf returns the spreadsheet format of a filename by its extension
+func FormatOf(filename string) (string, error) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return FormatCsv, nil
+	case ".xlsx":
+		return FormatXlsx, nil
+	}
+	return "", fmt.Errorf("unsupported spreadsheet '%s'", filename)
+}
+
+// Read returns every row of a CSV file or of the first sheet of a XLSX file
+func Read(reader io.Reader, format string) ([][]string, error) {
+	if format == FormatCsv {
+		csvReader := csv.NewReader(reader)
+		csvReader.FieldsPerRecord = -1
+		csvReader.TrimLeadingSpace = true
+		return csvReader.ReadAll()
+	}
+	if format == FormatXlsx {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+		return readXlsx(data)
+	}
+	return nil, fmt.Errorf("unsupported spreadsheet format '%s'", format)
+}
+
+// Writer writes rows to a CSV or XLSX file
+type Writer interface {
+	Write(row []string) error
+	Close() error
+}
+
+// NewWriter creates a spreadsheet writer for the format
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	if format == FormatCsv {
+		return &csvWriter{writer: csv.NewWriter(w)}, nil
+	}
+	if format == FormatXlsx {
+		return newXlsxWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported spreadsheet format '%s'", format)
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvWriter) Write(row []string) error {
+	return c.writer.Write(row)
+}
+
+func (c *csvWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+type xlsxRelationship struct {
+	Id     string `xml:"Id,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+type xlsxCell struct {
+	Ref    string `xml:"r,attr"`
+	Type   string `xml:"t,attr"`
+	Value  string `xml:"v"`
+	Inline struct {
+		Text string `xml:"t"`
+	} `xml:"is"`
+}
+
+type xlsxSharedString struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func readZipFile(files map[string]*zip.File, name string, v interface{}) error {
+	file, ok := files[name]
+	if !ok {
+		return fmt.Errorf("xlsx part '%s' not found", name)
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+func readXlsx(data []byte) ([][]string, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	// resolve the first sheet of the workbook
+	sheetPath := "xl/worksheets/sheet1.xml"
+	var workbook struct {
+		Sheets []struct {
+			RelId string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var relationships struct {
+		Items []xlsxRelationship `xml:"Relationship"`
+	}
+	if readZipFile(files, "xl/workbook.xml", &workbook) == nil &&
+		readZipFile(files, "xl/_rels/workbook.xml.rels", &relationships) == nil &&
+		len(workbook.Sheets) > 0 {
+		for _, item := range relationships.Items {
+			if item.Id == workbook.Sheets[0].RelId {
+				sheetPath = path.Join("xl", strings.TrimPrefix(item.Target, "/xl/"))
+				break
+			}
+		}
+	}
+
+	var sharedStrings struct {
+		Items []xlsxSharedString `xml:"si"`
+	}
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := readZipFile(files, "xl/sharedStrings.xml", &sharedStrings); err != nil {
+			return nil, err
+		}
+	}
+
+	var sheet struct {
+		Rows []struct {
+			Cells []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := readZipFile(files, sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, len(sheet.Rows))
+	for i, sheetRow := range sheet.Rows {
+		var row []string
+		for j, cell := range sheetRow.Cells {
+			column := j
+			if cell.Ref != "" {
+				column = columnIndex(cell.Ref)
+			}
+			for len(row) <= column {
+				row = append(row, "")
+			}
+
+			switch cell.Type {
+			case "s":
+				index, err := strconv.Atoi(cell.Value)
+				if err != nil || index >= len(sharedStrings.Items) {
+					return nil, errors.New("invalid xlsx shared string")
+				}
+				item := sharedStrings.Items[index]
+				text := item.Text
+				for _, run := range item.Runs {
+					text += run.Text
+				}
+				row[column] = text
+			case "inlineStr":
+				row[column] = cell.Inline.Text
+			default:
+				row[column] = cell.Value
+			}
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// columnIndex converts the column letters of a cell reference to a zero based index
+func columnIndex(ref string) int {
+	index := 0
+	for _, char := range ref {
+		if char < 'A' || char > 'Z' {
+			break
+		}
+		index = index*26 + int(char-'A'+1)
+	}
+	return index - 1
+}
+
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetFooter = `</sheetData></worksheet>`
+)
+
+// xlsxWriter streams rows as inline strings into a single sheet workbook
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	row     int
+}
+
+func newXlsxWriter(w io.Writer) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	for name, content := range map[string]string{
+		"[Content_Types].xml":        xlsxContentTypes,
+		"_rels/.rels":                xlsxRels,
+		"xl/workbook.xml":            xlsxWorkbook,
+		"xl/_rels/workbook.xml.rels": xlsxWorkbookRels,
+	} {
+		part, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(part, content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetHeader); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{archive: archive, sheet: sheet}, nil
+}
+
+func (x *xlsxWriter) Write(row []string) error {
+	x.row++
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<row r="%d">`, x.row)
+	for i, value := range row {
+		fmt.Fprintf(&buf, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">`, columnName(i), x.row)
+		if err := xml.EscapeText(&buf, []byte(value)); err != nil {
+			return err
+		}
+		buf.WriteString(`</t></is></c>`)
+	}
+	buf.WriteString(`</row>`)
+	_, err := x.sheet.Write(buf.Bytes())
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, xlsxSheetFooter); err != nil {
+		return err
+	}
+	return x.archive.Close()
+}
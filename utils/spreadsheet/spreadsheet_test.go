@@ -0,0 +1,46 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/go-playground/assert/v2"
+)
+
+func TestWriteReadSpreadsheet(t *testing.T) {
+	rows := [][]string{
+		{"name", "email", "title"},
+		{"jane", "jane@example.com", "R&D <lead>"},
+		{"john", "john@example.com", ""},
+	}
+
+	for _, format := range []string{FormatCsv, FormatXlsx} {
+		var buf bytes.Buffer
+		writer, err := NewWriter(&buf, format)
+		assert.Equal(t, err, nil)
+		for _, row := range rows {
+			assert.Equal(t, writer.Write(row), nil)
+		}
+		assert.Equal(t, writer.Close(), nil)
+
+		result, err := Read(&buf, format)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(result), len(rows))
+		for i, row := range rows {
+			for j, value := range row {
+				if j < len(result[i]) {
+					assert.Equal(t, result[i][j], value)
+				} else {
+					assert.Equal(t, value, "")
+				}
+			}
+		}
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	for index, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, columnName(index), name)
+		assert.Equal(t, columnIndex(name+"12"), index)
+	}
+}